@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/admin"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// runSeed applies the default category manifests that have not been applied yet
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	flags.Parse(args)
+	return db.SeedDefaultCategories(db.DB)
+}
+
+// runRecomputeAggregates recomputes stored aggregates from the rows they summarize
+func runRecomputeAggregates(args []string) error {
+	flags := flag.NewFlagSet("recompute-aggregates", flag.ExitOnError)
+	flags.Parse(args)
+
+	report, err := admin.RecomputeAggregates(db.DB)
+	if err != nil {
+		return err
+	}
+	log.Printf("Recomputed %d recurring expenses: %d updated, %d skipped with an invalid rule",
+		report.RecurringExpenses, report.Updated, report.Skipped)
+	return nil
+}
+
+// runExportUser writes the user's data as JSON to stdout or to the -o file
+func runExportUser(args []string) error {
+	flags := flag.NewFlagSet("export-user", flag.ExitOnError)
+	output := flags.String("o", "", "write the export to this file instead of stdout")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("usage: expense-service export-user [-o file] <user-id>")
+	}
+	userID, err := uuid.Parse(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("invalid user ID %q", flags.Arg(0))
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600) // Personal data
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return admin.ExportUser(db.DB, userID, w)
+}
+
+// runPurgeDeleted permanently removes rows soft-deleted longer ago than -older-than
+func runPurgeDeleted(args []string) error {
+	flags := flag.NewFlagSet("purge-deleted", flag.ExitOnError)
+	olderThan := flags.String("older-than", "", "age of the deletions to purge, e.g. 30d or 720h (required)")
+	purgeVersions := flags.Bool("purge-versions", false, "also delete the change history of the purged budgets")
+	dryRun := flags.Bool("dry-run", false, "report what would be purged without deleting anything")
+	flags.Parse(args)
+	if *olderThan == "" {
+		return errors.New("-older-than is required, e.g. -older-than 30d")
+	}
+	age, err := parseAge(*olderThan)
+	if err != nil {
+		return err
+	}
+
+	before := time.Now().Add(-age)
+	report, err := admin.PurgeDeleted(db.DB, before, *purgeVersions, *dryRun)
+	if err != nil {
+		return err
+	}
+	verb := "Purged"
+	if *dryRun {
+		verb = "Would purge"
+	}
+	log.Printf("%s rows deleted before %s: %d receipts, %d budgets (%d versions), %d rules, %d recurring expenses, %d envelope plans, %d categories (%d still referenced and kept)",
+		verb, before.Format(time.RFC3339), report.Receipts, report.Budgets, report.BudgetVersions, report.Rules, report.RecurringExpenses,
+		report.EnvelopePlans, report.Categories, report.CategoriesKept)
+	return nil
+}
+
+// runVerifyIntegrity reports inconsistencies and fails when there are any, so it can run as a scheduled check
+func runVerifyIntegrity(args []string) error {
+	flags := flag.NewFlagSet("verify-integrity", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Parse(args)
+
+	report, err := admin.VerifyIntegrity(db.DB)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, missing := range report.MissingCategories {
+			if missing.AllocationID != nil {
+				fmt.Printf("missing category: expense %s allocation %s (user %s) -> category %s\n",
+					missing.ExpenseID, *missing.AllocationID, missing.UserID, missing.CategoryID)
+			} else {
+				fmt.Printf("missing category: expense %s (user %s) -> category %s\n",
+					missing.ExpenseID, missing.UserID, missing.CategoryID)
+			}
+		}
+		for _, link := range report.ReceiptLinks {
+			fmt.Printf("receipt link: receipt %s, expense %s: %s\n", link.ReceiptID, link.ExpenseID, link.Problem)
+		}
+	}
+
+	if problems := report.Problems(); problems > 0 {
+		return fmt.Errorf("%d integrity problem(s) found", problems)
+	}
+	log.Println("No integrity problems found")
+	return nil
+}
+
+// parseAge parses a duration, also accepting a number of days such as 30d
+func parseAge(value string) (time.Duration, error) {
+	var age time.Duration
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if age, err = time.ParseDuration(value); err != nil {
+			return 0, fmt.Errorf("invalid age %q", value)
+		}
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("age %q must be positive", value)
+	}
+	return age, nil
+}
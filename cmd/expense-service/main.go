@@ -1,25 +1,119 @@
 package main
 
 import (
+	"expense-mgmt/configs"
 	"expense-mgmt/db"
+	"expense-mgmt/internal/alerts"
+	"expense-mgmt/internal/controller"
+	"expense-mgmt/internal/fx"
+	"expense-mgmt/internal/ocr"
+	"expense-mgmt/internal/recurrence"
 	"expense-mgmt/internal/routes"
+	"expense-mgmt/internal/storage"
+	"fmt"
 	"log"
 	"os"
 
 	"github.com/gin-gonic/gin"
 )
 
+// command is a subcommand of expense-service
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+// commands lists the subcommands; running without one serves the API
+var commands = []command{
+	{"serve", "serve                         Run the HTTP API and background workers (default)", serve},
+	{"migrate", "migrate up|down [n]|status    Apply, revert or list schema migrations", runMigrate},
+	{"seed", "seed                          Apply pending default category manifests", runSeed},
+	{"recompute-aggregates", "recompute-aggregates          Recompute stored counters such as recurring occurrence counts", runRecomputeAggregates},
+	{"export-user", "export-user [-o file] <id>    Export everything stored about a user as JSON", runExportUser},
+	{"purge-deleted", "purge-deleted -older-than 30d Permanently remove rows soft-deleted before the cutoff", runPurgeDeleted},
+	{"verify-integrity", "verify-integrity [-json]      Report missing categories and one-way receipt links", runVerifyIntegrity},
+}
+
 func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		usage()
+		os.Exit(2)
+	}
+
 	// Initialize database connection
 	if _,err := db.ConnectDatabase(); err != nil {
 		log.Fatalf("Database connection error: %v", err)
 	}
 
+	if err := cmd.run(args); err != nil {
+		log.Fatalf("%s: %v", cmd.name, err)
+	}
+}
+
+// usage prints the subcommands to stderr
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: expense-service <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	for _, cmd := range commands {
+		fmt.Fprintln(os.Stderr, "  "+cmd.usage)
+	}
+}
+
+// serve runs the HTTP API along with the OCR worker, the recurring expense scheduler and exchange rate syncing
+func serve(args []string) error {
+	// Apply pending schema migrations, unless the deployment runs them as a separate step
+	if configs.LoadConfig().Database.MigrateOnStart {
+		if _, err := db.MigrateUp(db.DB); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
 	// Seed default categories
 	if err := db.SeedDefaultCategories(db.DB); err != nil {
-		log.Fatalf("Failed to seed default categories: %v", err)
+		return fmt.Errorf("failed to seed default categories: %w", err)
+	}
+
+	// Load exchange rates used to convert foreign-currency expenses
+	if _, err := fx.StartSync(db.DB); err != nil {
+		return fmt.Errorf("exchange rate provider error: %w", err)
+	}
+
+	// Initialize blob storage for receipt images
+	store, err := storage.InitStore()
+	if err != nil {
+		return fmt.Errorf("blob storage error: %w", err)
 	}
 
+	// Start the background OCR worker for uploaded receipts
+	if _, err := ocr.StartWorker(db.DB, store); err != nil {
+		return fmt.Errorf("failed to start OCR worker: %w", err)
+	}
+
+	// Set up delivery of budget threshold alerts
+	if err := alerts.Start(db.DB); err != nil {
+		return fmt.Errorf("alert notifier error: %w", err)
+	}
+
+	// Start the scheduler that turns recurring expense templates into expenses; they count towards budget alerts too
+	recurrence.OnMaterialized = controller.CheckBudgetAlerts
+	recurrence.StartScheduler(db.DB, recurrence.DefaultSchedulerInterval)
+
 	// Initialize Gin engine
 	server := gin.Default()
 
@@ -27,6 +121,13 @@ func main() {
 	routes.CategoryRoutes(server) // Public routes
   routes.ExpenseRoutes(server)
   routes.BudgetRoutes(server)
+  routes.ReceiptRoutes(server)
+  routes.RecurringExpenseRoutes(server)
+  routes.TagRoutes(server)
+  routes.RuleRoutes(server)
+  routes.NotificationRoutes(server)
+  routes.EnvelopeRoutes(server)
+  routes.BudgetTemplateRoutes(server)
 	routes.AddHealthCheckRoute(server)
 	// Check for environment variable port
 	port := os.Getenv("PORT")
@@ -36,6 +137,7 @@ func main() {
 
 	// Start the server
 	if err := server.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start the server: %v", err)
+		return fmt.Errorf("failed to start the server: %w", err)
 	}
-}
\ No newline at end of file
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+)
+
+// runMigrate handles `migrate up`, `migrate down [steps]` and `migrate status`
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: expense-service migrate up | down [steps] | status")
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MigrateUp(db.DB)
+		if err != nil {
+			return err
+		}
+		log.Printf("%d migration(s) applied", applied)
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := db.MigrateDown(db.DB, steps)
+		if err != nil {
+			return err
+		}
+		log.Printf("%d migration(s) reverted", reverted)
+		return nil
+	case "status":
+		states, err := db.MigrationStatus(db.DB)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, state := range states {
+			status, appliedAt := "pending", ""
+			if state.AppliedAt != nil {
+				status, appliedAt = "applied", state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if state.Modified {
+				status = "modified"
+			}
+			if state.Missing {
+				status = "unknown"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", state.Version, state.Name, status, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
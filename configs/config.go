@@ -10,17 +10,53 @@ import (
 // Config holds the application configuration
 type Config struct {
 	Database struct {
-		Host     string `mapstructure:"host"`
-		Port     int    `mapstructure:"port"`
-		User     string `mapstructure:"user"`
-		Password string `mapstructure:"password"`
-		Name     string `mapstructure:"name"`
-		SSLMode  string `mapstructure:"sslmode"`
+		Host           string `mapstructure:"host"`
+		Port           int    `mapstructure:"port"`
+		User           string `mapstructure:"user"`
+		Password       string `mapstructure:"password"`
+		Name           string `mapstructure:"name"`
+		SSLMode        string `mapstructure:"sslmode"`
+		MigrateOnStart bool   `mapstructure:"migrate_on_start"`
 	} `mapstructure:"database"`
 	JWT struct {
 		Secret           string `mapstructure:"secret"`
 		ExpirationHours  int    `mapstructure:"expiration_hours"`
 	} `mapstructure:"jwt"`
+	Storage struct {
+		Driver         string `mapstructure:"driver"`
+		LocalPath      string `mapstructure:"local_path"`
+		MaxUploadBytes int64  `mapstructure:"max_upload_bytes"`
+	} `mapstructure:"storage"`
+	OCR struct {
+		Engine              string `mapstructure:"engine"`
+		TesseractPath       string `mapstructure:"tesseract_path"`
+		Language            string `mapstructure:"language"`
+		Endpoint            string `mapstructure:"endpoint"`
+		APIKey              string `mapstructure:"api_key"`
+		Workers             int    `mapstructure:"workers"`
+		MaxAttempts         int    `mapstructure:"max_attempts"`
+		PollIntervalSeconds int    `mapstructure:"poll_interval_seconds"`
+	} `mapstructure:"ocr"`
+	FX struct {
+		Provider          string `mapstructure:"provider"`
+		RatesFile         string `mapstructure:"rates_file"`
+		Endpoint          string `mapstructure:"endpoint"`
+		BaseCurrency      string `mapstructure:"base_currency"`
+		SyncIntervalHours int    `mapstructure:"sync_interval_hours"`
+		MaxRateAgeDays    int    `mapstructure:"max_rate_age_days"`
+	} `mapstructure:"fx"`
+	Alerts struct {
+		Notifiers           string `mapstructure:"notifiers"`
+		WebhookURL          string `mapstructure:"webhook_url"`
+		WebhookSecret       string `mapstructure:"webhook_secret"`
+		SMTPHost            string `mapstructure:"smtp_host"`
+		SMTPPort            int    `mapstructure:"smtp_port"`
+		SMTPUsername        string `mapstructure:"smtp_username"`
+		SMTPPassword        string `mapstructure:"smtp_password"`
+		SMTPFrom            string `mapstructure:"smtp_from"`
+		MaxAttempts         int    `mapstructure:"max_attempts"`
+		PollIntervalSeconds int    `mapstructure:"poll_interval_seconds"`
+	} `mapstructure:"alerts"`
 }
 
 // LoadConfig reads configuration from file and environment variables
@@ -42,6 +78,15 @@ func LoadConfig() *Config {
 		// Continue execution as env vars might be set
 	}
 
+	// Apply pending schema migrations at startup unless disabled
+	viper.SetDefault("database.migrate_on_start", true)
+
+	// Budget alerts land in the in-app inbox unless configured otherwise
+	viper.SetDefault("alerts.notifiers", "inbox")
+
+	// Exchange rates may be up to a week older than the transaction; 0 lifts the limit
+	viper.SetDefault("fx.max_rate_age_days", 7)
+
 	// Configure mappings for nested values with DB_ prefix
 	viper.BindEnv("database.host", "DB_HOST")
 	viper.BindEnv("database.port", "DB_PORT")
@@ -49,8 +94,36 @@ func LoadConfig() *Config {
 	viper.BindEnv("database.password", "DB_PASSWORD")
 	viper.BindEnv("database.name", "DB_NAME")
 	viper.BindEnv("database.sslmode", "DB_SSLMODE")
+	viper.BindEnv("database.migrate_on_start", "DB_MIGRATE_ON_START")
 	viper.BindEnv("jwt.secret", "JWT_SECRET")
 	viper.BindEnv("jwt.expiration_hours", "JWT_EXPIRATION_HOURS")
+	viper.BindEnv("storage.driver", "STORAGE_DRIVER")
+	viper.BindEnv("storage.local_path", "STORAGE_LOCAL_PATH")
+	viper.BindEnv("storage.max_upload_bytes", "STORAGE_MAX_UPLOAD_BYTES")
+	viper.BindEnv("ocr.engine", "OCR_ENGINE")
+	viper.BindEnv("ocr.tesseract_path", "OCR_TESSERACT_PATH")
+	viper.BindEnv("ocr.language", "OCR_LANGUAGE")
+	viper.BindEnv("ocr.endpoint", "OCR_ENDPOINT")
+	viper.BindEnv("ocr.api_key", "OCR_API_KEY")
+	viper.BindEnv("ocr.workers", "OCR_WORKERS")
+	viper.BindEnv("ocr.max_attempts", "OCR_MAX_ATTEMPTS")
+	viper.BindEnv("ocr.poll_interval_seconds", "OCR_POLL_INTERVAL_SECONDS")
+	viper.BindEnv("fx.provider", "FX_PROVIDER")
+	viper.BindEnv("fx.rates_file", "FX_RATES_FILE")
+	viper.BindEnv("fx.endpoint", "FX_ENDPOINT")
+	viper.BindEnv("fx.base_currency", "FX_BASE_CURRENCY")
+	viper.BindEnv("fx.sync_interval_hours", "FX_SYNC_INTERVAL_HOURS")
+	viper.BindEnv("fx.max_rate_age_days", "FX_MAX_RATE_AGE_DAYS")
+	viper.BindEnv("alerts.notifiers", "ALERTS_NOTIFIERS")
+	viper.BindEnv("alerts.webhook_url", "ALERTS_WEBHOOK_URL")
+	viper.BindEnv("alerts.webhook_secret", "ALERTS_WEBHOOK_SECRET")
+	viper.BindEnv("alerts.smtp_host", "ALERTS_SMTP_HOST")
+	viper.BindEnv("alerts.smtp_port", "ALERTS_SMTP_PORT")
+	viper.BindEnv("alerts.smtp_username", "ALERTS_SMTP_USERNAME")
+	viper.BindEnv("alerts.smtp_password", "ALERTS_SMTP_PASSWORD")
+	viper.BindEnv("alerts.smtp_from", "ALERTS_SMTP_FROM")
+	viper.BindEnv("alerts.max_attempts", "ALERTS_MAX_ATTEMPTS")
+	viper.BindEnv("alerts.poll_interval_seconds", "ALERTS_POLL_INTERVAL_SECONDS")
 
 	// Unmarshal the configuration into struct
 	if err := viper.Unmarshal(&config); err != nil {
@@ -0,0 +1,258 @@
+package db
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"expense-mgmt/internal/models"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles holds the schema migrations, one NNNN_name.up.sql file per version with an optional
+// NNNN_name.down.sql to revert it. Like seed manifests, a migration is never edited once shipped.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID serializes migrations across instances starting at the same time
+const migrationLockID = 7240117001
+
+// migrationFileName matches the version, name and direction of a migration file
+var migrationFileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// schemaMigrationsTable records the applied migrations; it is created before any migration runs
+const schemaMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    bigint NOT NULL,
+	name       text NOT NULL,
+	checksum   varchar(64) NOT NULL,
+	applied_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (version)
+)`
+
+// Migration is one version of the schema
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // Empty when the migration cannot be reverted
+
+	checksum string
+}
+
+// MigrationState is a known or applied migration and whether it has run on the database
+type MigrationState struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Modified  bool // The up step changed after it was applied
+	Missing   bool // Applied to the database but unknown to this build
+}
+
+// LoadMigrations parses the embedded migrations, ordered by version
+func LoadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		match := migrationFileName.FindStringSubmatch(path.Base(file))
+		if match == nil {
+			return nil, fmt.Errorf("%s: expected a name like 0001_create_table.up.sql", file)
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("%s: version must be a positive number", file)
+		}
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("%s: version %d is also used by %s", file, version, migration.Name)
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up step", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp applies the pending migrations in version order, each in its own transaction, and returns how many ran.
+// An advisory lock held for the whole run keeps concurrent instances from applying the same migration twice.
+func MigrateUp(db *gorm.DB) (int, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = withMigrationLock(db, func(conn *gorm.DB) error {
+		done, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range migrations {
+			if record, ok := done[migration.Version]; ok {
+				if record.Checksum != migration.checksum {
+					log.Printf("Migration %d (%s) changed after it was applied; add a new migration instead", migration.Version, migration.Name)
+				}
+				continue
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&models.SchemaMigration{
+					Version:  migration.Version,
+					Name:     migration.Name,
+					Checksum: migration.checksum,
+				}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Applied migration %d (%s)", migration.Version, migration.Name)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the last steps applied migrations, newest first, and returns how many were reverted
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, errors.New("steps must be a positive number")
+	}
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	byVersion := make(map[int]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	reverted := 0
+	err = withMigrationLock(db, func(conn *gorm.DB) error {
+		var records []models.SchemaMigration
+		if err := conn.Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+		for _, record := range records {
+			migration, ok := byVersion[record.Version]
+			if !ok {
+				return fmt.Errorf("migration %d (%s) is not part of this build", record.Version, record.Name)
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %d (%s) has no down step", migration.Version, migration.Name)
+			}
+			if err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(migration.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&models.SchemaMigration{}, "version = ?", migration.Version).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, err)
+			}
+			log.Printf("Reverted migration %d (%s)", migration.Version, migration.Name)
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrationStatus lists the known migrations and any applied migration this build does not know, by version
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	done := map[int]models.SchemaMigration{}
+	if db.Migrator().HasTable(&models.SchemaMigration{}) {
+		if done, err = appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, migration := range migrations {
+		state := MigrationState{Version: migration.Version, Name: migration.Name}
+		if record, ok := done[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			state.AppliedAt = &appliedAt
+			state.Modified = record.Checksum != migration.checksum
+			delete(done, migration.Version)
+		}
+		states = append(states, state)
+	}
+	for _, record := range done {
+		appliedAt := record.AppliedAt
+		states = append(states, MigrationState{Version: record.Version, Name: record.Name, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// withMigrationLock runs fn on a single connection holding the migration lock. The lock is taken at session level
+// rather than per transaction because every migration commits on its own.
+func withMigrationLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockID).Error; err != nil {
+			return err
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockID).Error; err != nil {
+				log.Printf("Failed to release the migration lock: %v", err)
+			}
+		}()
+
+		if err := conn.Exec(schemaMigrationsTable).Error; err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// appliedMigrations returns the recorded migrations keyed by version
+func appliedMigrations(db *gorm.DB) (map[int]models.SchemaMigration, error) {
+	var records []models.SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int]models.SchemaMigration, len(records))
+	for _, record := range records {
+		done[record.Version] = record
+	}
+	return done, nil
+}
@@ -0,0 +1,16 @@
+-- Drops the tables owned by the expense service. Users, auth tokens and the uuid-ossp extension are shared
+-- with the auth service and are left in place.
+DROP TABLE IF EXISTS category_rules;
+DROP TABLE IF EXISTS expense_tags;
+DROP TABLE IF EXISTS tags;
+DROP TABLE IF EXISTS exchange_rates;
+DROP TABLE IF EXISTS recurring_expenses;
+DROP TABLE IF EXISTS receipt_line_items;
+DROP TABLE IF EXISTS receipt_parses;
+DROP TABLE IF EXISTS receipts;
+DROP TABLE IF EXISTS budgets;
+DROP TABLE IF EXISTS expense_allocations;
+DROP TABLE IF EXISTS expenses;
+DROP TABLE IF EXISTS category_preferences;
+DROP TABLE IF EXISTS seed_versions;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,293 @@
+-- Baseline schema of the expense service. Every statement is idempotent so that databases created
+-- before migrations existed (by AutoMigrate or by hand) are adopted as they are. CREATE TABLE IF NOT EXISTS
+-- leaves an existing table untouched, so the columns added since the first release are also added one by one,
+-- before any index on them.
+
+-- uuid_generate_v4() is the default of every primary key
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+-- Users and auth tokens are shared with the auth service
+CREATE TABLE IF NOT EXISTS users (
+    user_id                uuid DEFAULT uuid_generate_v4(),
+    first_name             text NOT NULL,
+    last_name              text NOT NULL,
+    email                  text NOT NULL,
+    password_hash          text NOT NULL,
+    salt                   text NOT NULL,
+    is_email_verified      boolean DEFAULT false,
+    created_at             timestamptz,
+    reset_password_token   varchar(255),
+    reset_password_expires timestamptz,
+    PRIMARY KEY (user_id),
+    CONSTRAINT uni_users_email UNIQUE (email)
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS currency char(3) DEFAULT 'CAD';
+
+-- The first release only allowed CAD and USD under the same constraint name
+ALTER TABLE users DROP CONSTRAINT IF EXISTS chk_users_currency;
+ALTER TABLE users ADD CONSTRAINT chk_users_currency CHECK (currency ~ '^[A-Z]{3}$');
+
+CREATE TABLE IF NOT EXISTS auth_tokens (
+    token_id   uuid DEFAULT uuid_generate_v4(),
+    user_id    uuid NOT NULL,
+    token      text NOT NULL,
+    created_at timestamptz,
+    expires_at timestamptz,
+    PRIMARY KEY (token_id),
+    CONSTRAINT uni_auth_tokens_token UNIQUE (token)
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id          uuid DEFAULT uuid_generate_v4(),
+    user_id     uuid,
+    name        varchar(50) NOT NULL,
+    description text,
+    color_code  varchar(7),
+    is_default  boolean DEFAULT false,
+    parent_id   uuid,
+    archived_at timestamptz,
+    seed_key    varchar(100),
+    created_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  timestamptz,
+    PRIMARY KEY (id)
+);
+
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id uuid;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS archived_at timestamptz;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS seed_key varchar(100);
+
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_seed_key ON categories (seed_key);
+CREATE INDEX IF NOT EXISTS idx_categories_archived_at ON categories (archived_at);
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+
+CREATE TABLE IF NOT EXISTS seed_versions (
+    version     bigint,
+    description text,
+    checksum    varchar(64) NOT NULL,
+    applied_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (version)
+);
+
+CREATE TABLE IF NOT EXISTS category_preferences (
+    user_id     uuid,
+    category_id uuid,
+    hidden      boolean NOT NULL DEFAULT false,
+    name        varchar(50),
+    created_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, category_id)
+);
+
+CREATE TABLE IF NOT EXISTS expenses (
+    expense_id           uuid DEFAULT uuid_generate_v4(),
+    user_id              uuid NOT NULL,
+    category_id          uuid NOT NULL,
+    amount               decimal(10,2) NOT NULL,
+    original_amount      decimal(10,2),
+    currency             char(3),
+    date                 timestamp NOT NULL,
+    description          text,
+    merchant             varchar(255),
+    receipt_id           uuid,
+    recurring_expense_id uuid,
+    created_at           timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at           timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (expense_id)
+);
+
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS original_amount decimal(10,2);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS currency char(3);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS merchant varchar(255);
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS recurring_expense_id uuid;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_recurring_expense_id ON expenses (recurring_expense_id);
+
+-- A template can materialize at most one expense per date, even with several scheduler instances running
+CREATE UNIQUE INDEX IF NOT EXISTS idx_expenses_recurring_occurrence
+    ON expenses (recurring_expense_id, date) WHERE recurring_expense_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS expense_allocations (
+    id          uuid DEFAULT uuid_generate_v4(),
+    expense_id  uuid NOT NULL,
+    category_id uuid NOT NULL,
+    amount      decimal(10,2) NOT NULL,
+    description text,
+    created_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_allocations_category_id ON expense_allocations (category_id);
+CREATE INDEX IF NOT EXISTS idx_expense_allocations_expense_id ON expense_allocations (expense_id);
+
+CREATE TABLE IF NOT EXISTS budgets (
+    budget_id   uuid DEFAULT uuid_generate_v4(),
+    user_id     uuid NOT NULL,
+    category_id uuid,
+    amount      decimal(10,2) NOT NULL,
+    start_date  date NOT NULL,
+    end_date    date NOT NULL,
+    created_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  timestamptz,
+    PRIMARY KEY (budget_id),
+    CONSTRAINT chk_budgets_amount CHECK (amount >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_deleted_at ON budgets (deleted_at);
+
+CREATE TABLE IF NOT EXISTS receipts (
+    id              uuid DEFAULT uuid_generate_v4(),
+    user_id         uuid,
+    image_url       text NOT NULL,
+    storage_key     text,
+    file_name       varchar(255),
+    content_type    varchar(100),
+    file_size       bigint,
+    ocr_data        text,
+    status          varchar(20),
+    attempts        bigint DEFAULT 0,
+    last_error      text,
+    next_attempt_at timestamptz,
+    processed_at    timestamptz,
+    scanned_date    timestamptz DEFAULT CURRENT_TIMESTAMP,
+    expense_id      uuid,
+    date            timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at      timestamptz DEFAULT CURRENT_TIMESTAMP,
+    deleted_at      timestamptz,
+    PRIMARY KEY (id)
+);
+
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS user_id uuid;
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS storage_key text;
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS file_name varchar(255);
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS content_type varchar(100);
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS file_size bigint;
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS status varchar(20);
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS attempts bigint DEFAULT 0;
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS last_error text;
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz;
+ALTER TABLE receipts ADD COLUMN IF NOT EXISTS processed_at timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_receipts_status ON receipts (status);
+CREATE INDEX IF NOT EXISTS idx_receipts_user_id ON receipts (user_id);
+CREATE INDEX IF NOT EXISTS idx_receipts_deleted_at ON receipts (deleted_at);
+
+CREATE TABLE IF NOT EXISTS receipt_parses (
+    id                  uuid DEFAULT uuid_generate_v4(),
+    receipt_id          uuid NOT NULL,
+    merchant            varchar(255),
+    merchant_confidence decimal,
+    transaction_date    date,
+    date_confidence     decimal,
+    subtotal            decimal(10,2),
+    subtotal_confidence decimal,
+    tax                 decimal(10,2),
+    tax_confidence      decimal,
+    total               decimal(10,2),
+    total_confidence    decimal,
+    currency            varchar(3),
+    currency_confidence decimal,
+    created_at          timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at          timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_receipt_parses_receipt_id ON receipt_parses (receipt_id);
+
+CREATE TABLE IF NOT EXISTS receipt_line_items (
+    id          uuid DEFAULT uuid_generate_v4(),
+    receipt_id  uuid NOT NULL,
+    position    bigint NOT NULL,
+    description text,
+    quantity    decimal(10,3) DEFAULT '1',
+    unit_price  decimal(10,2),
+    amount      decimal(10,2) NOT NULL,
+    confidence  decimal,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipt_line_items_receipt_id ON receipt_line_items (receipt_id);
+
+CREATE TABLE IF NOT EXISTS recurring_expenses (
+    id               uuid DEFAULT uuid_generate_v4(),
+    user_id          uuid NOT NULL,
+    category_id      uuid NOT NULL,
+    amount           decimal(10,2) NOT NULL,
+    description      text,
+    rule             varchar(255) NOT NULL,
+    start_date       date NOT NULL,
+    next_occurrence  date,
+    occurrence_count bigint DEFAULT 0,
+    series_id        uuid,
+    active           boolean DEFAULT true,
+    created_at       timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at       timestamptz DEFAULT CURRENT_TIMESTAMP,
+    deleted_at       timestamptz,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_deleted_at ON recurring_expenses (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_series_id ON recurring_expenses (series_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_next_occurrence ON recurring_expenses (next_occurrence);
+CREATE INDEX IF NOT EXISTS idx_recurring_expenses_user_id ON recurring_expenses (user_id);
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id         uuid DEFAULT uuid_generate_v4(),
+    base       char(3) NOT NULL,
+    quote      char(3) NOT NULL,
+    date       date NOT NULL,
+    rate       decimal(18,8) NOT NULL,
+    source     varchar(50),
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    CONSTRAINT chk_exchange_rates_rate CHECK (rate > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_exchange_rates_pair_date ON exchange_rates (base, quote, date);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id         uuid DEFAULT uuid_generate_v4(),
+    user_id    uuid NOT NULL,
+    name       varchar(50) NOT NULL,
+    color_code varchar(7),
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tags_user_id ON tags (user_id);
+
+-- "Wedding" and "wedding" are the same tag
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS expense_tags (
+    expense_id uuid,
+    tag_id     uuid,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (expense_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_tags_tag_id ON expense_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS category_rules (
+    id          uuid DEFAULT uuid_generate_v4(),
+    user_id     uuid NOT NULL,
+    name        varchar(100) NOT NULL,
+    category_id uuid NOT NULL,
+    priority    bigint NOT NULL DEFAULT 0,
+    match       varchar(3) NOT NULL DEFAULT 'all',
+    conditions  jsonb NOT NULL,
+    active      boolean NOT NULL,
+    created_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  timestamptz,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_category_rules_deleted_at ON category_rules (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_category_rules_user_id ON category_rules (user_id);
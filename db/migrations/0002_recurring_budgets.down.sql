@@ -0,0 +1,6 @@
+-- Recurring budgets without an end date end today, or on their start date if it is later
+ALTER TABLE budgets DROP CONSTRAINT IF EXISTS chk_budgets_end_date;
+UPDATE budgets SET end_date = GREATEST(start_date, CURRENT_DATE) WHERE end_date IS NULL;
+ALTER TABLE budgets ALTER COLUMN end_date SET NOT NULL;
+ALTER TABLE budgets DROP COLUMN IF EXISTS rollover;
+ALTER TABLE budgets DROP COLUMN IF EXISTS recurrence;
@@ -0,0 +1,6 @@
+-- Recurring budgets repeat weekly, monthly, quarterly or yearly from their start date, optionally rolling
+-- the balance of a period into the next. A recurring budget may have no end date.
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS recurrence varchar(10);
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS rollover varchar(10);
+ALTER TABLE budgets ALTER COLUMN end_date DROP NOT NULL;
+ALTER TABLE budgets ADD CONSTRAINT chk_budgets_end_date CHECK (end_date IS NOT NULL OR recurrence <> '');
@@ -0,0 +1,4 @@
+-- Multi-category budgets would turn into overall budgets, so they are deleted with their categories
+UPDATE budgets SET deleted_at = CURRENT_TIMESTAMP
+WHERE deleted_at IS NULL AND budget_id IN (SELECT budget_id FROM budget_categories);
+DROP TABLE IF EXISTS budget_categories;
@@ -0,0 +1,9 @@
+-- A budget covers its category_id, the categories listed here, or with neither the user's total spending
+CREATE TABLE IF NOT EXISTS budget_categories (
+    budget_id   uuid NOT NULL,
+    category_id uuid NOT NULL,
+    created_at  timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (budget_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_categories_category_id ON budget_categories (category_id);
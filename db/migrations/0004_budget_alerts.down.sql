@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS budget_alert_events;
+ALTER TABLE budgets DROP COLUMN IF EXISTS alert_thresholds;
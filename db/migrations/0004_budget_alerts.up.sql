@@ -0,0 +1,33 @@
+-- Percentages of the available amount that trigger an alert, e.g. [50, 80, 100]
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS alert_thresholds jsonb NOT NULL DEFAULT '[]';
+
+-- One row per threshold reached in a budget period, so each alert fires once
+CREATE TABLE IF NOT EXISTS budget_alert_events (
+    id           uuid DEFAULT uuid_generate_v4(),
+    budget_id    uuid NOT NULL,
+    user_id      uuid NOT NULL,
+    period_start date NOT NULL,
+    threshold    bigint NOT NULL,
+    spent        decimal(10,2) NOT NULL,
+    available    decimal(10,2) NOT NULL,
+    created_at   timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_alert_events_period ON budget_alert_events (budget_id, period_start, threshold);
+CREATE INDEX IF NOT EXISTS idx_budget_alert_events_user_id ON budget_alert_events (user_id);
+
+-- The in-app inbox
+CREATE TABLE IF NOT EXISTS notifications (
+    id         uuid DEFAULT uuid_generate_v4(),
+    user_id    uuid NOT NULL,
+    kind       varchar(30) NOT NULL,
+    title      varchar(255) NOT NULL,
+    message    text,
+    budget_id  uuid,
+    read_at    timestamptz,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications (user_id);
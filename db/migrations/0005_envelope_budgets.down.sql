@@ -0,0 +1,5 @@
+-- Envelopes stay behind as ordinary budgets
+DROP TABLE IF EXISTS envelope_transfers;
+DROP INDEX IF EXISTS idx_budgets_envelope_plan_id;
+ALTER TABLE budgets DROP COLUMN IF EXISTS envelope_plan_id;
+DROP TABLE IF EXISTS envelope_plans;
@@ -0,0 +1,35 @@
+-- Zero-based budgeting: the income of a period is allocated across category envelopes, which are budgets
+CREATE TABLE IF NOT EXISTS envelope_plans (
+    id         uuid DEFAULT uuid_generate_v4(),
+    user_id    uuid NOT NULL,
+    start_date date NOT NULL,
+    end_date   date NOT NULL,
+    income     decimal(10,2) NOT NULL,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    PRIMARY KEY (id),
+    CONSTRAINT chk_envelope_plans_income CHECK (income >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_envelope_plans_user_id ON envelope_plans (user_id);
+CREATE INDEX IF NOT EXISTS idx_envelope_plans_deleted_at ON envelope_plans (deleted_at);
+
+ALTER TABLE budgets ADD COLUMN IF NOT EXISTS envelope_plan_id uuid;
+CREATE INDEX IF NOT EXISTS idx_budgets_envelope_plan_id ON budgets (envelope_plan_id);
+
+-- Money moved between envelopes; a NULL category is the unassigned balance
+CREATE TABLE IF NOT EXISTS envelope_transfers (
+    id               uuid DEFAULT uuid_generate_v4(),
+    plan_id          uuid NOT NULL,
+    user_id          uuid NOT NULL,
+    from_category_id uuid,
+    to_category_id   uuid,
+    amount           decimal(10,2) NOT NULL,
+    note             varchar(255),
+    created_at       timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    CONSTRAINT chk_envelope_transfers_amount CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_envelope_transfers_plan_id ON envelope_transfers (plan_id);
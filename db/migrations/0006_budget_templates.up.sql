@@ -0,0 +1,24 @@
+-- Named sets of category amounts applied to a date range to create several budgets at once
+CREATE TABLE IF NOT EXISTS budget_templates (
+    id         uuid DEFAULT uuid_generate_v4(),
+    user_id    uuid NOT NULL,
+    name       varchar(100) NOT NULL,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_templates_user_id ON budget_templates (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_templates_user_name ON budget_templates (user_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS budget_template_lines (
+    id          uuid DEFAULT uuid_generate_v4(),
+    template_id uuid NOT NULL,
+    category_id uuid NOT NULL,
+    amount      decimal(10,2) NOT NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT chk_budget_template_lines_amount CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budget_template_lines_template_id ON budget_template_lines (template_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_template_lines_category ON budget_template_lines (template_id, category_id);
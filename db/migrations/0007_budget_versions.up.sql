@@ -0,0 +1,41 @@
+-- Every change to a budget, with who made it and the budget before and after
+CREATE TABLE IF NOT EXISTS budget_versions (
+    id         uuid DEFAULT uuid_generate_v4(),
+    budget_id  uuid NOT NULL,
+    user_id    uuid NOT NULL,
+    version    integer NOT NULL,
+    action     varchar(10) NOT NULL,
+    changed_by uuid,
+    old_values jsonb,
+    new_values jsonb,
+    created_at timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_budget_versions_budget_version ON budget_versions (budget_id, version);
+CREATE INDEX IF NOT EXISTS idx_budget_versions_user_id ON budget_versions (user_id);
+
+-- Existing budgets start their history with their current values, as of their creation
+INSERT INTO budget_versions (budget_id, user_id, version, action, new_values, created_at)
+SELECT b.budget_id, b.user_id, 1, 'created',
+       jsonb_strip_nulls(jsonb_build_object(
+           'category_id', b.category_id,
+           'category_ids', (SELECT jsonb_agg(bc.category_id) FROM budget_categories bc WHERE bc.budget_id = b.budget_id),
+           'amount', b.amount,
+           'start_date', to_char(b.start_date, 'YYYY-MM-DD"T00:00:00Z"'),
+           'end_date', to_char(b.end_date, 'YYYY-MM-DD"T00:00:00Z"'),
+           'recurrence', b.recurrence,
+           'rollover', b.rollover,
+           'alert_thresholds', b.alert_thresholds
+       )),
+       b.created_at
+FROM budgets b
+WHERE NOT EXISTS (SELECT 1 FROM budget_versions v WHERE v.budget_id = b.budget_id);
+
+-- Deleted budgets also record their deletion
+INSERT INTO budget_versions (budget_id, user_id, version, action, old_values, created_at)
+SELECT v.budget_id, v.user_id, 2, 'deleted', v.new_values, b.deleted_at
+FROM budget_versions v
+JOIN budgets b ON b.budget_id = v.budget_id
+WHERE v.version = 1 AND b.deleted_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM budget_versions d WHERE d.budget_id = v.budget_id AND d.version = 2);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_budget_alert_events_status;
+ALTER TABLE budget_alert_events DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE budget_alert_events DROP COLUMN IF EXISTS delivered_at;
+ALTER TABLE budget_alert_events DROP COLUMN IF EXISTS next_attempt_at;
+ALTER TABLE budget_alert_events DROP COLUMN IF EXISTS last_error;
+ALTER TABLE budget_alert_events DROP COLUMN IF EXISTS delivered_to;
+ALTER TABLE budget_alert_events DROP COLUMN IF EXISTS attempts;
+ALTER TABLE budget_alert_events DROP COLUMN IF EXISTS payload;
+ALTER TABLE budget_alert_events DROP COLUMN IF EXISTS status;
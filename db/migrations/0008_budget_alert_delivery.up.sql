@@ -0,0 +1,13 @@
+-- Delivery state of each alert, so the alert worker retries deliveries that failed or were cut short by a restart.
+-- Alerts recorded before were sent when they fired.
+ALTER TABLE budget_alert_events ADD COLUMN IF NOT EXISTS status varchar(20) NOT NULL DEFAULT 'delivered';
+ALTER TABLE budget_alert_events ALTER COLUMN status SET DEFAULT 'pending';
+ALTER TABLE budget_alert_events ADD COLUMN IF NOT EXISTS payload jsonb;
+ALTER TABLE budget_alert_events ADD COLUMN IF NOT EXISTS attempts bigint NOT NULL DEFAULT 0;
+ALTER TABLE budget_alert_events ADD COLUMN IF NOT EXISTS delivered_to text;
+ALTER TABLE budget_alert_events ADD COLUMN IF NOT EXISTS last_error text;
+ALTER TABLE budget_alert_events ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz;
+ALTER TABLE budget_alert_events ADD COLUMN IF NOT EXISTS delivered_at timestamptz;
+ALTER TABLE budget_alert_events ADD COLUMN IF NOT EXISTS updated_at timestamptz DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_budget_alert_events_status ON budget_alert_events (status);
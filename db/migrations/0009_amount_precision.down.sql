@@ -0,0 +1,15 @@
+ALTER TABLE receipt_line_items ALTER COLUMN amount TYPE decimal(10,2);
+ALTER TABLE receipt_line_items ALTER COLUMN unit_price TYPE decimal(10,2);
+ALTER TABLE receipt_parses ALTER COLUMN total TYPE decimal(10,2);
+ALTER TABLE receipt_parses ALTER COLUMN tax TYPE decimal(10,2);
+ALTER TABLE receipt_parses ALTER COLUMN subtotal TYPE decimal(10,2);
+ALTER TABLE envelope_transfers ALTER COLUMN amount TYPE decimal(10,2);
+ALTER TABLE envelope_plans ALTER COLUMN income TYPE decimal(10,2);
+ALTER TABLE budget_alert_events ALTER COLUMN available TYPE decimal(10,2);
+ALTER TABLE budget_alert_events ALTER COLUMN spent TYPE decimal(10,2);
+ALTER TABLE budget_template_lines ALTER COLUMN amount TYPE decimal(10,2);
+ALTER TABLE budgets ALTER COLUMN amount TYPE decimal(10,2);
+ALTER TABLE recurring_expenses ALTER COLUMN amount TYPE decimal(10,2);
+ALTER TABLE expense_allocations ALTER COLUMN amount TYPE decimal(10,2);
+ALTER TABLE expenses ALTER COLUMN original_amount TYPE decimal(10,2);
+ALTER TABLE expenses ALTER COLUMN amount TYPE decimal(10,2);
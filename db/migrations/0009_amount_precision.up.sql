@@ -0,0 +1,17 @@
+-- Amounts keep three decimals for currencies with three minor units (KWD, BHD, OMR, ...), as paid and when one
+-- of them is the home currency
+ALTER TABLE expenses ALTER COLUMN amount TYPE decimal(12,3);
+ALTER TABLE expenses ALTER COLUMN original_amount TYPE decimal(12,3);
+ALTER TABLE expense_allocations ALTER COLUMN amount TYPE decimal(12,3);
+ALTER TABLE recurring_expenses ALTER COLUMN amount TYPE decimal(12,3);
+ALTER TABLE budgets ALTER COLUMN amount TYPE decimal(12,3);
+ALTER TABLE budget_template_lines ALTER COLUMN amount TYPE decimal(12,3);
+ALTER TABLE budget_alert_events ALTER COLUMN spent TYPE decimal(12,3);
+ALTER TABLE budget_alert_events ALTER COLUMN available TYPE decimal(12,3);
+ALTER TABLE envelope_plans ALTER COLUMN income TYPE decimal(12,3);
+ALTER TABLE envelope_transfers ALTER COLUMN amount TYPE decimal(12,3);
+ALTER TABLE receipt_parses ALTER COLUMN subtotal TYPE decimal(12,3);
+ALTER TABLE receipt_parses ALTER COLUMN tax TYPE decimal(12,3);
+ALTER TABLE receipt_parses ALTER COLUMN total TYPE decimal(12,3);
+ALTER TABLE receipt_line_items ALTER COLUMN unit_price TYPE decimal(12,3);
+ALTER TABLE receipt_line_items ALTER COLUMN amount TYPE decimal(12,3);
@@ -1,53 +1,272 @@
 package db
 
 import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
 	"expense-mgmt/internal/models"
+	"fmt"
+	"io/fs"
 	"log"
+	"sort"
+	"time"
 
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
 	"gorm.io/gorm"
 )
 
-var defaultExpenseCategories = []models.Category{
-	{Name: "Food & Dining", Description: "Restaurants, groceries, and food delivery", ColorCode: "#FFD700"},
-	{Name: "Transportation", Description: "Public transport, fuel, car maintenance", ColorCode: "#4682B4"},
-	{Name: "Housing", Description: "Rent, mortgage, utilities, maintenance", ColorCode: "#FF6347"},
-	{Name: "Entertainment", Description: "Movies, games, hobbies, streaming services", ColorCode: "#8A2BE2"},
-	{Name: "Shopping", Description: "Clothing, electronics, personal items", ColorCode: "#32CD32"},
-	{Name: "Healthcare", Description: "Medical expenses, medications, insurance", ColorCode: "#FF4500"},
-	{Name: "Education", Description: "Tuition, books, courses, training", ColorCode: "#4682B4"},
-	{Name: "Utilities", Description: "Electricity, water, internet, phone", ColorCode: "#A9A9A9"},
-	{Name: "Travel", Description: "Vacations, business trips, accommodations", ColorCode: "#20B2AA"},
-	{Name: "Insurance", Description: "Health, life, car, home insurance", ColorCode: "#FF8C00"},
-	{Name: "Others", Description: "Miscellaneous expenses", ColorCode: "#A9A9A9"},
-}
-
-// Seed the database by creating default categories..
+// seedManifests holds the versioned default category manifests. A manifest is never edited once shipped;
+// changes to the defaults go into a new manifest with the next version.
+//
+//go:embed seeds/*.yaml
+var seedManifests embed.FS
+
+// seedLockID serializes seeding across instances starting at the same time
+const seedLockID = 7240115001
+
+// seedManifest is one version of the default categories: the categories it adds or updates and those it retires
+type seedManifest struct {
+	Version     int            `yaml:"version"`
+	Description string         `yaml:"description"`
+	Categories  []seedCategory `yaml:"categories"`
+	Retire      []string       `yaml:"retire"` // Keys of default categories to archive
+
+	checksum string
+}
+
+// seedCategory is a default category identified by its key. Fields left out keep their current value on an
+// existing category, including its parent for an entry listed at the top level without a parent key.
+// Subcategories inherit the color of their parent unless they set one.
+type seedCategory struct {
+	Key           string         `yaml:"key"`
+	Name          string         `yaml:"name"`
+	Description   *string        `yaml:"description"`
+	ColorCode     string         `yaml:"color_code"`
+	Parent        string         `yaml:"parent"` // Key of the parent category for a subcategory listed at the top level
+	Subcategories []seedCategory `yaml:"subcategories"`
+}
+
+// SeedDefaultCategories applies the seed manifests that have not been applied to the database yet, in version order.
+// Each manifest is recorded in seed_versions, so new defaults, updates and retirements ship with a new manifest.
+// The tables are created by the schema migrations, which must have run first.
 func SeedDefaultCategories(db *gorm.DB) error {
-	// Ensure the categories table exists
-	if err := db.AutoMigrate(&models.Category{}); err != nil {
+	manifests, err := loadSeedManifests()
+	if err != nil {
 		return err
 	}
 
-	// Check if any categories already exist
-	var count int64
-	if err := db.Model(&models.Category{}).Count(&count).Error; err != nil {
-		return err
-	}
-	if count > 0 {
-		log.Println("Categories already seeded, skipping...")
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", seedLockID).Error; err != nil {
+			return err
+		}
+
+		var applied []models.SeedVersion
+		if err := tx.Find(&applied).Error; err != nil {
+			return err
+		}
+		appliedChecksums := make(map[int]string, len(applied))
+		for _, version := range applied {
+			appliedChecksums[version.Version] = version.Checksum
+		}
+
+		pending := 0
+		for _, manifest := range manifests {
+			if checksum, ok := appliedChecksums[manifest.Version]; ok {
+				if checksum != manifest.checksum {
+					log.Printf("Seed manifest %d changed after it was applied; add a new version instead", manifest.Version)
+				}
+				continue
+			}
+			if err := applySeedManifest(tx, manifest); err != nil {
+				return fmt.Errorf("seed manifest %d: %w", manifest.Version, err)
+			}
+			pending++
+		}
+
+		if pending == 0 {
+			log.Println("Categories already seeded, skipping...")
+		}
 		return nil
+	})
+}
+
+// loadSeedManifests parses and validates the embedded manifests, ordered by version
+func loadSeedManifests() ([]seedManifest, error) {
+	files, err := fs.Glob(seedManifests, "seeds/*.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []seedManifest
+	versions := map[int]string{}
+	for _, file := range files {
+		content, err := seedManifests.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var manifest seedManifest
+		if err := yaml.Unmarshal(content, &manifest); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		if manifest.Version <= 0 {
+			return nil, fmt.Errorf("%s: version must be a positive number", file)
+		}
+		if other, ok := versions[manifest.Version]; ok {
+			return nil, fmt.Errorf("%s: version %d is also used by %s", file, manifest.Version, other)
+		}
+		versions[manifest.Version] = file
+		if err := validateSeedCategories(manifest.Categories, map[string]bool{}); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		sum := sha256.Sum256(content)
+		manifest.checksum = hex.EncodeToString(sum[:])
+		manifests = append(manifests, manifest)
 	}
 
-	// Seed default categories
-	for _, category := range defaultExpenseCategories {
-		// Assign a blank user ID for default categories (or adjust as needed)
-		category.UserID = nil // or some predefined value if needed
-    category.IsDefault = true
-		if err := db.Create(&category).Error; err != nil {
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].Version < manifests[j].Version })
+	return manifests, nil
+}
+
+// validateSeedCategories checks that every category has a key used only once in the manifest
+func validateSeedCategories(categories []seedCategory, keys map[string]bool) error {
+	for _, category := range categories {
+		if category.Key == "" {
+			return fmt.Errorf("category %q has no key", category.Name)
+		}
+		if keys[category.Key] {
+			return fmt.Errorf("category key %q is listed twice", category.Key)
+		}
+		keys[category.Key] = true
+		if err := validateSeedCategories(category.Subcategories, keys); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
-	log.Println("Successfully seeded default categories")
+// applySeedManifest adds or updates the manifest's categories, retires the listed ones and records the version
+func applySeedManifest(tx *gorm.DB, manifest seedManifest) error {
+	created, updated := 0, 0
+	var seed func(categories []seedCategory, parent *models.Category) error
+	seed = func(categories []seedCategory, parent *models.Category) error {
+		for _, entry := range categories {
+			parentID, parentColor, err := seedParent(tx, entry, parent)
+			if err != nil {
+				return err
+			}
+			category, isNew, err := seedDefaultCategory(tx, entry, parentID, parentColor)
+			if err != nil {
+				return err
+			}
+			if isNew {
+				created++
+			} else {
+				updated++
+			}
+			if err := seed(entry.Subcategories, &category); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := seed(manifest.Categories, nil); err != nil {
+		return err
+	}
+
+	// Retired defaults are archived rather than deleted, so expenses, budgets and rules keep their category
+	retired := int64(0)
+	if len(manifest.Retire) > 0 {
+		result := tx.Model(&models.Category{}).
+			Where("is_default = ? AND seed_key IN ? AND archived_at IS NULL", true, manifest.Retire).
+			Update("archived_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		retired = result.RowsAffected
+	}
+
+	if err := tx.Create(&models.SeedVersion{
+		Version:     manifest.Version,
+		Description: manifest.Description,
+		Checksum:    manifest.checksum,
+	}).Error; err != nil {
+		return err
+	}
+
+	log.Printf("Applied seed manifest %d (%s): %d default categories created, %d updated, %d retired",
+		manifest.Version, manifest.Description, created, updated, retired)
 	return nil
 }
+
+// seedParent resolves the parent of a manifest entry: the category it is nested under, or the one named by its
+// parent key. It returns nil for a top-level category, along with the color a subcategory inherits.
+func seedParent(tx *gorm.DB, entry seedCategory, parent *models.Category) (*uuid.UUID, string, error) {
+	if parent != nil {
+		return &parent.ID, parent.ColorCode, nil
+	}
+	if entry.Parent == "" {
+		return nil, "", nil
+	}
+	var keyed models.Category
+	if err := tx.Where("is_default = ? AND seed_key = ?", true, entry.Parent).First(&keyed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", fmt.Errorf("category %q: unknown parent key %q", entry.Key, entry.Parent)
+		}
+		return nil, "", err
+	}
+	return &keyed.ID, keyed.ColorCode, nil
+}
+
+// seedDefaultCategory creates or updates the default category with the entry's key. A default category seeded
+// before keys existed, with the same name under the same parent, is adopted instead of duplicated.
+func seedDefaultCategory(tx *gorm.DB, entry seedCategory, parentID *uuid.UUID, parentColor string) (models.Category, bool, error) {
+	var existing models.Category
+	err := tx.Where("is_default = ? AND seed_key = ?", true, entry.Key).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		query := tx.Where("is_default = ? AND user_id IS NULL AND seed_key IS NULL AND name = ?", true, entry.Name)
+		if parentID == nil {
+			query = query.Where("parent_id IS NULL")
+		} else {
+			query = query.Where("parent_id = ?", *parentID)
+		}
+		err = query.First(&existing).Error
+	}
+	isNew := errors.Is(err, gorm.ErrRecordNotFound)
+	if err != nil && !isNew {
+		return models.Category{}, false, err
+	}
+
+	if isNew && entry.Name == "" {
+		return models.Category{}, false, fmt.Errorf("new category %q has no name", entry.Key)
+	}
+	key := entry.Key
+	category := existing
+	category.SeedKey = &key
+	category.UserID = nil
+	category.IsDefault = true
+	if isNew || parentID != nil {
+		category.ParentID = parentID
+	}
+	category.ArchivedAt = nil // Listing a retired key again brings it back
+	if entry.Name != "" {
+		category.Name = entry.Name
+	}
+	if entry.Description != nil {
+		category.Description = *entry.Description
+	}
+	if entry.ColorCode != "" {
+		category.ColorCode = entry.ColorCode
+	} else if parentID != nil {
+		category.ColorCode = parentColor
+	}
+
+	if isNew {
+		err = tx.Create(&category).Error
+	} else {
+		err = tx.Save(&category).Error
+	}
+	return category, isNew, err
+}
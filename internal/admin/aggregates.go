@@ -0,0 +1,67 @@
+package admin
+
+import (
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/recurrence"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AggregateReport counts the stored aggregates that were checked and those that had drifted
+type AggregateReport struct {
+	RecurringExpenses int `json:"recurring_expenses"` // Templates whose occurrence count and next occurrence were recomputed
+	Updated           int `json:"updated"`            // Templates whose stored values changed
+	Skipped           int `json:"skipped"`            // Templates with a rule that no longer parses
+}
+
+// RecomputeAggregates recomputes the values stored alongside the data they summarize, currently the occurrence
+// count and next occurrence of recurring expense templates. Each template is locked while it is refreshed so a
+// scheduler running at the same time cannot interleave.
+func RecomputeAggregates(database *gorm.DB) (AggregateReport, error) {
+	var report AggregateReport
+
+	var templateIDs []string
+	if err := database.Model(&models.RecurringExpense{}).Order("created_at").Pluck("id", &templateIDs).Error; err != nil {
+		return report, err
+	}
+
+	for _, templateID := range templateIDs {
+		err := database.Transaction(func(tx *gorm.DB) error {
+			var template models.RecurringExpense
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&template, "id = ?", templateID).Error; err != nil {
+				return err
+			}
+			rule, err := recurrence.Parse(template.Rule)
+			if err != nil {
+				log.Printf("Skipping recurring expense %s with invalid rule %q: %v", template.ID, template.Rule, err)
+				report.Skipped++
+				return nil
+			}
+
+			count, next := template.OccurrenceCount, template.NextOccurrence
+			if err := recurrence.Refresh(tx, &template, rule); err != nil {
+				return err
+			}
+			report.RecurringExpenses++
+			if count != template.OccurrenceCount || !sameDate(next, template.NextOccurrence) {
+				report.Updated++
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// sameDate reports whether two optional dates are both unset or fall on the same day
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
@@ -0,0 +1,154 @@
+package admin
+
+import (
+	"encoding/json"
+	"expense-mgmt/internal/models"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// exportedUser is the user's profile without credentials
+type exportedUser struct {
+	UserID          uuid.UUID `json:"user_id"`
+	FirstName       string    `json:"first_name"`
+	LastName        string    `json:"last_name"`
+	Email           string    `json:"email"`
+	IsEmailVerified bool      `json:"is_email_verified"`
+	Currency        string    `json:"currency"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// UserExport is everything the service stores about one user. Soft-deleted rows are included, with deleted_at set,
+// until they are purged.
+type UserExport struct {
+	ExportedAt          time.Time                   `json:"exported_at"`
+	User                exportedUser                `json:"user"`
+	Categories          []models.Category           `json:"categories"` // Custom categories, archived ones included
+	CategoryPreferences []models.CategoryPreference `json:"category_preferences"`
+	Tags                []models.Tag                `json:"tags"`
+	Expenses            []models.Expense            `json:"expenses"` // With their allocations and tag IDs
+	Budgets             []models.Budget             `json:"budgets"`
+	BudgetAlerts        []models.BudgetAlertEvent   `json:"budget_alerts"`
+	BudgetVersions      []models.BudgetVersion      `json:"budget_versions"`
+	BudgetTemplates     []models.BudgetTemplate     `json:"budget_templates"` // With their lines
+	EnvelopePlans       []models.EnvelopePlan       `json:"envelope_plans"`
+	EnvelopeTransfers   []models.EnvelopeTransfer   `json:"envelope_transfers"`
+	RecurringExpenses   []models.RecurringExpense   `json:"recurring_expenses"`
+	Rules               []models.CategoryRule       `json:"rules"`
+	Receipts            []models.Receipt            `json:"receipts"` // Metadata and OCR text; images stay in blob storage
+	Notifications       []models.Notification       `json:"notifications"`
+}
+
+// ExportUser writes everything stored about the user as indented JSON, e.g. to answer a data access request
+func ExportUser(database *gorm.DB, userID uuid.UUID, w io.Writer) error {
+	export := UserExport{ExportedAt: time.Now().UTC()}
+
+	var user models.User
+	if err := database.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return err
+	}
+	export.User = exportedUser{
+		UserID:          user.UserID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		IsEmailVerified: user.IsEmailVerified,
+		Currency:        user.Currency,
+		CreatedAt:       user.CreatedAt,
+	}
+
+	queries := []struct {
+		dest  interface{}
+		query *gorm.DB
+	}{
+		{&export.Categories, database.Unscoped().Where("user_id = ?", userID).Order("created_at")},
+		{&export.CategoryPreferences, database.Where("user_id = ?", userID)},
+		{&export.Tags, database.Where("user_id = ?", userID).Order("name")},
+		{&export.Expenses, database.Where("user_id = ?", userID).Order("date, created_at")},
+		{&export.Budgets, database.Unscoped().Where("user_id = ?", userID).Order("start_date")},
+		{&export.BudgetAlerts, database.Where("user_id = ?", userID).Order("created_at")},
+		{&export.BudgetVersions, database.Where("user_id = ?", userID).Order("budget_id, version")},
+		{&export.BudgetTemplates, database.Where("user_id = ?", userID).Order("name")},
+		{&export.EnvelopePlans, database.Unscoped().Where("user_id = ?", userID).Order("start_date")},
+		{&export.EnvelopeTransfers, database.Where("user_id = ?", userID).Order("created_at")},
+		{&export.RecurringExpenses, database.Unscoped().Where("user_id = ?", userID).Order("start_date")},
+		{&export.Rules, database.Unscoped().Where("user_id = ?", userID).Order("priority")},
+		{&export.Receipts, database.Unscoped().Where("user_id = ?", userID).Order("date")},
+		{&export.Notifications, database.Where("user_id = ?", userID).Order("created_at")},
+	}
+	for _, q := range queries {
+		if err := q.query.Find(q.dest).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := fillExpenseDetails(database, userID, export.Expenses); err != nil {
+		return err
+	}
+	if err := models.FillBudgetCategories(database, export.Budgets); err != nil {
+		return err
+	}
+	if err := fillTemplateLines(database, export.BudgetTemplates); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+// fillExpenseDetails sets the allocations and tag IDs of the user's expenses
+func fillExpenseDetails(database *gorm.DB, userID uuid.UUID, expenses []models.Expense) error {
+	userExpenses := database.Model(&models.Expense{}).Select("expense_id").Where("user_id = ?", userID)
+
+	var allocations []models.ExpenseAllocation
+	if err := database.Where("expense_id IN (?)", userExpenses).Order("created_at").Find(&allocations).Error; err != nil {
+		return err
+	}
+	var links []models.ExpenseTag
+	if err := database.Where("expense_id IN (?)", userExpenses).Find(&links).Error; err != nil {
+		return err
+	}
+
+	byExpense := make(map[uuid.UUID]*models.Expense, len(expenses))
+	for i := range expenses {
+		byExpense[expenses[i].ExpenseID] = &expenses[i]
+	}
+	for _, allocation := range allocations {
+		if expense, ok := byExpense[allocation.ExpenseID]; ok {
+			expense.Allocations = append(expense.Allocations, allocation)
+		}
+	}
+	for _, link := range links {
+		if expense, ok := byExpense[link.ExpenseID]; ok {
+			expense.TagIDs = append(expense.TagIDs, link.TagID)
+		}
+	}
+	return nil
+}
+
+// fillTemplateLines sets the lines of the user's budget templates
+func fillTemplateLines(database *gorm.DB, templates []models.BudgetTemplate) error {
+	byTemplate := make(map[uuid.UUID]*models.BudgetTemplate, len(templates))
+	ids := make([]uuid.UUID, len(templates))
+	for i := range templates {
+		byTemplate[templates[i].ID] = &templates[i]
+		ids[i] = templates[i].ID
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var lines []models.BudgetTemplateLine
+	if err := database.Where("template_id IN ?", ids).Find(&lines).Error; err != nil {
+		return err
+	}
+	for _, line := range lines {
+		template := byTemplate[line.TemplateID]
+		template.Lines = append(template.Lines, line)
+	}
+	return nil
+}
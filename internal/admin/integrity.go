@@ -0,0 +1,80 @@
+package admin
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Receipt link problems reported by VerifyIntegrity
+const (
+	LinkExpenseMissing = "expense_missing"  // The receipt points at an expense that does not exist
+	LinkExpenseNotBack = "expense_not_back" // The expense the receipt points at links another receipt or none
+	LinkReceiptMissing = "receipt_missing"  // The expense points at a receipt that does not exist or was deleted
+	LinkReceiptNotBack = "receipt_not_back" // The receipt the expense points at links another expense or none
+)
+
+// MissingCategory is an expense, or one allocation of a split expense, whose category no longer exists
+type MissingCategory struct {
+	ExpenseID    uuid.UUID  `json:"expense_id"`
+	AllocationID *uuid.UUID `json:"allocation_id,omitempty"`
+	UserID       uuid.UUID  `json:"user_id"`
+	CategoryID   uuid.UUID  `json:"category_id"`
+}
+
+// ReceiptLink is a link between a receipt and an expense that only exists on one side
+type ReceiptLink struct {
+	ReceiptID uuid.UUID `json:"receipt_id"`
+	ExpenseID uuid.UUID `json:"expense_id"`
+	Problem   string    `json:"problem"`
+}
+
+// IntegrityReport lists the inconsistencies found in the data
+type IntegrityReport struct {
+	MissingCategories []MissingCategory `json:"missing_categories"`
+	ReceiptLinks      []ReceiptLink     `json:"receipt_links"`
+}
+
+// Problems is the number of inconsistencies in the report
+func (r IntegrityReport) Problems() int {
+	return len(r.MissingCategories) + len(r.ReceiptLinks)
+}
+
+// VerifyIntegrity finds expenses and allocations whose category is missing or deleted, and receipts and expenses
+// whose link is not mirrored on the other side. It only reads; fixing the data is left to the operator.
+func VerifyIntegrity(database *gorm.DB) (IntegrityReport, error) {
+	report := IntegrityReport{MissingCategories: []MissingCategory{}, ReceiptLinks: []ReceiptLink{}}
+
+	if err := database.Raw(`SELECT e.expense_id, NULL AS allocation_id, e.user_id, e.category_id
+		FROM expenses e
+		LEFT JOIN categories c ON c.id = e.category_id AND c.deleted_at IS NULL
+		WHERE c.id IS NULL
+		UNION ALL
+		SELECT e.expense_id, a.id AS allocation_id, e.user_id, a.category_id
+		FROM expense_allocations a
+		JOIN expenses e ON e.expense_id = a.expense_id
+		LEFT JOIN categories c ON c.id = a.category_id AND c.deleted_at IS NULL
+		WHERE c.id IS NULL
+		ORDER BY expense_id`).Scan(&report.MissingCategories).Error; err != nil {
+		return report, err
+	}
+
+	if err := database.Raw(`SELECT r.id AS receipt_id, r.expense_id,
+			CASE WHEN e.expense_id IS NULL THEN ? ELSE ? END AS problem
+		FROM receipts r
+		LEFT JOIN expenses e ON e.expense_id = r.expense_id
+		WHERE r.deleted_at IS NULL AND r.expense_id IS NOT NULL
+			AND (e.expense_id IS NULL OR e.receipt_id IS DISTINCT FROM r.id)
+		UNION ALL
+		SELECT e.receipt_id, e.expense_id,
+			CASE WHEN r.id IS NULL THEN ? ELSE ? END AS problem
+		FROM expenses e
+		LEFT JOIN receipts r ON r.id = e.receipt_id AND r.deleted_at IS NULL
+		WHERE e.receipt_id IS NOT NULL
+			AND (r.id IS NULL OR r.expense_id IS DISTINCT FROM e.expense_id)
+		ORDER BY receipt_id`,
+		LinkExpenseMissing, LinkExpenseNotBack, LinkReceiptMissing, LinkReceiptNotBack).
+		Scan(&report.ReceiptLinks).Error; err != nil {
+		return report, err
+	}
+	return report, nil
+}
@@ -0,0 +1,122 @@
+package admin
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// errPurgeDryRun rolls back a dry-run purge once the counts are known
+var errPurgeDryRun = errors.New("dry run")
+
+// PurgeReport counts the soft-deleted rows removed for good, per table
+type PurgeReport struct {
+	Receipts          int64 `json:"receipts"`
+	Budgets           int64 `json:"budgets"`
+	BudgetVersions    int64 `json:"budget_versions"` // Only purged on request; kept by default as the budgets' change history
+	Rules             int64 `json:"rules"`
+	RecurringExpenses int64 `json:"recurring_expenses"`
+	EnvelopePlans     int64 `json:"envelope_plans"`
+	Categories        int64 `json:"categories"`
+	CategoriesKept    int64 `json:"categories_kept"` // Deleted categories still referenced by expenses, budgets or subcategories
+}
+
+// PurgeDeleted permanently removes the rows soft-deleted before the cutoff, in one transaction. Expenses keep
+// standing on their own: links to purged receipts and recurring templates are cleared, and a deleted category
+// that is still referenced is kept. The versions of purged budgets are kept unless purgeVersions is set. With
+// dryRun the counts are computed and everything is rolled back.
+func PurgeDeleted(database *gorm.DB, before time.Time, purgeVersions, dryRun bool) (PurgeReport, error) {
+	var report PurgeReport
+	err := database.Transaction(func(tx *gorm.DB) error {
+		purgedReceipts := "SELECT id FROM receipts WHERE deleted_at < ?"
+		for _, statement := range []string{
+			"UPDATE expenses SET receipt_id = NULL WHERE receipt_id IN (" + purgedReceipts + ")",
+			"DELETE FROM receipt_line_items WHERE receipt_id IN (" + purgedReceipts + ")",
+			"DELETE FROM receipt_parses WHERE receipt_id IN (" + purgedReceipts + ")",
+		} {
+			if err := tx.Exec(statement, before).Error; err != nil {
+				return err
+			}
+		}
+		if err := purge(tx, "DELETE FROM receipts WHERE deleted_at < ?", before, &report.Receipts); err != nil {
+			return err
+		}
+
+		for _, statement := range []string{
+			"DELETE FROM budget_categories WHERE budget_id IN (SELECT budget_id FROM budgets WHERE deleted_at < ?)",
+			"DELETE FROM budget_alert_events WHERE budget_id IN (SELECT budget_id FROM budgets WHERE deleted_at < ?)",
+		} {
+			if err := tx.Exec(statement, before).Error; err != nil {
+				return err
+			}
+		}
+		if purgeVersions {
+			if err := purge(tx, "DELETE FROM budget_versions WHERE budget_id IN (SELECT budget_id FROM budgets WHERE deleted_at < ?)",
+				before, &report.BudgetVersions); err != nil {
+				return err
+			}
+		}
+		if err := purge(tx, "DELETE FROM budgets WHERE deleted_at < ?", before, &report.Budgets); err != nil {
+			return err
+		}
+		if err := purge(tx, "DELETE FROM category_rules WHERE deleted_at < ?", before, &report.Rules); err != nil {
+			return err
+		}
+
+		// A plan goes with its transfer history, once none of its envelopes is left
+		purgedPlans := `SELECT id FROM envelope_plans WHERE deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM budgets b WHERE b.envelope_plan_id = envelope_plans.id)`
+		if err := tx.Exec("DELETE FROM envelope_transfers WHERE plan_id IN ("+purgedPlans+")", before).Error; err != nil {
+			return err
+		}
+		if err := purge(tx, "DELETE FROM envelope_plans WHERE id IN ("+purgedPlans+")", before, &report.EnvelopePlans); err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`UPDATE expenses SET recurring_expense_id = NULL
+			WHERE recurring_expense_id IN (SELECT id FROM recurring_expenses WHERE deleted_at < ?)`, before).Error; err != nil {
+			return err
+		}
+		if err := purge(tx, "DELETE FROM recurring_expenses WHERE deleted_at < ?", before, &report.RecurringExpenses); err != nil {
+			return err
+		}
+
+		// Rows left behind, including soft-deleted ones that are too recent, still need their category
+		unreferenced := `deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM expenses e WHERE e.category_id = categories.id)
+			AND NOT EXISTS (SELECT 1 FROM expense_allocations a WHERE a.category_id = categories.id)
+			AND NOT EXISTS (SELECT 1 FROM budgets b WHERE b.category_id = categories.id)
+			AND NOT EXISTS (SELECT 1 FROM budget_categories bc WHERE bc.category_id = categories.id)
+			AND NOT EXISTS (SELECT 1 FROM budget_template_lines tl WHERE tl.category_id = categories.id)
+			AND NOT EXISTS (SELECT 1 FROM envelope_transfers et WHERE categories.id IN (et.from_category_id, et.to_category_id))
+			AND NOT EXISTS (SELECT 1 FROM recurring_expenses r WHERE r.category_id = categories.id)
+			AND NOT EXISTS (SELECT 1 FROM category_rules cr WHERE cr.category_id = categories.id)
+			AND NOT EXISTS (SELECT 1 FROM categories child WHERE child.parent_id = categories.id)`
+		if err := tx.Exec("DELETE FROM category_preferences WHERE category_id IN (SELECT id FROM categories WHERE "+unreferenced+")", before).Error; err != nil {
+			return err
+		}
+		if err := purge(tx, "DELETE FROM categories WHERE "+unreferenced, before, &report.Categories); err != nil {
+			return err
+		}
+		if err := tx.Raw("SELECT COUNT(*) FROM categories WHERE deleted_at < ?", before).Scan(&report.CategoriesKept).Error; err != nil {
+			return err
+		}
+
+		if dryRun {
+			return errPurgeDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errPurgeDryRun) {
+		err = nil
+	}
+	return report, err
+}
+
+// purge runs a delete statement and records how many rows it removed
+func purge(tx *gorm.DB, statement string, before time.Time, count *int64) error {
+	result := tx.Exec(statement, before)
+	*count = result.RowsAffected
+	return result.Error
+}
@@ -0,0 +1,30 @@
+package alerts
+
+import (
+	"context"
+	"expense-mgmt/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// InboxNotifier stores each alert as a notification in the user's in-app inbox
+type InboxNotifier struct {
+	DB *gorm.DB
+}
+
+// Name identifies the notifier in logs
+func (n *InboxNotifier) Name() string {
+	return "inbox"
+}
+
+// Notify adds the alert to the inbox
+func (n *InboxNotifier) Notify(ctx context.Context, alert Alert) error {
+	budgetID := alert.BudgetID
+	return n.DB.WithContext(ctx).Create(&models.Notification{
+		UserID:   alert.UserID,
+		Kind:     models.NotificationBudgetAlert,
+		Title:    alert.Subject(),
+		Message:  alert.Message(),
+		BudgetID: &budgetID,
+	}).Error
+}
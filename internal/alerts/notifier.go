@@ -0,0 +1,120 @@
+package alerts
+
+import (
+	"context"
+	"expense-mgmt/configs"
+	"expense-mgmt/internal/money"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Alert reports that the spending in a budget period reached one of the budget's thresholds
+type Alert struct {
+	EventID     uuid.UUID     `json:"event_id"`
+	UserID      uuid.UUID     `json:"user_id"`
+	Email       string        `json:"-"` // Recipient of email alerts
+	BudgetID    uuid.UUID     `json:"budget_id"`
+	Budget      string        `json:"budget"` // Names of the budget's categories
+	Threshold   int           `json:"threshold"`
+	PeriodStart time.Time     `json:"period_start"`
+	PeriodEnd   time.Time     `json:"period_end"`
+	Available   money.Decimal `json:"available"`
+	Spent       money.Decimal `json:"total_spent"`
+	Percentage  money.Decimal `json:"percentage_spent"`
+	Currency    string        `json:"currency"`
+}
+
+// Subject is a one-line summary of the alert
+func (a Alert) Subject() string {
+	return fmt.Sprintf("%s budget reached %d%%", a.Budget, a.Threshold)
+}
+
+// Message describes the alert in a sentence
+func (a Alert) Message() string {
+	return fmt.Sprintf("You have spent %s of %s %s (%s%%) in your %s budget for %s to %s.",
+		a.Spent, a.Available, a.Currency, a.Percentage, a.Budget,
+		a.PeriodStart.Format("2006-01-02"), a.PeriodEnd.Format("2006-01-02"))
+}
+
+// Notifier delivers budget alerts through one channel
+type Notifier interface {
+	// Name identifies the notifier in logs
+	Name() string
+	// Notify delivers the alert
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// NewNotifiers builds the notifiers listed in the configuration, comma-separated; "none" disables delivery
+func NewNotifiers(database *gorm.DB, config *configs.Config) ([]Notifier, error) {
+	var notifiers []Notifier
+	for _, name := range strings.Split(config.Alerts.Notifiers, ",") {
+		switch strings.TrimSpace(name) {
+		case "", "none":
+		case "inbox":
+			notifiers = append(notifiers, &InboxNotifier{DB: database})
+		case "webhook":
+			if config.Alerts.WebhookURL == "" {
+				return nil, fmt.Errorf("alerts.webhook_url is required for the webhook notifier")
+			}
+			notifiers = append(notifiers, &WebhookNotifier{URL: config.Alerts.WebhookURL, Secret: config.Alerts.WebhookSecret})
+		case "smtp":
+			if config.Alerts.SMTPHost == "" || config.Alerts.SMTPFrom == "" {
+				return nil, fmt.Errorf("alerts.smtp_host and alerts.smtp_from are required for the smtp notifier")
+			}
+			notifiers = append(notifiers, &SMTPNotifier{
+				Host:     config.Alerts.SMTPHost,
+				Port:     config.Alerts.SMTPPort,
+				Username: config.Alerts.SMTPUsername,
+				Password: config.Alerts.SMTPPassword,
+				From:     config.Alerts.SMTPFrom,
+			})
+		default:
+			return nil, fmt.Errorf("unsupported alert notifier %q", name)
+		}
+	}
+	return notifiers, nil
+}
+
+var defaultWorker *Worker
+
+// Start builds the configured notifiers and starts the worker delivering the recorded alerts through them
+func Start(database *gorm.DB) error {
+	config := configs.LoadConfig() // Load the configuration
+
+	notifiers, err := NewNotifiers(database, config)
+	if err != nil {
+		return err
+	}
+
+	worker := &Worker{
+		DB:           database,
+		Notifiers:    notifiers,
+		MaxAttempts:  config.Alerts.MaxAttempts,
+		PollInterval: time.Duration(config.Alerts.PollIntervalSeconds) * time.Second,
+	}
+	worker.Start(context.Background())
+	defaultWorker = worker
+
+	names := make([]string, len(notifiers))
+	for i, notifier := range notifiers {
+		names[i] = notifier.Name()
+	}
+	if len(names) == 0 {
+		log.Println("No alert notifiers configured, budget alerts are only recorded")
+	} else {
+		log.Printf("Budget alerts delivered through: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// Notify wakes the running worker so a freshly recorded alert is delivered without waiting for the next poll
+func Notify() {
+	if defaultWorker != nil {
+		defaultWorker.Notify()
+	}
+}
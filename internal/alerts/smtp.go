@@ -0,0 +1,63 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPNotifier emails each alert to the user. Without a username it sends unauthenticated, which is what local
+// fake SMTP servers used for testing expect.
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Name identifies the notifier in logs
+func (n *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+// Notify sends the alert to the user's email address
+func (n *SMTPNotifier) Notify(ctx context.Context, alert Alert) error {
+	if alert.Email == "" {
+		return fmt.Errorf("user %s has no email address", alert.UserID)
+	}
+	port := n.Port
+	if port == 0 {
+		port = 25
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "From: %s\r\n", n.From)
+	fmt.Fprintf(&message, "To: %s\r\n", alert.Email)
+	fmt.Fprintf(&message, "Subject: %s\r\n", alert.Subject())
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	message.WriteString(alert.Message() + "\r\n")
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	// smtp.SendMail takes no context, so the deadline is enforced around it
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(n.Host, strconv.Itoa(port)), auth, n.From, []string{alert.Email}, []byte(message.String()))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
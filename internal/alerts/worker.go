@@ -0,0 +1,229 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expense-mgmt/internal/models"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultMaxAttempts  = 5
+	defaultPollInterval = 30 * time.Second
+	retryBaseDelay      = time.Minute      // Delay before the first retry, doubled on each further attempt
+	staleAfter          = 10 * time.Minute // Alerts stuck in sending this long are picked up again
+	deliveryTimeout     = 30 * time.Second // Bounds the delivery of one alert through one notifier
+)
+
+// Worker delivers the recorded budget alerts through the notifiers in the background. A delivery that fails is
+// retried with exponential backoff, and only through the notifiers that have not delivered the alert yet.
+type Worker struct {
+	DB           *gorm.DB
+	Notifiers    []Notifier
+	MaxAttempts  int
+	PollInterval time.Duration
+
+	wake chan struct{}
+}
+
+// Start launches the worker goroutine; it stops when ctx is cancelled
+func (w *Worker) Start(ctx context.Context) {
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = defaultMaxAttempts
+	}
+	if w.PollInterval <= 0 {
+		w.PollInterval = defaultPollInterval
+	}
+	w.wake = make(chan struct{}, 1)
+
+	go w.run(ctx)
+}
+
+// Notify wakes the worker goroutine
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+}
+
+func (w *Worker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain every alert that is due before going back to sleep
+		for ctx.Err() == nil {
+			processed, err := w.ProcessNext(ctx)
+			if err != nil {
+				log.Printf("Alert worker error: %v", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// ProcessNext claims one alert that is due and delivers it. It reports whether an alert was processed.
+func (w *Worker) ProcessNext(ctx context.Context) (bool, error) {
+	event, err := w.claim()
+	if err != nil || event == nil {
+		return false, err
+	}
+
+	delivered, err := w.deliver(ctx, event)
+	if err != nil {
+		return true, w.fail(event, delivered, err)
+	}
+	return true, w.complete(event, delivered)
+}
+
+// claim locks the oldest due alert and marks it as sending so other pods skip it. Alerts left sending by a crash
+// are picked up again once stale, unless they already used all their attempts.
+func (w *Worker) claim() (*models.BudgetAlertEvent, error) {
+	var event models.BudgetAlertEvent
+	found := false
+	now := time.Now()
+
+	err := w.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.BudgetAlertEvent{}).
+			Where("status = ? AND updated_at < ? AND attempts >= ?", models.AlertDeliverySending, now.Add(-staleAfter), w.MaxAttempts).
+			Updates(map[string]interface{}{
+				"status":          models.AlertDeliveryFailed,
+				"last_error":      "delivery did not finish",
+				"next_attempt_at": nil,
+				"updated_at":      now,
+			}).Error; err != nil {
+			return err
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR (status = ? AND updated_at < ?)",
+				models.AlertDeliveryPending, now, models.AlertDeliverySending, now.Add(-staleAfter)).
+			Order("created_at ASC").
+			First(&event).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		found = true
+		event.Attempts++
+		return tx.Model(&event).Updates(map[string]interface{}{
+			"status":     models.AlertDeliverySending,
+			"attempts":   event.Attempts,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim budget alert: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &event, nil
+}
+
+// deliver sends the alert through every notifier that has not delivered it yet and returns the notifiers that
+// have, including the ones of earlier attempts. A failing notifier does not keep the others from delivering.
+func (w *Worker) deliver(ctx context.Context, event *models.BudgetAlertEvent) ([]string, error) {
+	var delivered []string
+	if event.DeliveredTo != "" {
+		delivered = strings.Split(event.DeliveredTo, ",")
+	}
+
+	var alert Alert
+	if err := json.Unmarshal([]byte(event.Payload), &alert); err != nil {
+		return delivered, fmt.Errorf("invalid alert payload: %w", err)
+	}
+	var user models.User
+	if err := w.DB.Select("email").Where("user_id = ?", event.UserID).First(&user).Error; err != nil {
+		return delivered, fmt.Errorf("failed to fetch alert recipient: %w", err)
+	}
+	alert.Email = user.Email
+
+	var failures []string
+	for _, notifier := range w.Notifiers {
+		if contains(delivered, notifier.Name()) {
+			continue
+		}
+		notifyCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		err := notifier.Notify(notifyCtx, alert)
+		cancel()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", notifier.Name(), err))
+			continue
+		}
+		delivered = append(delivered, notifier.Name())
+	}
+	if len(failures) > 0 {
+		return delivered, errors.New(strings.Join(failures, "; "))
+	}
+	return delivered, nil
+}
+
+// complete marks the alert as delivered
+func (w *Worker) complete(event *models.BudgetAlertEvent, delivered []string) error {
+	now := time.Now()
+	return w.DB.Model(&models.BudgetAlertEvent{}).
+		Where("id = ? AND status = ?", event.ID, models.AlertDeliverySending).
+		Updates(map[string]interface{}{
+			"status":          models.AlertDeliveryDelivered,
+			"delivered_to":    strings.Join(delivered, ","),
+			"last_error":      "",
+			"next_attempt_at": nil,
+			"delivered_at":    now,
+			"updated_at":      now,
+		}).Error
+}
+
+// fail schedules a retry with exponential backoff, or marks the alert as failed once attempts are exhausted
+func (w *Worker) fail(event *models.BudgetAlertEvent, delivered []string, cause error) error {
+	log.Printf("Failed to deliver budget alert %s (attempt %d): %v", event.ID, event.Attempts, cause)
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"delivered_to": strings.Join(delivered, ","),
+		"last_error":   cause.Error(),
+		"updated_at":   now,
+	}
+	if event.Attempts >= w.MaxAttempts {
+		updates["status"] = models.AlertDeliveryFailed
+		updates["next_attempt_at"] = nil
+	} else {
+		updates["status"] = models.AlertDeliveryPending
+		updates["next_attempt_at"] = now.Add(retryBaseDelay << (event.Attempts - 1))
+	}
+
+	return w.DB.Model(&models.BudgetAlertEvent{}).
+		Where("id = ? AND status = ?", event.ID, models.AlertDeliverySending).
+		Updates(updates).Error
+}
+
+// contains reports whether the names include the given one
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package budgets
+
+import (
+	"expense-mgmt/internal/money"
+	"math"
+	"time"
+)
+
+// ForecastConfidence is the share of outcomes, in percent, the projected band is meant to cover
+const ForecastConfidence = 80
+
+// forecastZ is the normal quantile of a two-sided 80% band
+const forecastZ = 1.2816
+
+// maxSeasonality bounds the seasonality factor so a single odd year cannot dominate the projection
+const maxSeasonality = 3.0
+
+// ForecastInput is what the forecast of a budget period is computed from. Amounts are in the home currency.
+type ForecastInput struct {
+	Period        Period
+	Today         time.Time
+	Currency      string
+	Available     money.Decimal
+	Spent         map[time.Time]money.Decimal // Spending in the period so far, by date
+	Discretionary map[time.Time]money.Decimal // Part of Spent not materialized from recurring expenses, by date
+	Scheduled     map[time.Time]money.Decimal // Recurring expenses still to come in the period, by date
+	History       []SeasonalWindow            // The same calendar window in earlier years
+}
+
+// SeasonalWindow is the discretionary spending within the period's calendar window in an earlier year, split at the
+// date matching today
+type SeasonalWindow struct {
+	Elapsed   money.Decimal
+	Remaining money.Decimal
+}
+
+// Forecast projects the spending of a budget period at its end
+type Forecast struct {
+	AsOf               time.Time     `json:"as_of"`
+	DaysElapsed        int           `json:"days_elapsed"`
+	DaysRemaining      int           `json:"days_remaining"`
+	DailyRate          money.Decimal `json:"daily_rate"`         // Discretionary spending per day so far
+	Seasonality        money.Decimal `json:"seasonality_factor"` // How fast the rest of the window was spent in earlier years compared with its start; 1 without history
+	SeasonalYears      int           `json:"seasonal_years"`     // Earlier years the seasonality is based on
+	Scheduled          money.Decimal `json:"scheduled"`          // Recurring expenses still to come in the period
+	ProjectedSpent     money.Decimal `json:"projected_spent"`
+	ProjectedLow       money.Decimal `json:"projected_low"`
+	ProjectedHigh      money.Decimal `json:"projected_high"`
+	Confidence         int           `json:"confidence"` // Percent of outcomes expected between the low and high projections
+	ProjectedRemaining money.Decimal `json:"projected_remaining"`
+	ProjectedOverspend bool          `json:"projected_overspend"`
+	ExhaustionDate     *time.Time    `json:"exhaustion_date"`     // Nil when the budget is expected to last the period
+	ExhaustionEarliest *time.Time    `json:"exhaustion_earliest"` // At the high projection
+	ExhaustionLatest   *time.Time    `json:"exhaustion_latest"`   // At the low projection; nil when it may last
+}
+
+// AddDaily adds an amount to the calendar day of the date in one of the by-date maps of a ForecastInput
+func AddDaily(daily map[time.Time]money.Decimal, date time.Time, amount money.Decimal) {
+	day := truncateDay(date)
+	daily[day] = daily[day].Add(amount)
+}
+
+// NewForecast projects the period's spending from the discretionary burn rate so far, scaled by how the rest of the
+// same window was spent in earlier years, plus the recurring expenses still scheduled. The band widens with the
+// day-to-day variation of the spending and the disagreement between earlier years.
+func NewForecast(in ForecastInput) Forecast {
+	today := truncateDay(in.Today)
+	if today.Before(in.Period.Start) {
+		today = in.Period.Start
+	}
+	if today.After(in.Period.End) {
+		today = in.Period.End
+	}
+	elapsed := days(in.Period.Start, today) + 1
+	remaining := days(today, in.Period.End)
+	places := money.MinorUnits(in.Currency)
+
+	spent, scheduled := money.Zero, money.Zero
+	for _, amount := range in.Spent {
+		spent = spent.Add(amount)
+	}
+	for _, amount := range in.Scheduled {
+		scheduled = scheduled.Add(amount)
+	}
+
+	// Burn rate and its day-to-day spread, days without spending included
+	var total, squares float64
+	for day := in.Period.Start; !day.After(today); day = day.AddDate(0, 0, 1) {
+		amount := in.Discretionary[day].Float64()
+		total += amount
+		squares += amount * amount
+	}
+	rate := total / float64(elapsed)
+	deviation := math.Sqrt(math.Max(squares/float64(elapsed)-rate*rate, 0))
+
+	// Seasonality compares the daily rate in the rest of the window with the rate up to today, in earlier years
+	seasonality, spread, years := 1.0, 0.0, 0
+	if remaining > 0 {
+		var factors []float64
+		for _, window := range in.History {
+			if window.Elapsed.Sign() <= 0 || window.Remaining.Sign() < 0 {
+				continue
+			}
+			factor := (window.Remaining.Float64() / float64(remaining)) / (window.Elapsed.Float64() / float64(elapsed))
+			factors = append(factors, math.Min(factor, maxSeasonality))
+		}
+		if years = len(factors); years > 0 {
+			seasonality, spread = meanDeviation(factors)
+		}
+	}
+
+	expected := rate * float64(remaining) * seasonality
+	uncertainty := math.Hypot(deviation*math.Sqrt(float64(remaining))*seasonality, rate*float64(remaining)*spread)
+	low := math.Max(expected-forecastZ*uncertainty, 0)
+	high := expected + forecastZ*uncertainty
+
+	committed := spent.Add(scheduled)
+	projected := committed.Add(money.NewFromFloat(expected)).Round(places, money.HalfUp)
+	forecast := Forecast{
+		AsOf:               today,
+		DaysElapsed:        elapsed,
+		DaysRemaining:      remaining,
+		DailyRate:          money.NewFromFloat(rate).Round(places, money.HalfUp),
+		Seasonality:        money.NewFromFloat(seasonality).Round(2, money.HalfUp),
+		SeasonalYears:      years,
+		Scheduled:          scheduled,
+		ProjectedSpent:     projected,
+		ProjectedLow:       committed.Add(money.NewFromFloat(low)).Round(places, money.HalfUp),
+		ProjectedHigh:      committed.Add(money.NewFromFloat(high)).Round(places, money.HalfUp),
+		Confidence:         ForecastConfidence,
+		ProjectedRemaining: in.Available.Sub(projected),
+		ProjectedOverspend: projected.GreaterThan(in.Available),
+	}
+
+	forecast.ExhaustionDate = exhaustion(in, today, expected)
+	forecast.ExhaustionEarliest = exhaustion(in, today, high)
+	forecast.ExhaustionLatest = exhaustion(in, today, low)
+	return forecast
+}
+
+// exhaustion returns the first day the cumulative spending reaches the available amount, when the discretionary
+// spending still to come is spread evenly over the remaining days. A budget already spent returns the day it ran out.
+func exhaustion(in ForecastInput, today time.Time, discretionary float64) *time.Time {
+	remaining := days(today, in.Period.End)
+	perDay := 0.0
+	if remaining > 0 {
+		perDay = discretionary / float64(remaining)
+	}
+
+	cumulative := 0.0
+	available := in.Available.Float64()
+	for day := in.Period.Start; !day.After(in.Period.End); day = day.AddDate(0, 0, 1) {
+		if day.After(today) {
+			cumulative += perDay + in.Scheduled[day].Float64()
+		} else {
+			// Scheduled occurrences not materialized yet are due right away
+			cumulative += in.Spent[day].Float64() + in.Scheduled[day].Float64()
+		}
+		if cumulative > 0 && cumulative >= available {
+			return &day
+		}
+	}
+	return nil
+}
+
+// meanDeviation returns the mean and population standard deviation of the values
+func meanDeviation(values []float64) (float64, float64) {
+	var total, squares float64
+	for _, value := range values {
+		total += value
+		squares += value * value
+	}
+	mean := total / float64(len(values))
+	return mean, math.Sqrt(math.Max(squares/float64(len(values))-mean*mean, 0))
+}
+
+// days counts the calendar days from one date to a later one
+func days(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
@@ -0,0 +1,190 @@
+package budgets
+
+import (
+	"expense-mgmt/internal/money"
+	"testing"
+	"time"
+)
+
+// january is a 30-day period, so with today on January 10 ten days have elapsed and twenty remain
+var january = Period{Start: date("2024-01-01"), End: date("2024-01-30")}
+
+// perDay spreads an amount over every day from start to end
+func perDay(amount string, start, end string) map[time.Time]money.Decimal {
+	daily := map[time.Time]money.Decimal{}
+	for day := date(start); !day.After(date(end)); day = day.AddDate(0, 0, 1) {
+		AddDaily(daily, day, money.MustParse(amount))
+	}
+	return daily
+}
+
+// on puts amounts on single days, given as date and amount pairs
+func on(pairs ...string) map[time.Time]money.Decimal {
+	daily := map[time.Time]money.Decimal{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		AddDaily(daily, date(pairs[i]), money.MustParse(pairs[i+1]))
+	}
+	return daily
+}
+
+func formatDay(day *time.Time) string {
+	if day == nil {
+		return "none"
+	}
+	return day.Format("2006-01-02")
+}
+
+func TestForecast(t *testing.T) {
+	tests := []struct {
+		name          string
+		in            ForecastInput
+		projected     string
+		overspend     bool
+		exhaustion    string
+		earliest      string
+		latest        string
+		seasonalYears int
+		seasonality   string
+	}{
+		{
+			name:      "steady spending lasts exactly the period",
+			in:        ForecastInput{Available: money.MustParse("300"), Spent: perDay("10", "2024-01-01", "2024-01-10"), Discretionary: perDay("10", "2024-01-01", "2024-01-10")},
+			projected: "300.00", exhaustion: "2024-01-30", earliest: "2024-01-30", latest: "2024-01-30", seasonality: "1.00",
+		},
+		{
+			name:      "steady spending with room to spare",
+			in:        ForecastInput{Available: money.MustParse("500"), Spent: perDay("10", "2024-01-01", "2024-01-10"), Discretionary: perDay("10", "2024-01-01", "2024-01-10")},
+			projected: "300.00", exhaustion: "none", earliest: "none", latest: "none", seasonality: "1.00",
+		},
+		{
+			name:      "already spent returns the day it ran out",
+			in:        ForecastInput{Available: money.MustParse("100"), Spent: on("2024-01-02", "30", "2024-01-03", "90"), Discretionary: on("2024-01-02", "30", "2024-01-03", "90")},
+			projected: "360.00", overspend: true, exhaustion: "2024-01-03", earliest: "2024-01-03", latest: "2024-01-03", seasonality: "1.00",
+		},
+		{
+			name:      "already spent by a recurring expense alone",
+			in:        ForecastInput{Available: money.MustParse("100"), Spent: on("2024-01-05", "150")},
+			projected: "150.00", overspend: true, exhaustion: "2024-01-05", earliest: "2024-01-05", latest: "2024-01-05", seasonality: "1.00",
+		},
+		{
+			name:      "no spending never runs out, even with nothing available",
+			in:        ForecastInput{Available: money.Zero},
+			projected: "0.00", exhaustion: "none", earliest: "none", latest: "none", seasonality: "1.00",
+		},
+		{
+			name: "a scheduled recurring expense tips the budget over",
+			in: ForecastInput{Available: money.MustParse("500"), Spent: perDay("10", "2024-01-01", "2024-01-10"),
+				Discretionary: perDay("10", "2024-01-01", "2024-01-10"), Scheduled: on("2024-01-15", "400")},
+			projected: "700.00", overspend: true, exhaustion: "2024-01-15", earliest: "2024-01-15", latest: "2024-01-15", seasonality: "1.00",
+		},
+		{
+			name: "an overdue scheduled expense counts on its own day",
+			in: ForecastInput{Available: money.MustParse("500"), Spent: perDay("10", "2024-01-01", "2024-01-10"),
+				Discretionary: perDay("10", "2024-01-01", "2024-01-10"), Scheduled: on("2024-01-05", "400")},
+			projected: "700.00", overspend: true, exhaustion: "2024-01-10", earliest: "2024-01-10", latest: "2024-01-10", seasonality: "1.00",
+		},
+		{
+			name: "earlier years spent twice as fast in the rest of the window",
+			in: ForecastInput{Available: money.MustParse("1000"), Spent: perDay("10", "2024-01-01", "2024-01-10"),
+				Discretionary: perDay("10", "2024-01-01", "2024-01-10"),
+				History: []SeasonalWindow{
+					{Elapsed: money.MustParse("100"), Remaining: money.MustParse("400")},
+					{Elapsed: money.MustParse("50"), Remaining: money.MustParse("200")},
+					{Elapsed: money.Zero, Remaining: money.MustParse("900")}, // No spending to compare with
+				}},
+			projected: "500.00", exhaustion: "none", earliest: "none", latest: "none", seasonalYears: 2, seasonality: "2.00",
+		},
+		{
+			name: "seasonality is capped",
+			in: ForecastInput{Available: money.MustParse("1000"), Spent: perDay("10", "2024-01-01", "2024-01-10"),
+				Discretionary: perDay("10", "2024-01-01", "2024-01-10"),
+				History:       []SeasonalWindow{{Elapsed: money.MustParse("10"), Remaining: money.MustParse("1000")}}},
+			projected: "700.00", exhaustion: "none", earliest: "none", latest: "none", seasonalYears: 1, seasonality: "3.00",
+		},
+	}
+	for _, tt := range tests {
+		tt.in.Period, tt.in.Today, tt.in.Currency = january, date("2024-01-10"), "CAD"
+		for _, daily := range []*map[time.Time]money.Decimal{&tt.in.Spent, &tt.in.Discretionary, &tt.in.Scheduled} {
+			if *daily == nil {
+				*daily = map[time.Time]money.Decimal{}
+			}
+		}
+
+		forecast := NewForecast(tt.in)
+		if forecast.DaysElapsed != 10 || forecast.DaysRemaining != 20 {
+			t.Errorf("%s: days = %d elapsed, %d remaining, want 10 and 20", tt.name, forecast.DaysElapsed, forecast.DaysRemaining)
+		}
+		if forecast.ProjectedSpent.String() != tt.projected {
+			t.Errorf("%s: projected spent = %s, want %s", tt.name, forecast.ProjectedSpent, tt.projected)
+		}
+		if forecast.ProjectedOverspend != tt.overspend {
+			t.Errorf("%s: projected overspend = %v, want %v", tt.name, forecast.ProjectedOverspend, tt.overspend)
+		}
+		if got := formatDay(forecast.ExhaustionDate); got != tt.exhaustion {
+			t.Errorf("%s: exhaustion date = %s, want %s", tt.name, got, tt.exhaustion)
+		}
+		if got := formatDay(forecast.ExhaustionEarliest); got != tt.earliest {
+			t.Errorf("%s: earliest exhaustion = %s, want %s", tt.name, got, tt.earliest)
+		}
+		if got := formatDay(forecast.ExhaustionLatest); got != tt.latest {
+			t.Errorf("%s: latest exhaustion = %s, want %s", tt.name, got, tt.latest)
+		}
+		if forecast.SeasonalYears != tt.seasonalYears || forecast.Seasonality.String() != tt.seasonality {
+			t.Errorf("%s: seasonality = %s over %d years, want %s over %d", tt.name, forecast.Seasonality, forecast.SeasonalYears,
+				tt.seasonality, tt.seasonalYears)
+		}
+		if !forecast.ProjectedRemaining.Equal(tt.in.Available.Sub(forecast.ProjectedSpent)) {
+			t.Errorf("%s: projected remaining = %s, want available minus projected", tt.name, forecast.ProjectedRemaining)
+		}
+	}
+}
+
+func TestForecastBand(t *testing.T) {
+	// One large expense on the first day: the same burn rate as steady spending, with a wide day-to-day spread
+	in := ForecastInput{
+		Period: january, Today: date("2024-01-10"), Currency: "CAD", Available: money.MustParse("70"),
+		Spent: on("2024-01-01", "20"), Discretionary: on("2024-01-01", "20"), Scheduled: map[time.Time]money.Decimal{},
+	}
+	forecast := NewForecast(in)
+
+	if forecast.DailyRate.String() != "2.00" || forecast.ProjectedSpent.String() != "60.00" {
+		t.Errorf("daily rate %s, projected %s, want 2.00 and 60.00", forecast.DailyRate, forecast.ProjectedSpent)
+	}
+	// Deviation 6 per day over 20 days: 60 ± 1.2816 × 6 × √20
+	if forecast.ProjectedLow.String() != "25.61" || forecast.ProjectedHigh.String() != "94.39" {
+		t.Errorf("band = %s..%s, want 25.61..94.39", forecast.ProjectedLow, forecast.ProjectedHigh)
+	}
+	if forecast.Confidence != ForecastConfidence {
+		t.Errorf("confidence = %d, want %d", forecast.Confidence, ForecastConfidence)
+	}
+	// Only the high projection runs out within the period
+	if forecast.ExhaustionEarliest == nil || forecast.ExhaustionDate != nil || forecast.ExhaustionLatest != nil {
+		t.Errorf("exhaustion earliest %s, expected %s, latest %s, want only the earliest", formatDay(forecast.ExhaustionEarliest),
+			formatDay(forecast.ExhaustionDate), formatDay(forecast.ExhaustionLatest))
+	}
+}
+
+func TestForecastOutsideThePeriod(t *testing.T) {
+	tests := []struct {
+		today              string
+		elapsed, remaining int
+		asOf               string
+	}{
+		{"2023-12-15", 1, 29, "2024-01-01"},
+		{"2024-02-15", 30, 0, "2024-01-30"},
+	}
+	for _, tt := range tests {
+		forecast := NewForecast(ForecastInput{Period: january, Today: date(tt.today), Currency: "CAD", Available: money.MustParse("100"),
+			Spent: on("2024-01-20", "150"), Discretionary: map[time.Time]money.Decimal{}, Scheduled: map[time.Time]money.Decimal{}})
+		if forecast.DaysElapsed != tt.elapsed || forecast.DaysRemaining != tt.remaining || forecast.AsOf.Format("2006-01-02") != tt.asOf {
+			t.Errorf("today %s: %d elapsed, %d remaining as of %s, want %d, %d as of %s", tt.today,
+				forecast.DaysElapsed, forecast.DaysRemaining, forecast.AsOf.Format("2006-01-02"), tt.elapsed, tt.remaining, tt.asOf)
+		}
+	}
+	// After the period, the spending already recorded decides the exhaustion date
+	forecast := NewForecast(ForecastInput{Period: january, Today: date("2024-02-15"), Currency: "CAD", Available: money.MustParse("100"),
+		Spent: on("2024-01-20", "150"), Discretionary: map[time.Time]money.Decimal{}, Scheduled: map[time.Time]money.Decimal{}})
+	if got := formatDay(forecast.ExhaustionDate); got != "2024-01-20" {
+		t.Errorf("exhaustion after the period = %s, want 2024-01-20", got)
+	}
+}
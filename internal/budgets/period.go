@@ -0,0 +1,182 @@
+package budgets
+
+import (
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"fmt"
+	"time"
+)
+
+// Recurrence is how often a recurring budget starts a new period
+type Recurrence string
+
+const (
+	Weekly    Recurrence = "weekly"
+	Monthly   Recurrence = "monthly"
+	Quarterly Recurrence = "quarterly"
+	Yearly    Recurrence = "yearly"
+)
+
+// Rollover is what a period of a recurring budget carries into the next one
+type Rollover string
+
+const (
+	RolloverUnspent   Rollover = "unspent"   // Money left over is added to the next period
+	RolloverOverspent Rollover = "overspent" // Overspending is taken from the next period
+	RolloverBoth      Rollover = "both"      // Either way
+)
+
+// ParseRecurrence validates a recurrence; the empty string is a one-off budget
+func ParseRecurrence(value string) (Recurrence, error) {
+	switch recurrence := Recurrence(value); recurrence {
+	case "", Weekly, Monthly, Quarterly, Yearly:
+		return recurrence, nil
+	}
+	return "", fmt.Errorf("invalid recurrence %q (expected weekly, monthly, quarterly or yearly)", value)
+}
+
+// ParseRollover validates a rollover; the empty string carries nothing over
+func ParseRollover(value string) (Rollover, error) {
+	switch rollover := Rollover(value); rollover {
+	case "", RolloverUnspent, RolloverOverspent, RolloverBoth:
+		return rollover, nil
+	}
+	return "", fmt.Errorf("invalid rollover %q (expected unspent, overspent or both)", value)
+}
+
+// Carry is the part of a period's balance (available minus spent) that rolls into the next period
+func (r Rollover) Carry(balance money.Decimal) money.Decimal {
+	switch {
+	case r == RolloverBoth,
+		r == RolloverUnspent && balance.Sign() > 0,
+		r == RolloverOverspent && balance.Sign() < 0:
+		return balance
+	}
+	return money.Zero
+}
+
+// Period is one instance of a budget, from Start to End inclusive
+type Period struct {
+	Index int // 0 for the first period of the budget
+	Start time.Time
+	End   time.Time
+}
+
+// Contains reports whether the date falls within the period
+func (p Period) Contains(date time.Time) bool {
+	day := truncateDay(date)
+	return !day.Before(p.Start) && !day.After(p.End)
+}
+
+// Nth returns the n-th period of the budget. A one-off budget has a single period spanning its dates; a recurring
+// budget starts a period every week, month, quarter or year from its start date, and its last period is cut
+// short at its end date. ok is false when the budget has no such period.
+func Nth(budget models.Budget, n int) (Period, bool) {
+	start := truncateDay(budget.StartDate)
+	recurrence := Recurrence(budget.Recurrence)
+	if recurrence == "" {
+		if n != 0 || budget.EndDate == nil {
+			return Period{}, false
+		}
+		return Period{Index: 0, Start: start, End: truncateDay(*budget.EndDate)}, true
+	}
+	if n < 0 {
+		return Period{}, false
+	}
+
+	period := Period{Index: n, Start: step(start, recurrence, n), End: step(start, recurrence, n+1).AddDate(0, 0, -1)}
+	if budget.EndDate != nil {
+		end := truncateDay(*budget.EndDate)
+		if period.Start.After(end) {
+			return Period{}, false
+		}
+		if period.End.After(end) {
+			period.End = end
+		}
+	}
+	return period, true
+}
+
+// At returns the period of the budget containing the date; ok is false outside the budget's span
+func At(budget models.Budget, date time.Time) (Period, bool) {
+	day := truncateDay(date)
+	start := truncateDay(budget.StartDate)
+	if day.Before(start) {
+		return Period{}, false
+	}
+
+	// Estimate the index, then correct it for months of different lengths
+	n := 0
+	switch Recurrence(budget.Recurrence) {
+	case Weekly:
+		n = int(day.Sub(start).Hours()/24) / 7
+	case Monthly, Quarterly, Yearly:
+		months := (day.Year()-start.Year())*12 + int(day.Month()-start.Month())
+		n = months / monthsPer(Recurrence(budget.Recurrence))
+	}
+	for n > 0 && step(start, Recurrence(budget.Recurrence), n).After(day) {
+		n--
+	}
+	for {
+		period, ok := Nth(budget, n)
+		if !ok || period.Start.After(day) {
+			return Period{}, false
+		}
+		if !period.End.Before(day) {
+			return period, true
+		}
+		n++
+	}
+}
+
+// Between lists the periods of the budget overlapping from..to, oldest first
+func Between(budget models.Budget, from, to time.Time) []Period {
+	from, to = truncateDay(from), truncateDay(to)
+	n := 0
+	if first, ok := At(budget, from); ok {
+		n = first.Index
+	}
+
+	var periods []Period
+	for ; ; n++ {
+		period, ok := Nth(budget, n)
+		if !ok || period.Start.After(to) {
+			return periods
+		}
+		if !period.End.Before(from) {
+			periods = append(periods, period)
+		}
+	}
+}
+
+// step returns the start of the n-th period after start. Months are added with the day clamped to the end of the
+// month, so a budget starting on January 31 has periods starting February 28 (or 29), March 31 and so on.
+func step(start time.Time, recurrence Recurrence, n int) time.Time {
+	if recurrence == Weekly {
+		return start.AddDate(0, 0, 7*n)
+	}
+	months := n * monthsPer(recurrence)
+	first := time.Date(start.Year(), start.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := start.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// monthsPer is the length of a monthly, quarterly or yearly period in months
+func monthsPer(recurrence Recurrence) int {
+	switch recurrence {
+	case Quarterly:
+		return 3
+	case Yearly:
+		return 12
+	}
+	return 1
+}
+
+// truncateDay drops the time of day; budget dates are calendar dates
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,187 @@
+package budgets
+
+import (
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// budget builds a budget starting on start; end may be empty
+func budget(recurrence Recurrence, start, end string) models.Budget {
+	b := models.Budget{Amount: money.NewFromInt(100), StartDate: date(start), Recurrence: string(recurrence)}
+	if end != "" {
+		endDate := date(end)
+		b.EndDate = &endDate
+	}
+	return b
+}
+
+// describe formats a period as "index start..end", or "none"
+func describe(period Period, ok bool) string {
+	if !ok {
+		return "none"
+	}
+	return fmt.Sprintf("%d %s..%s", period.Index, period.Start.Format("2006-01-02"), period.End.Format("2006-01-02"))
+}
+
+func TestNth(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget models.Budget
+		n      int
+		want   string
+	}{
+		{"one-off", budget("", "2024-01-10", "2024-01-20"), 0, "0 2024-01-10..2024-01-20"},
+		{"one-off has a single period", budget("", "2024-01-10", "2024-01-20"), 1, "none"},
+		{"one-off without an end", budget("", "2024-01-10", ""), 0, "none"},
+		{"weekly", budget(Weekly, "2024-01-01", ""), 2, "2 2024-01-15..2024-01-21"},
+		{"monthly", budget(Monthly, "2024-01-15", ""), 1, "1 2024-02-15..2024-03-14"},
+		{"monthly from January 31", budget(Monthly, "2023-01-31", ""), 0, "0 2023-01-31..2023-02-27"},
+		{"monthly clamped to February 28", budget(Monthly, "2023-01-31", ""), 1, "1 2023-02-28..2023-03-30"},
+		{"monthly back on the 31st", budget(Monthly, "2023-01-31", ""), 2, "2 2023-03-31..2023-04-29"},
+		{"monthly clamped to a leap February 29", budget(Monthly, "2024-01-31", ""), 1, "1 2024-02-29..2024-03-30"},
+		{"quarterly", budget(Quarterly, "2024-01-01", ""), 1, "1 2024-04-01..2024-06-30"},
+		{"quarterly across a year end", budget(Quarterly, "2024-11-30", ""), 1, "1 2025-02-28..2025-05-29"},
+		{"yearly from February 29", budget(Yearly, "2024-02-29", ""), 0, "0 2024-02-29..2025-02-27"},
+		{"yearly after a leap year", budget(Yearly, "2024-02-29", ""), 1, "1 2025-02-28..2026-02-27"},
+		{"last period cut at the end date", budget(Monthly, "2024-01-01", "2024-03-10"), 2, "2 2024-03-01..2024-03-10"},
+		{"no period after the end date", budget(Monthly, "2024-01-01", "2024-03-10"), 3, "none"},
+		{"negative index", budget(Monthly, "2024-01-01", ""), -1, "none"},
+	}
+	for _, tt := range tests {
+		if got := describe(Nth(tt.budget, tt.n)); got != tt.want {
+			t.Errorf("%s: Nth(%d) = %s, want %s", tt.name, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAt(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget models.Budget
+		date   time.Time
+		want   string
+	}{
+		{"before the start", budget(Monthly, "2024-01-15", ""), date("2024-01-14"), "none"},
+		{"first day", budget(Monthly, "2024-01-15", ""), date("2024-01-15"), "0 2024-01-15..2024-02-14"},
+		{"last day of a period", budget(Monthly, "2024-01-15", ""), date("2024-03-14"), "1 2024-02-15..2024-03-14"},
+		{"first day of the next period", budget(Monthly, "2024-01-15", ""), date("2024-03-15"), "2 2024-03-15..2024-04-14"},
+		{"time of day is ignored", budget(Monthly, "2024-01-15", ""), time.Date(2024, 2, 14, 23, 59, 0, 0, time.UTC), "0 2024-01-15..2024-02-14"},
+		{"Jan 31 budget on Feb 27", budget(Monthly, "2023-01-31", ""), date("2023-02-27"), "0 2023-01-31..2023-02-27"},
+		{"Jan 31 budget on Feb 28", budget(Monthly, "2023-01-31", ""), date("2023-02-28"), "1 2023-02-28..2023-03-30"},
+		{"Jan 31 budget on Mar 30", budget(Monthly, "2023-01-31", ""), date("2023-03-30"), "1 2023-02-28..2023-03-30"},
+		{"Jan 31 budget on Mar 31", budget(Monthly, "2023-01-31", ""), date("2023-03-31"), "2 2023-03-31..2023-04-29"},
+		{"Jan 31 budget on Apr 30", budget(Monthly, "2023-01-31", ""), date("2023-04-30"), "3 2023-04-30..2023-05-30"},
+		{"Jan 31 budget in a leap February", budget(Monthly, "2024-01-31", ""), date("2024-02-29"), "1 2024-02-29..2024-03-30"},
+		{"Jan 30 budget on Mar 1", budget(Monthly, "2023-01-30", ""), date("2023-03-01"), "1 2023-02-28..2023-03-29"},
+		{"months later", budget(Monthly, "2023-01-31", ""), date("2024-12-31"), "23 2024-12-31..2025-01-30"},
+		{"weekly", budget(Weekly, "2024-01-01", ""), date("2024-01-14"), "1 2024-01-08..2024-01-14"},
+		{"quarterly", budget(Quarterly, "2024-01-01", ""), date("2024-06-30"), "1 2024-04-01..2024-06-30"},
+		{"yearly on the clamped anniversary", budget(Yearly, "2024-02-29", ""), date("2025-02-28"), "1 2025-02-28..2026-02-27"},
+		{"one-off", budget("", "2024-01-10", "2024-01-20"), date("2024-01-20"), "0 2024-01-10..2024-01-20"},
+		{"after a one-off", budget("", "2024-01-10", "2024-01-20"), date("2024-01-21"), "none"},
+		{"after the end date", budget(Monthly, "2024-01-01", "2024-03-10"), date("2024-03-11"), "none"},
+	}
+	for _, tt := range tests {
+		if got := describe(At(tt.budget, tt.date)); got != tt.want {
+			t.Errorf("%s: At(%s) = %s, want %s", tt.name, tt.date.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     []string
+	}{
+		{"2024-02-20", "2024-04-14", []string{"1 2024-02-15..2024-03-14", "2 2024-03-15..2024-04-14"}},
+		{"2024-02-20", "2024-04-15", []string{"1 2024-02-15..2024-03-14", "2 2024-03-15..2024-04-14", "3 2024-04-15..2024-05-14"}},
+		{"2023-12-01", "2024-01-20", []string{"0 2024-01-15..2024-02-14"}},
+		{"2023-12-01", "2024-01-14", nil},
+	}
+	b := budget(Monthly, "2024-01-15", "")
+	for _, tt := range tests {
+		var got []string
+		for _, period := range Between(b, date(tt.from), date(tt.to)) {
+			got = append(got, describe(period, true))
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Between(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	period := Period{Start: date("2024-01-01"), End: date("2024-01-31")}
+	tests := []struct {
+		date time.Time
+		want bool
+	}{
+		{date("2023-12-31"), false},
+		{date("2024-01-01"), true},
+		{time.Date(2024, 1, 31, 23, 59, 0, 0, time.UTC), true},
+		{date("2024-02-01"), false},
+	}
+	for _, tt := range tests {
+		if got := period.Contains(tt.date); got != tt.want {
+			t.Errorf("Contains(%s) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestCarry(t *testing.T) {
+	tests := []struct {
+		rollover Rollover
+		balance  string
+		want     string
+	}{
+		{"", "50.00", "0"},
+		{"", "-20.00", "0"},
+		{RolloverUnspent, "50.00", "50.00"},
+		{RolloverUnspent, "-20.00", "0"},
+		{RolloverOverspent, "50.00", "0"},
+		{RolloverOverspent, "-20.00", "-20.00"},
+		{RolloverBoth, "50.00", "50.00"},
+		{RolloverBoth, "-20.00", "-20.00"},
+		{RolloverBoth, "0.00", "0.00"},
+		{RolloverUnspent, "0.00", "0"},
+	}
+	for _, tt := range tests {
+		if got := tt.rollover.Carry(money.MustParse(tt.balance)); got.String() != tt.want {
+			t.Errorf("Rollover(%q).Carry(%s) = %s, want %s", tt.rollover, tt.balance, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, value := range []string{"", "weekly", "monthly", "quarterly", "yearly"} {
+		if got, err := ParseRecurrence(value); err != nil || string(got) != value {
+			t.Errorf("ParseRecurrence(%q) = %q, %v", value, got, err)
+		}
+	}
+	for _, value := range []string{"daily", "Monthly", " monthly"} {
+		if _, err := ParseRecurrence(value); err == nil {
+			t.Errorf("ParseRecurrence(%q) did not return an error", value)
+		}
+	}
+	for _, value := range []string{"", "unspent", "overspent", "both"} {
+		if got, err := ParseRollover(value); err != nil || string(got) != value {
+			t.Errorf("ParseRollover(%q) = %q, %v", value, got, err)
+		}
+	}
+	for _, value := range []string{"all", "Unspent"} {
+		if _, err := ParseRollover(value); err == nil {
+			t.Errorf("ParseRollover(%q) did not return an error", value)
+		}
+	}
+}
@@ -1,17 +1,27 @@
 package controller
 
 import (
+	"encoding/json"
 	"errors"
 	"expense-mgmt/db"
+	"expense-mgmt/internal/alerts"
+	"expense-mgmt/internal/budgets"
+	"expense-mgmt/internal/fx"
 	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
 	"expense-mgmt/utils"
 	"fmt"
+	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateBudget creates a new budget for the user
@@ -25,10 +35,14 @@ func CreateBudget(c *gin.Context) {
 
 	// Intermediary struct to capture incoming JSON
 	type BudgetInput struct {
-		CategoryID uuid.UUID `json:"category_id" binding:"required"` // Ensure category_id is provided
-		Amount     float64   `json:"amount" binding:"required,gt=0"` // Ensure amount is positive
-		StartDate  string    `json:"start_date" binding:"required"`  // Ensure start_date is provided
-		EndDate    string    `json:"end_date" binding:"required"`    // Ensure end_date is provided
+		CategoryID  *uuid.UUID    `json:"category_id"`                   // Budget on a single category
+		CategoryIDs []uuid.UUID   `json:"category_ids"`                  // Budget on several categories; leave both out for an overall budget
+		Amount      money.Decimal `json:"amount"`                        // Checked to be positive below
+		StartDate   string        `json:"start_date" binding:"required"` // Ensure start_date is provided
+		EndDate     string        `json:"end_date"`                      // Required unless the budget recurs
+		Recurrence  string        `json:"recurrence"`                    // weekly, monthly, quarterly or yearly
+		Rollover    string        `json:"rollover"`                      // unspent, overspent or both
+		Thresholds  []int         `json:"alert_thresholds"`              // Percentages that trigger an alert, e.g. [50, 80, 100]
 	}
 
 	var input BudgetInput
@@ -36,6 +50,10 @@ func CreateBudget(c *gin.Context) {
 		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
 		return
 	}
+	if input.Amount.Sign() <= 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid input: amount must be greater than zero", nil, nil)
+		return
+	}
 
 	// Parse dates and validate their format
 	startDate, err := time.Parse("2006-01-02", input.StartDate)
@@ -44,66 +62,59 @@ func CreateBudget(c *gin.Context) {
 		return
 	}
 
-	endDate, err := time.Parse("2006-01-02", input.EndDate)
-	if err != nil {
-		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid end_date format (expected YYYY-MM-DD): %v", err), nil, nil)
-		return
-	}
-
-	// Ensure end_date is not earlier than start_date
-	if endDate.Before(startDate) {
-		utils.SendResponse(c, http.StatusBadRequest, "end_date must be later than or equal to start_date", nil, nil)
-		return
+	var endDate *time.Time
+	if input.EndDate != "" {
+		parsed, err := time.Parse("2006-01-02", input.EndDate)
+		if err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid end_date format (expected YYYY-MM-DD): %v", err), nil, nil)
+			return
+		}
+		endDate = &parsed
 	}
 
-	// Pre-check for valid category_id
-	var categoryExists bool
-	err = db.GetDBInstance().Model(&models.Category{}).
-		Where("id = ?", input.CategoryID).
-		Select("COUNT(1) > 0").
-		Scan(&categoryExists).Error
-
-	if err != nil {
-		utils.SendResponse(c, http.StatusInternalServerError, "Database error while verifying category_id", nil, err.Error())
-		return
+	newBudget := models.Budget{
+		UserID:          userID.(uuid.UUID),
+		Amount:          input.Amount,
+		StartDate:       startDate,
+		EndDate:         endDate,
+		Recurrence:      input.Recurrence,
+		Rollover:        input.Rollover,
+		AlertThresholds: input.Thresholds,
 	}
-
-	if !categoryExists {
-		utils.SendResponse(c, http.StatusBadRequest, "Invalid category_id: Category does not exist or is not associated with the user", nil, nil)
+	if message := setBudgetCategories(&newBudget, input.CategoryID, input.CategoryIDs); message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
 		return
 	}
 
-	// Ensure the budget does not overlap with existing budgets for the same category
-	var overlappingBudgetExists bool
-	err = db.GetDBInstance().Model(&models.Budget{}).
-		Where("user_id = ? AND category_id = ? AND NOT (end_date < ? OR start_date > ?)", userID, input.CategoryID, startDate, endDate).
-		Select("COUNT(1) > 0").
-		Scan(&overlappingBudgetExists).Error
+	// Check the dates and categories, and that the budget does not overlap another one on the same categories
+	message, err := checkBudget(db.GetDBInstance(), &newBudget)
 	if err != nil {
-		utils.SendResponse(c, http.StatusInternalServerError, "Failed to check for overlapping budgets", nil, nil)
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to validate budget", nil, nil)
 		return
 	}
-
-	if overlappingBudgetExists {
-		utils.SendResponse(c, http.StatusBadRequest, "Budget period overlaps with an existing budget for the same category", nil, nil)
+	if message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
 		return
 	}
 
-	// Create the new budget
-	newBudget := models.Budget{
-		UserID:     userID.(uuid.UUID),
-		CategoryID: input.CategoryID,
-		Amount:     input.Amount,
-		StartDate:  startDate,
-		EndDate:    endDate,
-	}
-
-	// Save the budget to the database
-	if err := db.GetDBInstance().Create(&newBudget).Error; err != nil {
+	// Save the budget and its categories to the database
+	err = db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newBudget).Error; err != nil {
+			return err
+		}
+		if err := saveBudgetCategories(tx, &newBudget); err != nil {
+			return err
+		}
+		return models.RecordBudgetVersion(tx, newBudget, models.BudgetCreated, nil, &newBudget.UserID)
+	})
+	if err != nil {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to create budget", nil, nil)
 		return
 	}
 
+	// The spending so far may already reach some of the thresholds
+	CheckBudgetAlerts(newBudget.UserID, time.Now())
+
 	// Send success response
 	utils.SendResponse(c, http.StatusCreated, "Budget created successfully", newBudget, nil)
 }
@@ -128,7 +139,12 @@ func ListBudgets(c *gin.Context) {
 
 	// Apply filters
 	if categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+		id, err := uuid.Parse(categoryID)
+		if err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid category_id", nil, nil)
+			return
+		}
+		query = models.BudgetsCovering(query, []uuid.UUID{id})
 	}
 	if startDate != "" {
 		query = query.Where("start_date >= ?", startDate)
@@ -142,6 +158,10 @@ func ListBudgets(c *gin.Context) {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budgets", nil, nil)
 		return
 	}
+	if err := models.FillBudgetCategories(db.GetDBInstance(), budgets); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budgets", nil, nil)
+		return
+	}
 
 	utils.SendResponse(c, http.StatusOK, "Budgets fetched successfully", budgets, nil)
 }
@@ -169,6 +189,10 @@ func GetSingleBudget(c *gin.Context) {
 		}
 		return
 	}
+	if err := fillBudgetCategories(&budget); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget", nil, nil)
+		return
+	}
 
 	utils.SendResponse(c, http.StatusOK, "Budget fetched successfully", budget, nil)
 }
@@ -206,13 +230,26 @@ func UpdateBudget(c *gin.Context) {
 		}
 		return
 	}
+	if budget.EnvelopePlanID != nil {
+		utils.SendResponse(c, http.StatusConflict, "Budget is an envelope of an envelope plan; use the envelope endpoints", nil, nil)
+		return
+	}
+	if err := fillBudgetCategories(&budget); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Error retrieving budget from database", nil, nil)
+		return
+	}
+	previous := models.SnapshotBudget(budget)
 
 	// Bind the JSON request data
 	var updateData struct {
-		Amount     *float64   `json:"amount"`      // Use pointers to distinguish between unset and zero values
-		StartDate  *string    `json:"start_date"`  // Use string to validate and parse date later
-		EndDate    *string    `json:"end_date"`    // Use string for date validation
-		CategoryID *uuid.UUID `json:"category_id"` // Optional category update
+		Amount      *money.Decimal `json:"amount"`       // Use pointers to distinguish between unset and zero values
+		StartDate   *string        `json:"start_date"`   // Use string to validate and parse date later
+		EndDate     *string        `json:"end_date"`     // Use string for date validation; "" removes the end of a recurring budget
+		CategoryID  *uuid.UUID     `json:"category_id"`  // Moves the budget to a single category
+		CategoryIDs *[]uuid.UUID   `json:"category_ids"` // Moves the budget to several categories; [] makes it an overall budget
+		Recurrence  *string        `json:"recurrence"`   // "" turns a recurring budget into a one-off one
+		Rollover    *string        `json:"rollover"`
+		Thresholds  *[]int         `json:"alert_thresholds"` // [] turns the alerts off
 	}
 
 	if err := c.ShouldBindJSON(&updateData); err != nil {
@@ -222,7 +259,7 @@ func UpdateBudget(c *gin.Context) {
 
 	// Validate and update each field
 	if updateData.Amount != nil {
-		if *updateData.Amount <= 0 {
+		if updateData.Amount.Sign() <= 0 {
 			utils.SendResponse(c, http.StatusBadRequest, "Amount must be greater than zero", nil, nil)
 			return
 		}
@@ -238,7 +275,9 @@ func UpdateBudget(c *gin.Context) {
 		budget.StartDate = startDate
 	}
 
-	if updateData.EndDate != nil {
+	if updateData.EndDate != nil && *updateData.EndDate == "" {
+		budget.EndDate = nil
+	} else if updateData.EndDate != nil {
 		endDate, err := time.Parse("2006-01-02", *updateData.EndDate)
 		if err != nil {
 			utils.SendResponse(c, http.StatusBadRequest, "Invalid end_date format. Use YYYY-MM-DD", nil, nil)
@@ -254,19 +293,65 @@ func UpdateBudget(c *gin.Context) {
 			utils.SendResponse(c, http.StatusBadRequest, "End date cannot be earlier than the current start date", nil, nil)
 			return
 		}
-		budget.EndDate = endDate
+		budget.EndDate = &endDate
 	}
 
-	if updateData.CategoryID != nil {
-		budget.CategoryID = *updateData.CategoryID
+	if updateData.CategoryID != nil || updateData.CategoryIDs != nil {
+		var categoryIDs []uuid.UUID
+		if updateData.CategoryIDs != nil {
+			categoryIDs = *updateData.CategoryIDs
+		}
+		budget.CategoryID, budget.CategoryIDs = nil, nil
+		if message := setBudgetCategories(&budget, updateData.CategoryID, categoryIDs); message != "" {
+			utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+			return
+		}
 	}
 
-	// Save the updated budget
-	if err := db.GetDBInstance().Save(&budget).Error; err != nil {
+	if updateData.Recurrence != nil {
+		budget.Recurrence = *updateData.Recurrence
+	}
+	if updateData.Rollover != nil {
+		budget.Rollover = *updateData.Rollover
+	}
+	if updateData.Thresholds != nil {
+		budget.AlertThresholds = *updateData.Thresholds
+	}
+	message, err := checkBudget(db.GetDBInstance(), &budget)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to validate budget", nil, nil)
+		return
+	}
+	if message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+		return
+	}
+
+	// Save the updated budget along with its categories
+	err = db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if !lockBudget(c, tx, budget.BudgetID, previous) {
+			return errResponseSent
+		}
+		if err := tx.Save(&budget).Error; err != nil {
+			return err
+		}
+		if err := saveBudgetCategories(tx, &budget); err != nil {
+			return err
+		}
+		changedBy := userID.(uuid.UUID)
+		return models.RecordBudgetVersion(tx, budget, models.BudgetUpdated, previous, &changedBy)
+	})
+	if errors.Is(err, errResponseSent) {
+		return
+	}
+	if err != nil {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to update budget", nil, nil)
 		return
 	}
 
+	// A lower amount or new thresholds may already be reached by the current spending
+	CheckBudgetAlerts(budget.UserID, time.Now())
+
 	// Respond with the updated budget
 	utils.SendResponse(c, http.StatusOK, "Budget updated successfully", budget, nil)
 }
@@ -293,9 +378,31 @@ func DeleteBudget(c *gin.Context) {
 		}
 		return
 	}
+	if budget.EnvelopePlanID != nil {
+		utils.SendResponse(c, http.StatusConflict, "Budget is an envelope of an envelope plan; use the envelope endpoints", nil, nil)
+		return
+	}
+
+	if err := fillBudgetCategories(&budget); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget", nil, nil)
+		return
+	}
 
-	// Delete the budget
-	if err := db.GetDBInstance().Delete(&budget).Error; err != nil {
+	// Delete the budget, recording its last values
+	changedBy := userID.(uuid.UUID)
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if !lockBudget(c, tx, budget.BudgetID, models.SnapshotBudget(budget)) {
+			return errResponseSent
+		}
+		if err := tx.Delete(&budget).Error; err != nil {
+			return err
+		}
+		return models.RecordBudgetVersion(tx, budget, models.BudgetDeleted, models.SnapshotBudget(budget), &changedBy)
+	})
+	if errors.Is(err, errResponseSent) {
+		return
+	}
+	if err != nil {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete budget", nil, nil)
 		return
 	}
@@ -318,128 +425,800 @@ func BudgetAnalysis(c *gin.Context) {
 	startDate := c.DefaultQuery("start_date", "")
 	endDate := c.DefaultQuery("end_date", "")
 
+	history := defaultBudgetHistory
+	if value := c.Query("history"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > maxBudgetHistory {
+			utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("history must be between 0 and %d", maxBudgetHistory), nil, nil)
+			return
+		}
+		history = parsed
+	}
+
 	// Validate date formats if provided
-	var err error
+	var windowStart, windowEnd *time.Time
 	if startDate != "" {
-		if _, err = time.Parse("2006-01-02", startDate); err != nil {
+		parsed, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
 			utils.SendResponse(c, http.StatusBadRequest, "Invalid start_date format. Use YYYY-MM-DD", nil, nil)
 			return
 		}
+		windowStart = &parsed
 	}
 	if endDate != "" {
-		if _, err = time.Parse("2006-01-02", endDate); err != nil {
+		parsed, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
 			utils.SendResponse(c, http.StatusBadRequest, "Invalid end_date format. Use YYYY-MM-DD", nil, nil)
 			return
 		}
+		windowEnd = &parsed
+	}
+
+	// With as_of, budgets are evaluated as they stood at that time
+	var asOf *time.Time
+	if value := c.Query("as_of"); value != "" {
+		parsed, err := parseAsOf(value)
+		if err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid as_of format. Use YYYY-MM-DD or an RFC 3339 timestamp", nil, nil)
+			return
+		}
+		asOf = &parsed
 	}
 
 	// Fetch budgets for the specified period
 	var budgets []models.Budget
 	budgetQuery := db.GetDBInstance().Where("user_id = ?", userID)
 
+	var filterCategory *uuid.UUID
 	if categoryID != "" {
-		budgetQuery = budgetQuery.Where("category_id = ?", categoryID)
+		id, err := uuid.Parse(categoryID)
+		if err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid category_id", nil, nil)
+			return
+		}
+		filterCategory = &id
 	}
-	if startDate != "" {
-		budgetQuery = budgetQuery.Where("start_date >= ?", startDate)
+	if asOf != nil {
+		// Budgets deleted or changed since are filtered once restored to their values at that time
+		budgetQuery = db.GetDBInstance().Unscoped().Where("user_id = ? AND created_at <= ?", userID, *asOf)
+	} else if filterCategory != nil {
+		budgetQuery = models.BudgetsCovering(budgetQuery, []uuid.UUID{*filterCategory})
 	}
-	if endDate != "" {
-		budgetQuery = budgetQuery.Where("end_date <= ?", endDate)
+	if asOf == nil && (windowStart != nil || windowEnd != nil) {
+		// One-off budgets must fall within the dates, recurring ones only need a period within them
+		oneOff := db.GetDBInstance().Where("recurrence IS NULL OR recurrence = ''")
+		if windowStart != nil {
+			oneOff = oneOff.Where("start_date >= ?", *windowStart)
+		}
+		if windowEnd != nil {
+			oneOff = oneOff.Where("end_date <= ?", *windowEnd)
+		}
+		recurring := db.GetDBInstance().Where("recurrence <> ''")
+		if windowStart != nil {
+			recurring = recurring.Where("(end_date IS NULL OR end_date >= ?)", *windowStart)
+		}
+		if windowEnd != nil {
+			recurring = recurring.Where("start_date <= ?", *windowEnd)
+		}
+		budgetQuery = budgetQuery.Where(oneOff.Or(recurring))
+	}
+	if err := budgetQuery.Order("start_date ASC").Find(&budgets).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budgets", nil, nil)
+		return
 	}
-	if err := budgetQuery.Find(&budgets).Error; err != nil {
+
+	if err := models.FillBudgetCategories(db.GetDBInstance(), budgets); err != nil {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budgets", nil, nil)
 		return
 	}
+	if asOf != nil {
+		restored, err := models.BudgetsAsOf(db.GetDBInstance(), budgets, *asOf)
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget history", nil, nil)
+			return
+		}
+		budgets = slices.DeleteFunc(restored, func(budget models.Budget) bool {
+			return !budgetInAnalysis(budget, filterCategory, windowStart, windowEnd)
+		})
+	}
 
 	if len(budgets) == 0 {
 		utils.SendResponse(c, http.StatusNotFound, "No budgets found for the specified period", nil, nil)
 		return
 	}
 
-	// Map to hold total spending per category
-	categorySpendMap := make(map[uuid.UUID]float64)
-
-	// Fetch total spending per category for the specified period
-	expensesQuery := db.GetDBInstance().Model(&models.Expense{}).Where("user_id = ?", userID)
-	if startDate != "" {
-		expensesQuery = expensesQuery.Where("date >= ?", startDate)
+	// Recurring budgets report the period containing today (or as_of), or the date range when it lies elsewhere
+	reference := time.Now()
+	if asOf != nil {
+		reference = *asOf
 	}
-	if endDate != "" {
-		expensesQuery = expensesQuery.Where("date <= ?", endDate)
+	if windowEnd != nil && reference.After(*windowEnd) {
+		reference = *windowEnd
 	}
-	if categoryID != "" {
-		expensesQuery = expensesQuery.Where("category_id = ?", categoryID)
+	if windowStart != nil && reference.Before(*windowStart) {
+		reference = *windowStart
 	}
 
-	rows, err := expensesQuery.Select("category_id, SUM(amount) as total_spent").Group("category_id").Rows()
+	// Budgets are set in the home currency, so expenses are converted at the rate of their date
+	home, err := fx.HomeCurrency(db.GetDBInstance(), userID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch user currency", nil, nil)
+		return
+	}
+	periods, err := budgetPeriodResults(db.GetDBInstance(), userID, home, budgets, reference, history, windowStart, asOf)
 	if err != nil {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense data", nil, nil)
 		return
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var catID uuid.UUID
-		var totalSpent float64
-		if err := rows.Scan(&catID, &totalSpent); err != nil {
-			utils.SendResponse(c, http.StatusInternalServerError, "Failed to process expense data", nil, nil)
+	// Budgets whose reported period is under way get a forecast of its end; a past evaluation has none
+	var forecasts map[uuid.UUID]*budgetForecast
+	if asOf == nil {
+		forecasts, err = budgetForecasts(db.GetDBInstance(), userID, home, budgets, periods, time.Now())
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to forecast budget spending", nil, nil)
 			return
 		}
-		categorySpendMap[catID] = totalSpent
+	}
+
+	// Default categories are named the way the user renamed them
+	preferences, err := models.FetchCategoryPreferences(db.GetDBInstance(), userID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch category preferences", nil, nil)
+		return
 	}
 
 	// Prepare analysis results
 	type AnalysisResult struct {
-		CategoryID   uuid.UUID `json:"category_id"`
-		Category     string    `json:"category"`
-		Amount       float64   `json:"budgeted_amount"`
-		TotalSpent   float64   `json:"total_spent"`
-		Remaining    float64   `json:"remaining_budget"`
-		Percentage   float64   `json:"percentage_spent"`
-		Exceeds      bool      `json:"exceeds_budget"`
+		BudgetID    uuid.UUID   `json:"budget_id"`
+		CategoryID  *uuid.UUID  `json:"category_id"`            // Null for an overall budget
+		CategoryIDs []uuid.UUID `json:"category_ids,omitempty"` // Categories of a multi-category budget
+		Category    string      `json:"category"`
+		Recurrence  string      `json:"recurrence,omitempty"`
+		Rollover    string      `json:"rollover,omitempty"`
+		budgetPeriodResult                                          // Current period of a recurring budget
+		Forecast    *budgetForecast      `json:"forecast,omitempty"`     // Projected end of the current period, while it is under way
+		PastPeriods []budgetPeriodResult `json:"past_periods,omitempty"` // Earlier periods, most recent first
+		Currency    string               `json:"currency"`               // Home currency of the amounts
 	}
 
 	var analysisResults []AnalysisResult
 
 	for _, budget := range budgets {
-		totalSpent := categorySpendMap[budget.CategoryID]
-		remaining := budget.Amount - totalSpent
-		percentage := 0.0
-		if budget.Amount > 0 {
-			percentage = (totalSpent / budget.Amount) * 100
-		}
-		exceeds := totalSpent > budget.Amount
-
-		// Fetch category name (use "Unknown" if unavailable)
-		categoryName := "Unknown" // Default to Unknown in case of errors
-
-		var category struct {
-      Name string
-    }
- 
-    err := db.GetDBInstance().
-        Table("categories").
-        Select("name").
-        Where("id = ?", budget.CategoryID).
-        Scan(&category).Error
-    if err != nil {
-      // Log the error for debugging, but don't fail the entire process
-      fmt.Printf("Failed to fetch category name for category_id: %s, Error: %v\n", budget.CategoryID, err)
-    }else {
-      categoryName = category.Name // Assign the fetched name
-    }
-	
+		// Fetch the category names (use "Unknown" if unavailable)
+		categoryName := budgetCategoryName(db.GetDBInstance(), budget, preferences)
+
+		results := periods[budget.BudgetID]
 		analysisResults = append(analysisResults, AnalysisResult{
-			CategoryID:   budget.CategoryID,
-			Category:     categoryName,
-			Amount:       budget.Amount,
-			TotalSpent:   totalSpent,
-			Remaining:    remaining,
-			Percentage:   percentage,
-			Exceeds:      exceeds,
+			BudgetID:           budget.BudgetID,
+			CategoryID:         budget.CategoryID,
+			CategoryIDs:        budget.CategoryIDs,
+			Category:           categoryName,
+			Recurrence:         budget.Recurrence,
+			Rollover:           budget.Rollover,
+			budgetPeriodResult: results[0],
+			Forecast:           forecasts[budget.BudgetID],
+			PastPeriods:        results[1:],
+			Currency:           home,
 		})
 	}
 
 	// Send the analysis results
 	utils.SendResponse(c, http.StatusOK, "Budget analysis fetched successfully", analysisResults, nil)
 }
+
+// ListBudgetPeriods lists the periods of a budget up to the current one, most recent first, with the spending,
+// carried over amounts and balance of each
+func ListBudgetPeriods(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var budget models.Budget
+	if err := db.GetDBInstance().Where("user_id = ? AND budget_id = ?", userID, c.Param("budgetId")).First(&budget).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Budget not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget", nil, nil)
+		}
+		return
+	}
+	if err := fillBudgetCategories(&budget); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget", nil, nil)
+		return
+	}
+
+	home, err := fx.HomeCurrency(db.GetDBInstance(), userID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch user currency", nil, nil)
+		return
+	}
+	now := time.Now()
+	history := currentBudgetPeriod(budget, now).Index
+	if history > maxBudgetPeriods {
+		history = maxBudgetPeriods
+	}
+	periods, err := budgetPeriodResults(db.GetDBInstance(), userID, home, []models.Budget{budget}, now, history, nil, nil)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense data", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Budget periods fetched successfully", gin.H{
+		"budget_id": budget.BudgetID,
+		"periods":   periods[budget.BudgetID],
+		"currency":  home,
+	}, nil)
+}
+
+// ListBudgetVersions lists every recorded change to a budget, oldest first, including for a deleted or purged budget
+func ListBudgetVersions(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+	budgetID, err := uuid.Parse(c.Param("budgetId"))
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid budget ID format", nil, nil)
+		return
+	}
+
+	versions := []models.BudgetVersion{}
+	if err := db.GetDBInstance().Where("user_id = ? AND budget_id = ?", userID, budgetID).Order("version ASC").Find(&versions).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget history", nil, nil)
+		return
+	}
+	// A budget without versions yet must still exist; a purged one only lives on in its versions
+	if len(versions) == 0 {
+		var budget models.Budget
+		if err := db.GetDBInstance().Unscoped().Where("user_id = ? AND budget_id = ?", userID, budgetID).First(&budget).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.SendResponse(c, http.StatusNotFound, "Budget not found", nil, nil)
+			} else {
+				utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget", nil, nil)
+			}
+			return
+		}
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Budget history fetched successfully", gin.H{
+		"budget_id": budgetID,
+		"versions":  versions,
+	}, nil)
+}
+
+// Number of past periods BudgetAnalysis reports for each recurring budget by default and at most, and the number of
+// periods ListBudgetPeriods returns at most
+const (
+	defaultBudgetHistory = 3
+	maxBudgetHistory     = 60
+	maxBudgetPeriods     = 520
+)
+
+// Alert thresholds are percentages of the available amount within these bounds
+const (
+	minAlertThreshold = 1
+	maxAlertThreshold = 1000
+)
+
+// budgetPeriodResult is the spending against one period of a budget
+type budgetPeriodResult struct {
+	PeriodStart time.Time     `json:"period_start"`
+	PeriodEnd   time.Time     `json:"period_end"`
+	Amount      money.Decimal `json:"budgeted_amount"`
+	CarriedOver money.Decimal `json:"carried_over"` // Rolled over from the previous period
+	Available   money.Decimal `json:"available"`    // Budgeted amount plus the amount carried over
+	TotalSpent  money.Decimal `json:"total_spent"`
+	Remaining   money.Decimal `json:"remaining_budget"`
+	Percentage  money.Decimal `json:"percentage_spent"` // Share of the available amount
+	Exceeds     bool          `json:"exceeds_budget"`
+}
+
+// budgetPeriodResults evaluates the period of each budget current at the reference date and up to history periods
+// before it, skipping earlier periods that end before notBefore. Spending dated after until, when set, is left out.
+// Results are keyed by budget, the current period first. Spending is converted into the home currency, and a budget
+// on a parent category covers its subcategories.
+func budgetPeriodResults(database *gorm.DB, userID interface{}, home string, list []models.Budget, reference time.Time, history int, notBefore, until *time.Time) (map[uuid.UUID][]budgetPeriodResult, error) {
+	type plan struct {
+		budget  models.Budget
+		periods []budgets.Period // From the first period needed to compute the carried over amount to the current one
+		oldest  int              // Index of the oldest period reported
+		spent   map[int]money.Decimal
+	}
+
+	plans := make([]plan, len(list))
+	var from, to time.Time
+	for i, budget := range list {
+		current := currentBudgetPeriod(budget, reference)
+		oldest := current.Index - history
+		if oldest < 0 {
+			oldest = 0
+		}
+		first := oldest
+		if budget.Rollover != "" {
+			first = 0 // What a period carries over depends on every period before it
+		}
+		var periods []budgets.Period
+		for n := first; n <= current.Index; n++ {
+			if period, ok := budgets.Nth(budget, n); ok {
+				periods = append(periods, period)
+			}
+		}
+		plans[i] = plan{budget: budget, periods: periods, oldest: oldest, spent: map[int]money.Decimal{}}
+		if i == 0 || periods[0].Start.Before(from) {
+			from = periods[0].Start
+		}
+		if i == 0 || current.End.After(to) {
+			to = current.End
+		}
+	}
+
+	if until != nil && until.Before(to) {
+		to = time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	// Each allocation of a split expense counts towards its own category
+	query := models.SpendingLines(database).Where("user_id = ? AND date >= ? AND date < ?", userID, from, to.AddDate(0, 0, 1))
+	totals, _, err := homeSpendingTotals(query, home, "category_id", "date")
+	if err != nil {
+		return nil, err
+	}
+	parents, err := models.FetchCategoryParents(database, userID)
+	if err != nil {
+		return nil, err
+	}
+	envelopes, err := envelopeCategories(database, list)
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range totals {
+		covered := append([]uuid.UUID{total.CategoryID}, models.CategoryAncestors(parents, total.CategoryID)...)
+		for i := range plans {
+			p := &plans[i]
+			if !budgetCovers(p.budget, covered, envelopes) {
+				continue
+			}
+			if period, ok := budgets.At(p.budget, total.Date); ok {
+				p.spent[period.Index] = p.spent[period.Index].Add(total.Amount)
+			}
+		}
+	}
+
+	results := make(map[uuid.UUID][]budgetPeriodResult, len(plans))
+	for _, p := range plans {
+		rollover := budgets.Rollover(p.budget.Rollover)
+		carried := money.Zero
+		var reported []budgetPeriodResult
+		for _, period := range p.periods {
+			spent := p.spent[period.Index]
+			available := p.budget.Amount.Add(carried)
+			result := budgetPeriodResult{
+				PeriodStart: period.Start,
+				PeriodEnd:   period.End,
+				Amount:      p.budget.Amount,
+				CarriedOver: carried,
+				Available:   available,
+				TotalSpent:  spent,
+				Remaining:   available.Sub(spent),
+				Percentage:  money.Zero,
+				Exceeds:     spent.GreaterThan(available),
+			}
+			if available.Sign() > 0 {
+				result.Percentage = spent.Mul(money.NewFromInt(100)).Div(available, 2, money.HalfUp)
+			}
+			carried = rollover.Carry(result.Remaining)
+
+			isCurrent := period.Index == p.periods[len(p.periods)-1].Index
+			if isCurrent || (period.Index >= p.oldest && (notBefore == nil || !period.End.Before(*notBefore))) {
+				reported = append([]budgetPeriodResult{result}, reported...)
+			}
+		}
+		results[p.budget.BudgetID] = reported
+	}
+	return results, nil
+}
+
+// CheckBudgetAlerts emits an alert for each threshold of the user's budgets reached for the first time in the periods
+// containing the dates. It runs after the spending changed; failures are logged rather than failing the change.
+func CheckBudgetAlerts(userID uuid.UUID, dates ...time.Time) {
+	if err := emitBudgetAlerts(db.GetDBInstance(), userID, dates); err != nil {
+		log.Printf("Failed to check budget alerts for user %s: %v", userID, err)
+	}
+}
+
+// emitBudgetAlerts records an event for each newly reached threshold and wakes the alert worker to deliver it. The
+// unique event per budget, period and threshold keeps an alert from firing twice, even when requests race.
+func emitBudgetAlerts(database *gorm.DB, userID uuid.UUID, dates []time.Time) error {
+	var list []models.Budget
+	if err := database.Where("user_id = ? AND alert_thresholds <> '[]'", userID).Find(&list).Error; err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	if err := models.FillBudgetCategories(database, list); err != nil {
+		return err
+	}
+	home, err := fx.HomeCurrency(database, userID)
+	if err != nil {
+		return err
+	}
+	preferences, err := models.FetchCategoryPreferences(database, userID)
+	if err != nil {
+		return err
+	}
+	// Several dates often fall in the same period of a budget, which is then checked once
+	type budgetPeriodKey struct {
+		budgetID uuid.UUID
+		start    time.Time
+	}
+	checked := map[budgetPeriodKey]bool{}
+	recorded := false
+	for _, date := range dates {
+		var due []models.Budget
+		for _, budget := range list {
+			period, ok := budgets.At(budget, date)
+			if !ok || checked[budgetPeriodKey{budget.BudgetID, period.Start}] {
+				continue
+			}
+			checked[budgetPeriodKey{budget.BudgetID, period.Start}] = true
+			due = append(due, budget)
+		}
+		if len(due) == 0 {
+			continue
+		}
+		results, err := budgetPeriodResults(database, userID, home, due, date, 0, nil, nil)
+		if err != nil {
+			return err
+		}
+
+		for _, budget := range due {
+			period := results[budget.BudgetID][0]
+			for _, threshold := range budget.AlertThresholds {
+				// Any spending exhausts a period left with nothing available by the previous one
+				reached := !period.Percentage.LessThan(money.NewFromInt(int64(threshold))) ||
+					(period.Available.Sign() <= 0 && period.TotalSpent.Sign() > 0)
+				if !reached {
+					break // Thresholds are sorted
+				}
+
+				event := models.BudgetAlertEvent{
+					BudgetID:    budget.BudgetID,
+					UserID:      userID,
+					PeriodStart: period.PeriodStart,
+					Threshold:   threshold,
+					Spent:       period.TotalSpent,
+					Available:   period.Available,
+					Status:      models.AlertDeliveryPending,
+				}
+				// The alert worker only sees the event once it carries its payload
+				err := database.Transaction(func(tx *gorm.DB) error {
+					result := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit("Payload").Create(&event)
+					if result.Error != nil || result.RowsAffected == 0 {
+						return result.Error // Already recorded for this period
+					}
+					payload, err := json.Marshal(alerts.Alert{
+						EventID:     event.ID,
+						UserID:      userID,
+						BudgetID:    budget.BudgetID,
+						Budget:      budgetCategoryName(tx, budget, preferences),
+						Threshold:   threshold,
+						PeriodStart: period.PeriodStart,
+						PeriodEnd:   period.PeriodEnd,
+						Available:   period.Available,
+						Spent:       period.TotalSpent,
+						Percentage:  period.Percentage,
+						Currency:    home,
+					})
+					if err != nil {
+						return err
+					}
+					recorded = true
+					return tx.Model(&event).Update("payload", string(payload)).Error
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if recorded {
+		alerts.Notify()
+	}
+	return nil
+}
+
+// currentBudgetPeriod is the period of the budget containing the date, or the nearest one when the date is before
+// the budget starts or after it ends
+func currentBudgetPeriod(budget models.Budget, date time.Time) budgets.Period {
+	if period, ok := budgets.At(budget, date); ok {
+		return period
+	}
+	if date.After(budget.StartDate) && budget.EndDate != nil {
+		if period, ok := budgets.At(budget, *budget.EndDate); ok {
+			return period
+		}
+	}
+	period, _ := budgets.Nth(budget, 0)
+	return period
+}
+
+// validateBudgetSchedule checks the dates, recurrence and rollover of a budget and returns a message for the client
+// when they are invalid
+func validateBudgetSchedule(budget *models.Budget) string {
+	if _, err := budgets.ParseRecurrence(budget.Recurrence); err != nil {
+		return err.Error()
+	}
+	if _, err := budgets.ParseRollover(budget.Rollover); err != nil {
+		return err.Error()
+	}
+	if budget.EndDate == nil && budget.Recurrence == "" {
+		return "end_date is required unless the budget recurs"
+	}
+	if budget.EndDate != nil && budget.EndDate.Before(budget.StartDate) {
+		return "end_date must be later than or equal to start_date"
+	}
+	if budget.Rollover != "" && budget.Recurrence == "" {
+		return "rollover requires a recurring budget"
+	}
+	return ""
+}
+
+// checkBudget validates a new or changed budget: its schedule, that its categories exist and belong to the user,
+// and that it does not overlap another budget on the same categories. It returns a message for the client when the
+// budget is rejected.
+func checkBudget(database *gorm.DB, budget *models.Budget) (string, error) {
+	if message := validateBudgetSchedule(budget); message != "" {
+		return message, nil
+	}
+	if message := validateAlertThresholds(budget); message != "" {
+		return message, nil
+	}
+
+	if categoryIDs := budget.Categories(); len(categoryIDs) > 0 {
+		var count int64
+		err := database.Model(&models.Category{}).
+			Where("id IN ? AND (is_default = ? OR user_id = ?)", categoryIDs, true, budget.UserID).
+			Count(&count).Error
+		if err != nil {
+			return "", err
+		}
+		if int(count) != len(categoryIDs) {
+			return "Invalid category_id: Category does not exist or is not associated with the user", nil
+		}
+	}
+
+	overlaps, err := budgetOverlaps(database, budget)
+	if err != nil {
+		return "", err
+	}
+	if overlaps && budget.Categories() == nil {
+		return "Budget period overlaps with an existing overall budget", nil
+	}
+	if overlaps {
+		return "Budget period overlaps with an existing budget for the same category", nil
+	}
+	return "", nil
+}
+
+// validateAlertThresholds sorts the alert thresholds of a budget and drops duplicates. It returns a message for the
+// client when one is out of range.
+func validateAlertThresholds(budget *models.Budget) string {
+	thresholds := slices.Clone(budget.AlertThresholds)
+	slices.Sort(thresholds)
+	thresholds = slices.Compact(thresholds)
+	for _, threshold := range thresholds {
+		if threshold < minAlertThreshold || threshold > maxAlertThreshold {
+			return fmt.Sprintf("alert_thresholds must be percentages between %d and %d", minAlertThreshold, maxAlertThreshold)
+		}
+	}
+	budget.AlertThresholds = thresholds
+	return ""
+}
+
+// setBudgetCategories sets the categories of a budget from the category_id or category_ids sent by the client. A
+// single category in category_ids is stored like category_id; neither makes an overall budget.
+func setBudgetCategories(budget *models.Budget, categoryID *uuid.UUID, categoryIDs []uuid.UUID) string {
+	if categoryID != nil && len(categoryIDs) > 0 {
+		return "Provide either category_id or category_ids, not both"
+	}
+	if categoryID != nil {
+		budget.CategoryID = categoryID
+		return ""
+	}
+
+	var unique []uuid.UUID
+	for _, id := range categoryIDs {
+		if !slices.Contains(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 1 {
+		budget.CategoryID = &unique[0]
+	} else {
+		budget.CategoryIDs = unique
+	}
+	return ""
+}
+
+// saveBudgetCategories replaces the budget_categories rows of a budget with its CategoryIDs
+func saveBudgetCategories(tx *gorm.DB, budget *models.Budget) error {
+	if err := tx.Where("budget_id = ?", budget.BudgetID).Delete(&models.BudgetCategory{}).Error; err != nil {
+		return err
+	}
+	if len(budget.CategoryIDs) == 0 {
+		return nil
+	}
+	rows := make([]models.BudgetCategory, len(budget.CategoryIDs))
+	for i, categoryID := range budget.CategoryIDs {
+		rows[i] = models.BudgetCategory{BudgetID: budget.BudgetID, CategoryID: categoryID}
+	}
+	return tx.Create(&rows).Error
+}
+
+// lockBudget reloads the budget holding a row lock, so concurrent edits are applied one after the other, and checks
+// that it still has the values the request read. Otherwise another request changed or deleted it in the meantime
+// and the response is sent.
+func lockBudget(c *gin.Context, tx *gorm.DB, budgetID uuid.UUID, read *models.BudgetSnapshot) bool {
+	var current models.Budget
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "budget_id = ?", budgetID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendResponse(c, http.StatusNotFound, "Budget not found", nil, nil)
+		return false
+	}
+	list := []models.Budget{current}
+	if err == nil {
+		err = models.FillBudgetCategories(tx, list)
+	}
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to lock budget", nil, nil)
+		return false
+	}
+	if !models.SameSnapshot(read, models.SnapshotBudget(list[0])) {
+		utils.SendResponse(c, http.StatusConflict, "Budget was changed by another request; fetch it and try again", nil, nil)
+		return false
+	}
+	return true
+}
+
+// fillBudgetCategories sets CategoryIDs on a single budget
+func fillBudgetCategories(budget *models.Budget) error {
+	list := []models.Budget{*budget}
+	if err := models.FillBudgetCategories(db.GetDBInstance(), list); err != nil {
+		return err
+	}
+	budget.CategoryIDs = list[0].CategoryIDs
+	return nil
+}
+
+// budgetCategoryName names the categories of a budget the way the user sees them: joined for a multi-category
+// budget, "All categories" for an overall one and "Unknown" when they cannot be fetched
+func budgetCategoryName(database *gorm.DB, budget models.Budget, preferences map[uuid.UUID]models.CategoryPreference) string {
+	categoryIDs := budget.Categories()
+	if len(categoryIDs) == 0 {
+		return "All categories"
+	}
+
+	var categories []struct {
+		ID   uuid.UUID
+		Name string
+	}
+	err := database.Table("categories").Select("id, name").Where("id IN ?", categoryIDs).Scan(&categories).Error
+	if err != nil || len(categories) == 0 {
+		// Log the error for debugging, but don't fail the entire process
+		fmt.Printf("Failed to fetch category names for budget_id: %s, Error: %v\n", budget.BudgetID, err)
+		return "Unknown"
+	}
+
+	names := make(map[uuid.UUID]string, len(categories))
+	for _, category := range categories {
+		names[category.ID] = category.Name
+		if preference, ok := preferences[category.ID]; ok && preference.Name != "" {
+			names[category.ID] = preference.Name
+		}
+	}
+	var list []string
+	for _, id := range categoryIDs {
+		if name, ok := names[id]; ok {
+			list = append(list, name)
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+// parseAsOf reads the as_of parameter of BudgetAnalysis: an RFC 3339 timestamp, or a date standing for the end of
+// that day
+func parseAsOf(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parsed.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
+
+// budgetInAnalysis applies the category_id, start_date and end_date filters of BudgetAnalysis to a budget restored
+// as of an earlier time, the way the query does for current budgets
+func budgetInAnalysis(budget models.Budget, categoryID *uuid.UUID, windowStart, windowEnd *time.Time) bool {
+	if categoryID != nil && !slices.Contains(budget.Categories(), *categoryID) {
+		return false
+	}
+	if budget.Recurrence == "" {
+		return (windowStart == nil || !budget.StartDate.Before(*windowStart)) &&
+			(windowEnd == nil || budget.EndDate != nil && !budget.EndDate.After(*windowEnd))
+	}
+	return (windowStart == nil || budget.EndDate == nil || !budget.EndDate.Before(*windowStart)) &&
+		(windowEnd == nil || !budget.StartDate.After(*windowEnd))
+}
+
+// budgetCovers reports whether spending in a category, given with its ancestors nearest first, counts towards the
+// budget. An overall budget counts every line, others count it once when it falls under any of their categories. An
+// envelope only counts the lines whose nearest category with an envelope in the same plan is its own, so each line
+// draws down a single envelope; envelopes are the categories with an envelope, by plan (see envelopeCategories).
+func budgetCovers(budget models.Budget, covered []uuid.UUID, envelopes map[uuid.UUID][]uuid.UUID) bool {
+	if budget.EnvelopePlanID != nil && budget.CategoryID != nil {
+		planned := envelopes[*budget.EnvelopePlanID]
+		nearest := slices.IndexFunc(covered, func(id uuid.UUID) bool { return slices.Contains(planned, id) })
+		return nearest >= 0 && covered[nearest] == *budget.CategoryID
+	}
+	categories := budget.Categories()
+	return len(categories) == 0 ||
+		slices.ContainsFunc(covered, func(id uuid.UUID) bool { return slices.Contains(categories, id) })
+}
+
+// envelopeCategories returns the categories of every envelope in the plans of the listed envelopes, by plan,
+// including envelopes that are not listed themselves
+func envelopeCategories(database *gorm.DB, list []models.Budget) (map[uuid.UUID][]uuid.UUID, error) {
+	var planIDs []uuid.UUID
+	for _, budget := range list {
+		if budget.EnvelopePlanID != nil && !slices.Contains(planIDs, *budget.EnvelopePlanID) {
+			planIDs = append(planIDs, *budget.EnvelopePlanID)
+		}
+	}
+	envelopes := make(map[uuid.UUID][]uuid.UUID, len(planIDs))
+	if len(planIDs) == 0 {
+		return envelopes, nil
+	}
+
+	var planned []models.Budget
+	if err := database.Select("envelope_plan_id", "category_id").
+		Where("envelope_plan_id IN ? AND category_id IS NOT NULL", planIDs).
+		Find(&planned).Error; err != nil {
+		return nil, err
+	}
+	for _, budget := range planned {
+		envelopes[*budget.EnvelopePlanID] = append(envelopes[*budget.EnvelopePlanID], *budget.CategoryID)
+	}
+	return envelopes, nil
+}
+
+// budgetOverlaps reports whether another budget of the user covering any of the budget's categories, or another
+// overall budget for an overall one, overlaps the budget's span
+func budgetOverlaps(database *gorm.DB, budget *models.Budget) (bool, error) {
+	var exists bool
+	query := models.BudgetsOverlapping(database.Model(&models.Budget{}), budget.StartDate, budget.EndDate).
+		Where("user_id = ?", budget.UserID)
+	query = models.BudgetsCovering(query, budget.Categories())
+	if budget.BudgetID != uuid.Nil {
+		query = query.Where("budget_id <> ?", budget.BudgetID)
+	}
+	err := query.Select("COUNT(1) > 0").Scan(&exists).Error
+	return exists, err
+}
+
+// sameBudgetSchedule reports whether two budgets cover exactly the same periods
+func sameBudgetSchedule(a, b models.Budget) bool {
+	if !a.StartDate.Equal(b.StartDate) || a.Recurrence != b.Recurrence {
+		return false
+	}
+	if a.EndDate == nil || b.EndDate == nil {
+		return a.EndDate == nil && b.EndDate == nil
+	}
+	return a.EndDate.Equal(*b.EndDate)
+}
@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"expense-mgmt/internal/budgets"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"expense-mgmt/internal/recurrence"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// forecastYears is how many earlier years the seasonality of a forecast looks back at
+const forecastYears = 3
+
+// budgetForecast is the forecast reported with the current period of a budget
+type budgetForecast = budgets.Forecast
+
+// budgetForecasts forecasts the end of the reported period of each budget whose period contains today, keyed by
+// budget. The periods are the results of budgetPeriodResults for the same budgets.
+func budgetForecasts(database *gorm.DB, userID interface{}, home string, list []models.Budget, periods map[uuid.UUID][]budgetPeriodResult, now time.Time) (map[uuid.UUID]*budgetForecast, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	type active struct {
+		budget models.Budget
+		input  budgets.ForecastInput
+		years  []budgets.SeasonalWindow
+	}
+	var actives []*active
+	var from, to time.Time
+	for _, budget := range list {
+		results := periods[budget.BudgetID]
+		if len(results) == 0 {
+			continue
+		}
+		period := budgets.Period{Start: results[0].PeriodStart, End: results[0].PeriodEnd}
+		if !period.Contains(today) {
+			continue
+		}
+		actives = append(actives, &active{
+			budget: budget,
+			input: budgets.ForecastInput{
+				Period:        period,
+				Today:         today,
+				Currency:      home,
+				Available:     results[0].Available,
+				Spent:         map[time.Time]money.Decimal{},
+				Discretionary: map[time.Time]money.Decimal{},
+				Scheduled:     map[time.Time]money.Decimal{},
+			},
+			years: make([]budgets.SeasonalWindow, forecastYears),
+		})
+		if len(actives) == 1 || period.Start.Before(from) {
+			from = period.Start
+		}
+		if len(actives) == 1 || period.End.After(to) {
+			to = period.End
+		}
+	}
+	forecasts := make(map[uuid.UUID]*budgetForecast, len(actives))
+	if len(actives) == 0 {
+		return forecasts, nil
+	}
+
+	parents, err := models.FetchCategoryParents(database, userID)
+	if err != nil {
+		return nil, err
+	}
+	envelopes, err := envelopeCategories(database, list)
+	if err != nil {
+		return nil, err
+	}
+
+	// The current periods up to today, then the same windows in each earlier year
+	for year := 0; year <= forecastYears; year++ {
+		end := to.AddDate(-year, 0, 0)
+		if year == 0 {
+			end = today
+		}
+		query := models.SpendingLines(database).
+			Where("user_id = ? AND date >= ? AND date < ?", userID, from.AddDate(-year, 0, 0), end.AddDate(0, 0, 1))
+		totals, _, err := homeSpendingTotals(query, home, "category_id", "date", "recurring")
+		if err != nil {
+			return nil, err
+		}
+		for _, total := range totals {
+			covered := append([]uuid.UUID{total.CategoryID}, models.CategoryAncestors(parents, total.CategoryID)...)
+			for _, a := range actives {
+				if !budgetCovers(a.budget, covered, envelopes) {
+					continue
+				}
+				period := budgets.Period{
+					Start: a.input.Period.Start.AddDate(-year, 0, 0),
+					End:   a.input.Period.End.AddDate(-year, 0, 0),
+				}
+				if !period.Contains(total.Date) {
+					continue
+				}
+				if year == 0 {
+					budgets.AddDaily(a.input.Spent, total.Date, total.Amount)
+					if !total.Recurring {
+						budgets.AddDaily(a.input.Discretionary, total.Date, total.Amount)
+					}
+					continue
+				}
+				// Recurring expenses are projected from their schedule, so seasonality only looks at the rest
+				if total.Recurring {
+					continue
+				}
+				window := &a.years[year-1]
+				if total.Date.After(today.AddDate(-year, 0, 0)) {
+					window.Remaining = window.Remaining.Add(total.Amount)
+				} else {
+					window.Elapsed = window.Elapsed.Add(total.Amount)
+				}
+			}
+		}
+	}
+
+	// Recurring expenses not materialized yet, including occurrences due today the scheduler has not reached
+	var templates []models.RecurringExpense
+	if err := database.Where("user_id = ? AND active = ? AND next_occurrence IS NOT NULL AND next_occurrence <= ?", userID, true, to).
+		Find(&templates).Error; err != nil {
+		return nil, err
+	}
+	for _, template := range templates {
+		rule, err := recurrence.Parse(template.Rule)
+		if err != nil {
+			continue // The scheduler deactivates templates with broken rules
+		}
+		covered := append([]uuid.UUID{template.CategoryID}, models.CategoryAncestors(parents, template.CategoryID)...)
+		for _, a := range actives {
+			if !budgetCovers(a.budget, covered, envelopes) {
+				continue
+			}
+			for _, occurrence := range rule.Between(template.StartDate, *template.NextOccurrence, a.input.Period.End) {
+				if !occurrence.Before(a.input.Period.Start) {
+					budgets.AddDaily(a.input.Scheduled, occurrence, template.Amount)
+				}
+			}
+		}
+	}
+
+	for _, a := range actives {
+		a.input.History = a.years
+		forecast := budgets.NewForecast(a.input)
+		forecasts[a.budget.BudgetID] = &forecast
+	}
+	return forecasts, nil
+}
@@ -0,0 +1,469 @@
+package controller
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/fx"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"expense-mgmt/utils"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxBudgetTemplateNameLength matches the size of budget_templates.name
+const maxBudgetTemplateNameLength = 100
+
+// budgetTemplateLineInput is one category amount of a template as sent by the client
+type budgetTemplateLineInput struct {
+	CategoryID uuid.UUID     `json:"category_id"`
+	Amount     money.Decimal `json:"amount"`
+}
+
+// budgetTemplateConflict is a template line that could not become a budget, with the reason
+type budgetTemplateConflict struct {
+	CategoryID uuid.UUID     `json:"category_id"`
+	Amount     money.Decimal `json:"amount"`
+	Reason     string        `json:"reason"`
+}
+
+// CreateBudgetTemplate saves a named set of category amounts
+func CreateBudgetTemplate(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var input struct {
+		Name  string                    `json:"name" binding:"required"`
+		Lines []budgetTemplateLineInput `json:"lines" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+
+	template := models.BudgetTemplate{UserID: userID.(uuid.UUID), Name: strings.TrimSpace(input.Name)}
+	setBudgetTemplateLines(&template, input.Lines)
+	if !checkBudgetTemplate(c, db.GetDBInstance(), &template) {
+		return
+	}
+
+	if err := saveBudgetTemplate(db.GetDBInstance(), &template); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to create budget template", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusCreated, "Budget template created successfully", template, nil)
+}
+
+// CreateBudgetTemplateFromMonth builds a template from what the user actually spent per category in a past month,
+// in the home currency
+func CreateBudgetTemplateFromMonth(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var input struct {
+		Name  string `json:"name" binding:"required"`
+		Month string `json:"month" binding:"required"` // YYYY-MM
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	month, err := time.Parse("2006-01", input.Month)
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid month format (expected YYYY-MM): %v", err), nil, nil)
+		return
+	}
+
+	home, err := fx.HomeCurrency(db.GetDBInstance(), userID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch user currency", nil, nil)
+		return
+	}
+	// Each allocation of a split expense counts towards its own category
+	query := models.SpendingLines(db.GetDBInstance()).
+		Where("user_id = ? AND date >= ? AND date < ?", userID, month, month.AddDate(0, 1, 0))
+	byCategory, unconverted, err := homeSpendingTotals(query, home, "category_id")
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense data", nil, nil)
+		return
+	}
+
+	var templateLines []budgetTemplateLineInput
+	for _, total := range byCategory {
+		if amount := total.Amount.Round(money.MinorUnits(home), money.HalfUp); amount.Sign() > 0 {
+			templateLines = append(templateLines, budgetTemplateLineInput{CategoryID: total.CategoryID, Amount: amount})
+		}
+	}
+	if len(templateLines) == 0 {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("No spending recorded in %s", input.Month), nil, nil)
+		return
+	}
+
+	template := models.BudgetTemplate{UserID: userID.(uuid.UUID), Name: strings.TrimSpace(input.Name)}
+	setBudgetTemplateLines(&template, templateLines)
+	if !checkBudgetTemplate(c, db.GetDBInstance(), &template) {
+		return
+	}
+
+	if err := saveBudgetTemplate(db.GetDBInstance(), &template); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to create budget template", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusCreated, "Budget template created from spending", gin.H{
+		"template":             template,
+		"currency":             home,
+		"unconverted_expenses": unconverted, // Expenses counted at their stored amount for lack of a rate
+	}, nil)
+}
+
+// ListBudgetTemplates fetches the user's budget templates with their lines
+func ListBudgetTemplates(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	templates := []models.BudgetTemplate{}
+	if err := db.GetDBInstance().Where("user_id = ?", userID).Order("name ASC").Find(&templates).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget templates", nil, nil)
+		return
+	}
+	if err := fillBudgetTemplateLines(db.GetDBInstance(), templates); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget templates", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Budget templates fetched successfully", templates, nil)
+}
+
+// GetBudgetTemplate fetches a single budget template with its lines
+func GetBudgetTemplate(c *gin.Context) {
+	template, ok := fetchUserBudgetTemplate(c)
+	if !ok {
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Budget template fetched successfully", template, nil)
+}
+
+// UpdateBudgetTemplate renames a template or replaces its lines. Budgets created from it are left unchanged.
+func UpdateBudgetTemplate(c *gin.Context) {
+	template, ok := fetchUserBudgetTemplate(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name  *string                    `json:"name"`
+		Lines *[]budgetTemplateLineInput `json:"lines"` // Replaces every line
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	if input.Name != nil {
+		template.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Lines != nil {
+		setBudgetTemplateLines(template, *input.Lines)
+	}
+	if !checkBudgetTemplate(c, db.GetDBInstance(), template) {
+		return
+	}
+
+	if err := saveBudgetTemplate(db.GetDBInstance(), template); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to update budget template", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Budget template updated successfully", template, nil)
+}
+
+// DeleteBudgetTemplate deletes a template and its lines. Budgets created from it are kept.
+func DeleteBudgetTemplate(c *gin.Context) {
+	template, ok := fetchUserBudgetTemplate(c)
+	if !ok {
+		return
+	}
+
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("template_id = ?", template.ID).Delete(&models.BudgetTemplateLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(template).Error
+	})
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete budget template", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Budget template deleted successfully", nil, nil)
+}
+
+// ApplyBudgetTemplate creates a budget for each line of a template over the given dates, in one transaction. A
+// line that would overlap an existing budget on its category is reported as a conflict and skipped; the others are
+// still created.
+func ApplyBudgetTemplate(c *gin.Context) {
+	template, ok := fetchUserBudgetTemplate(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		StartDate  string `json:"start_date" binding:"required"`
+		EndDate    string `json:"end_date"`         // Required unless the budgets recur
+		Recurrence string `json:"recurrence"`       // weekly, monthly, quarterly or yearly
+		Rollover   string `json:"rollover"`         // unspent, overspent or both
+		Thresholds []int  `json:"alert_thresholds"` // Alert thresholds of every budget created
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	startDate, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid start_date format (expected YYYY-MM-DD): %v", err), nil, nil)
+		return
+	}
+	var endDate *time.Time
+	if input.EndDate != "" {
+		parsed, err := time.Parse("2006-01-02", input.EndDate)
+		if err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid end_date format (expected YYYY-MM-DD): %v", err), nil, nil)
+			return
+		}
+		endDate = &parsed
+	}
+
+	// The schedule is shared by every line, so a bad one rejects the whole request
+	schedule := models.Budget{
+		UserID:          template.UserID,
+		StartDate:       startDate,
+		EndDate:         endDate,
+		Recurrence:      input.Recurrence,
+		Rollover:        input.Rollover,
+		AlertThresholds: input.Thresholds,
+	}
+	if message := validateBudgetSchedule(&schedule); message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+		return
+	}
+	if message := validateAlertThresholds(&schedule); message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+		return
+	}
+
+	created := []models.Budget{}
+	conflicts := []budgetTemplateConflict{}
+	err = db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		for _, line := range template.Lines {
+			categoryID := line.CategoryID
+			budget := schedule
+			budget.CategoryID = &categoryID
+			budget.Amount = line.Amount
+
+			// Budgets created for earlier lines are visible to the overlap check of later ones
+			message, err := checkBudget(tx, &budget)
+			if err != nil {
+				return err
+			}
+			if message != "" {
+				conflicts = append(conflicts, budgetTemplateConflict{CategoryID: line.CategoryID, Amount: line.Amount, Reason: message})
+				continue
+			}
+			if err := tx.Create(&budget).Error; err != nil {
+				return err
+			}
+			if err := models.RecordBudgetVersion(tx, budget, models.BudgetCreated, nil, &template.UserID); err != nil {
+				return err
+			}
+			created = append(created, budget)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to apply budget template", nil, nil)
+		return
+	}
+
+	result := gin.H{"template_id": template.ID, "budgets": created, "conflicts": conflicts}
+	if len(created) == 0 {
+		utils.SendResponse(c, http.StatusConflict, "No budget created; every line of the template conflicts", result, nil)
+		return
+	}
+
+	// The spending so far may already reach some of the thresholds
+	CheckBudgetAlerts(template.UserID, time.Now())
+
+	utils.SendResponse(c, http.StatusCreated, "Budget template applied successfully", result, nil)
+}
+
+// fetchUserBudgetTemplate loads the template named in the URL with its lines if it belongs to the user, sending the
+// error response otherwise
+func fetchUserBudgetTemplate(c *gin.Context) (*models.BudgetTemplate, bool) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return nil, false
+	}
+
+	// Validate the templateId format
+	templateID := c.Param("templateId")
+	if _, err := uuid.Parse(templateID); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid template ID format", nil, nil)
+		return nil, false
+	}
+
+	var template models.BudgetTemplate
+	if err := db.GetDBInstance().Where("user_id = ? AND id = ?", userID, templateID).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Budget template not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget template", nil, nil)
+		}
+		return nil, false
+	}
+	templates := []models.BudgetTemplate{template}
+	if err := fillBudgetTemplateLines(db.GetDBInstance(), templates); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget template", nil, nil)
+		return nil, false
+	}
+
+	return &templates[0], true
+}
+
+// setBudgetTemplateLines replaces the lines of a template with the ones sent by the client
+func setBudgetTemplateLines(template *models.BudgetTemplate, lines []budgetTemplateLineInput) {
+	template.Lines = make([]models.BudgetTemplateLine, len(lines))
+	for i, line := range lines {
+		template.Lines[i] = models.BudgetTemplateLine{TemplateID: template.ID, CategoryID: line.CategoryID, Amount: line.Amount}
+	}
+}
+
+// checkBudgetTemplate validates the name and lines of a new or changed template, sending the error response when
+// they are rejected
+func checkBudgetTemplate(c *gin.Context, database *gorm.DB, template *models.BudgetTemplate) bool {
+	if template.Name == "" {
+		utils.SendResponse(c, http.StatusBadRequest, "Template name is required", nil, nil)
+		return false
+	}
+	if len(template.Name) > maxBudgetTemplateNameLength {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Template name must be at most %d characters", maxBudgetTemplateNameLength), nil, nil)
+		return false
+	}
+	if len(template.Lines) == 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "A template needs at least one line", nil, nil)
+		return false
+	}
+
+	categoryIDs := make([]uuid.UUID, 0, len(template.Lines))
+	for _, line := range template.Lines {
+		if line.CategoryID == uuid.Nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Every line needs a category_id", nil, nil)
+			return false
+		}
+		if line.Amount.Sign() <= 0 {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid input: amount must be greater than zero", nil, nil)
+			return false
+		}
+		for _, seen := range categoryIDs {
+			if seen == line.CategoryID {
+				utils.SendResponse(c, http.StatusBadRequest, "A category can appear in only one line of a template", nil, nil)
+				return false
+			}
+		}
+		categoryIDs = append(categoryIDs, line.CategoryID)
+	}
+
+	var count int64
+	if err := database.Model(&models.Category{}).
+		Where("id IN ? AND (is_default = ? OR user_id = ?)", categoryIDs, true, template.UserID).
+		Count(&count).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while checking categories", nil, nil)
+		return false
+	}
+	if int(count) != len(categoryIDs) {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid category_id: Category does not exist or is not associated with the user", nil, nil)
+		return false
+	}
+
+	// Names are unique per user, ignoring case
+	if err := database.Model(&models.BudgetTemplate{}).
+		Where("user_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", template.UserID, template.Name, template.ID).
+		Count(&count).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while checking template name", nil, nil)
+		return false
+	}
+	if count > 0 {
+		utils.SendResponse(c, http.StatusConflict, "Template name already exists", nil, nil)
+		return false
+	}
+	return true
+}
+
+// saveBudgetTemplate creates or updates a template and replaces its lines, in one transaction
+func saveBudgetTemplate(database *gorm.DB, template *models.BudgetTemplate) error {
+	return database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(template).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("template_id = ?", template.ID).Delete(&models.BudgetTemplateLine{}).Error; err != nil {
+			return err
+		}
+		for i := range template.Lines {
+			template.Lines[i].ID = uuid.Nil
+			template.Lines[i].TemplateID = template.ID
+		}
+		sort.Slice(template.Lines, func(i, j int) bool {
+			return template.Lines[i].Amount.GreaterThan(template.Lines[j].Amount)
+		})
+		return tx.Create(&template.Lines).Error
+	})
+}
+
+// fillBudgetTemplateLines sets the lines of the templates, largest amount first
+func fillBudgetTemplateLines(database *gorm.DB, templates []models.BudgetTemplate) error {
+	if len(templates) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(templates))
+	for i, template := range templates {
+		ids[i] = template.ID
+	}
+
+	var lines []models.BudgetTemplateLine
+	if err := database.Where("template_id IN ?", ids).Order("amount DESC").Find(&lines).Error; err != nil {
+		return err
+	}
+	byTemplate := make(map[uuid.UUID][]models.BudgetTemplateLine, len(templates))
+	for _, line := range lines {
+		byTemplate[line.TemplateID] = append(byTemplate[line.TemplateID], line)
+	}
+	for i := range templates {
+		templates[i].Lines = byTemplate[templates[i].ID]
+		if templates[i].Lines == nil {
+			templates[i].Lines = []models.BudgetTemplateLine{}
+		}
+	}
+	return nil
+}
@@ -5,10 +5,12 @@ import (
 	"expense-mgmt/db"
 	"expense-mgmt/internal/common"
 	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/rules"
 	"expense-mgmt/utils"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -83,6 +85,20 @@ func CreateCustomCategory(c *gin.Context) {
 	// Trim the category name to remove any leading/trailing spaces
 	category.Name = strings.TrimSpace(category.Name)
 
+	// A subcategory can go under a default category or one of the user's own
+	category.Children = nil
+	if category.ParentID != nil {
+		message, err := validateCategoryParent(DB, parsedUserID, uuid.Nil, *category.ParentID)
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating parent category", nil, nil)
+			return
+		}
+		if message != "" {
+			utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+			return
+		}
+	}
+
 	 // Check if the category name already exists (case-insensitive check)
 	 var existingCategory models.Category
 	 if err := DB.Where("user_id = ? AND LOWER(name) = LOWER(?)", parsedUserID, category.Name).First(&existingCategory).Error; err == nil {
@@ -146,6 +162,19 @@ func ListUserCategories(c *gin.Context) {
 		// Merge default and custom categories
     allCategories := append(defaultCategories, customCategories...)
 
+	// Apply the user's preferences; hidden and archived categories are left out unless asked for
+	allCategories, err := visibleCategories(DB, userID, allCategories, c.Query("include_hidden") == "true", c.Query("include_archived") == "true")
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Error retrieving category preferences", nil, nil)
+		return
+	}
+
+	// Optionally nest subcategories under their parents
+	if c.Query("tree") == "true" {
+		utils.SendResponse(c, http.StatusOK, "Categories retrieved successfully", categoryTree(allCategories), nil)
+		return
+	}
+
 	// Return the result as a response
 	utils.SendResponse(c, http.StatusOK, "Categories retrieved successfully", allCategories, nil)
 }
@@ -179,6 +208,16 @@ func GetCategoryDetails(c *gin.Context) {
 			return
 	}
 
+	// Show a default category the way the user renamed or hid it
+	if userID, ok := c.Get("userId"); ok && category.IsDefault {
+		var preference models.CategoryPreference
+		if err := DB.Where("user_id = ? AND category_id = ?", userID, category.ID).Limit(1).Find(&preference).Error; err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Error retrieving category preferences", nil, nil)
+			return
+		}
+		preference.Apply(&category)
+	}
+
 	// Return the category details in the response
 	utils.SendResponse(c, http.StatusOK, "Category details fetched successfully", category, nil)
 }
@@ -224,9 +263,10 @@ func UpdateCategory(c *gin.Context) {
 
 	// Bind the updated fields from the request body
 	var updatedCategoryData struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		ColorCode   string `json:"color_code"`
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		ColorCode   string  `json:"color_code"`
+		ParentID    *string `json:"parent_id"` // "" moves the category to the top level
 	}
 	if err := c.ShouldBindJSON(&updatedCategoryData); err != nil {
 			utils.SendResponse(c, http.StatusBadRequest, "Invalid request body", nil, nil)
@@ -241,6 +281,29 @@ func UpdateCategory(c *gin.Context) {
 		category.Description = updatedCategoryData.Description
 	}
 
+	// Move the category under another parent, keeping the tree free of cycles
+	if updatedCategoryData.ParentID != nil {
+		if *updatedCategoryData.ParentID == "" {
+			category.ParentID = nil
+		} else {
+			parentID, err := uuid.Parse(*updatedCategoryData.ParentID)
+			if err != nil {
+				utils.SendResponse(c, http.StatusBadRequest, "Invalid parent category ID format", nil, nil)
+				return
+			}
+			message, err := validateCategoryParent(DB, userID, category.ID, parentID)
+			if err != nil {
+				utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating parent category", nil, nil)
+				return
+			}
+			if message != "" {
+				utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+				return
+			}
+			category.ParentID = &parentID
+		}
+	}
+
 	// Update ColorCode: use provided value, or keep existing if available, or generate new if not
 	if updatedCategoryData.ColorCode != "" {
 			category.ColorCode = updatedCategoryData.ColorCode
@@ -293,12 +356,775 @@ func DeleteCategory(c *gin.Context) {
 			return
 	}
 
-	// Permanently delete the category from the database
-	if err := DB.Unscoped().Delete(&category).Error; err != nil {
+	// Decide where the expenses, budgets, recurring expenses and rules still using the category go
+	strategy := c.Query("strategy")
+	var targetID uuid.UUID
+	switch strategy {
+	case categoryDeleteReassign:
+		parsedTargetID, err := uuid.Parse(c.Query("target_category_id"))
+		if err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "A valid target_category_id is required to reassign", nil, nil)
+			return
+		}
+		if parsedTargetID == category.ID {
+			utils.SendResponse(c, http.StatusBadRequest, "Cannot reassign a category to itself", nil, nil)
+			return
+		}
+		var targetExists bool
+		err = DB.Model(&models.Category{}).
+			Where("id = ? AND (is_default = ? OR user_id = ?)", parsedTargetID, true, userID).
+			Select("COUNT(1) > 0").
+			Scan(&targetExists).Error
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Database error while verifying target_category_id", nil, nil)
+			return
+		}
+		if !targetExists {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid target_category_id", nil, nil)
+			return
+		}
+		targetID = parsedTargetID
+	case categoryDeleteOthers:
+		targetID, err = rules.FallbackCategoryID(DB)
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch the Others category", nil, nil)
+			return
+		}
+	case categoryDeleteRefuse:
+	default:
+		utils.SendResponse(c, http.StatusBadRequest, "strategy must be 'reassign', 'others' or 'refuse'", nil, nil)
+		return
+	}
+
+	// Budgets of the category that would overlap a budget of the target are rejected or summed, as in a merge
+	budgetStrategy := c.DefaultQuery("budgets", budgetMergeReject)
+	if budgetStrategy != budgetMergeReject && budgetStrategy != budgetMergeSum {
+		utils.SendResponse(c, http.StatusBadRequest, "budgets must be 'reject' or 'sum'", nil, nil)
+		return
+	}
+
+	// Move the references and permanently delete the category in one transaction; its subcategories move up to its parent
+	var moved categoryMoves
+	var summed int64
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if strategy == categoryDeleteRefuse {
+			references, err := countCategoryReferences(tx, category.ID)
+			if err != nil {
+				return err
+			}
+			if references.total() > 0 {
+				utils.SendResponse(c, http.StatusConflict, "Category is still in use", references, nil)
+				return errResponseSent
+			}
+		} else {
+			conflicts, resolved, err := budgetMergeConflicts(tx, userID, category.ID, targetID, budgetStrategy)
+			if err != nil {
+				return err
+			}
+			if len(conflicts) > 0 {
+				utils.SendResponse(c, http.StatusConflict, "Budgets overlap in the target category", gin.H{"conflicts": conflicts}, nil)
+				return errResponseSent
+			}
+			if err := sumMergedBudgets(tx, resolved, category.UserID); err != nil {
+				return err
+			}
+			summed = int64(len(resolved))
+			if moved, err = moveCategoryReferences(tx, category.ID, targetID, category.UserID); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&models.Category{}).Where("parent_id = ?", category.ID).Update("parent_id", category.ParentID).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&category).Error
+	})
+	if errors.Is(err, errResponseSent) {
+		return
+	}
+	if err != nil {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete category", nil, nil)
 		return
 	}
+	if moved.Expenses > 0 || moved.Allocations > 0 {
+		CheckBudgetAlerts(*category.UserID, moved.dates...)
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Category deleted successfully", gin.H{
+		"strategy":           strategy,
+		"target_category_id": targetID,
+		"moved":              moved,
+		"budgets_summed":     summed,
+	}, nil)
+}
+
+// MergeCategory moves everything using one of the user's custom categories into another category, then archives it
+func MergeCategory(c *gin.Context) {
+	// Get the DB instance
+	DB := db.GetDBInstance()
+
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	// Parse the source category ID
+	sourceID, err := uuid.Parse(c.Param("categoryId"))
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid category ID format", nil, nil)
+		return
+	}
+
+	var input struct {
+		TargetCategoryID uuid.UUID `json:"target_category_id" binding:"required"`
+		Budgets          string    `json:"budgets"` // "reject" (default) or "sum" overlapping budgets
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	if input.Budgets == "" {
+		input.Budgets = budgetMergeReject
+	}
+	if input.Budgets != budgetMergeReject && input.Budgets != budgetMergeSum {
+		utils.SendResponse(c, http.StatusBadRequest, "budgets must be 'reject' or 'sum'", nil, nil)
+		return
+	}
+	if input.TargetCategoryID == sourceID {
+		utils.SendResponse(c, http.StatusBadRequest, "Cannot merge a category into itself", nil, nil)
+		return
+	}
+
+	// Only the user's own categories can be merged away; the target may also be a default category
+	var source models.Category
+	if err := DB.Where("id = ? AND user_id = ? AND is_default = ? AND archived_at IS NULL", sourceID, userID, false).First(&source).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Category not found or not allowed to merge", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch category", nil, nil)
+		}
+		return
+	}
+	var target models.Category
+	if err := DB.Where("id = ? AND (is_default = ? OR user_id = ?) AND archived_at IS NULL", input.TargetCategoryID, true, userID).First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid target_category_id", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Database error while verifying target_category_id", nil, nil)
+		}
+		return
+	}
+
+	var moved categoryMoves
+	var summed, subcategories int64
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// Reconcile the budgets that overlap a budget of the target category first
+		conflicts, resolved, err := budgetMergeConflicts(tx, userID, source.ID, target.ID, input.Budgets)
+		if err != nil {
+			return err
+		}
+		if len(conflicts) > 0 {
+			utils.SendResponse(c, http.StatusConflict, "Budgets overlap in the target category", gin.H{"conflicts": conflicts}, nil)
+			return errResponseSent
+		}
+		if err := sumMergedBudgets(tx, resolved, source.UserID); err != nil {
+			return err
+		}
+		summed = int64(len(resolved))
+
+		if moved, err = moveCategoryReferences(tx, source.ID, target.ID, source.UserID); err != nil {
+			return err
+		}
+
+		// Subcategories move under the target; a target nested in the source first moves up to the source's parent
+		var nested bool
+		if err := tx.Model(&models.Category{}).
+			Where("id = ? AND id IN (?)", target.ID, models.CategorySubtree(tx, source.ID)).
+			Select("COUNT(1) > 0").
+			Scan(&nested).Error; err != nil {
+			return err
+		}
+		if nested {
+			if err := tx.Model(&target).Update("parent_id", source.ParentID).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&models.Category{}).Where("parent_id = ?", source.ID).Update("parent_id", target.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		subcategories = result.RowsAffected
+
+		return tx.Model(&source).Update("archived_at", time.Now()).Error
+	})
+	if errors.Is(err, errResponseSent) {
+		return
+	}
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to merge categories", nil, nil)
+		return
+	}
+	if moved.Expenses > 0 || moved.Allocations > 0 {
+		CheckBudgetAlerts(*source.UserID, moved.dates...)
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Categories merged successfully", gin.H{
+		"source_category_id":  source.ID,
+		"target_category_id":  target.ID,
+		"moved":               moved,
+		"budgets_summed":      summed,
+		"subcategories_moved": subcategories,
+	}, nil)
+}
+
+// How budgets of the source category that overlap a budget of the target category are handled by a merge
+const (
+	budgetMergeReject = "reject" // Report them and leave both categories untouched
+	budgetMergeSum    = "sum"    // Add the source amount to a target budget of the same period
+)
+
+// budgetConflict is a budget of the merged category overlapping a budget of the target category
+type budgetConflict struct {
+	SourceBudget models.Budget `json:"source_budget"`
+	TargetBudget models.Budget `json:"target_budget"`
+	Reason       string        `json:"reason"`
+}
+
+// budgetMergeConflicts pairs the user's budgets covering the source category with the other overlapping budgets
+// covering the target. With the sum strategy, pairs of single-category budgets covering the same period are resolved
+// and returned separately; every other overlap is a conflict.
+func budgetMergeConflicts(tx *gorm.DB, userID interface{}, sourceID, targetID uuid.UUID, strategy string) ([]budgetConflict, []budgetConflict, error) {
+	var sourceBudgets []models.Budget
+	if err := models.BudgetsCovering(tx.Where("user_id = ?", userID), []uuid.UUID{sourceID}).
+		Order("start_date ASC").
+		Find(&sourceBudgets).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := models.FillBudgetCategories(tx, sourceBudgets); err != nil {
+		return nil, nil, err
+	}
+
+	conflicts := []budgetConflict{}
+	var resolved []budgetConflict
+	for _, sourceBudget := range sourceBudgets {
+		var overlapping []models.Budget
+		query := models.BudgetsOverlapping(tx, sourceBudget.StartDate, sourceBudget.EndDate).
+			Where("user_id = ? AND budget_id <> ?", userID, sourceBudget.BudgetID)
+		if err := models.BudgetsCovering(query, []uuid.UUID{targetID}).
+			Order("start_date ASC").
+			Find(&overlapping).Error; err != nil {
+			return nil, nil, err
+		}
+		if err := models.FillBudgetCategories(tx, overlapping); err != nil {
+			return nil, nil, err
+		}
+		for _, targetBudget := range overlapping {
+			pair := budgetConflict{SourceBudget: sourceBudget, TargetBudget: targetBudget, Reason: "Budget periods overlap"}
+			summable := sameBudgetSchedule(sourceBudget, targetBudget) && sourceBudget.CategoryID != nil && targetBudget.CategoryID != nil
+			if strategy == budgetMergeSum && summable && len(overlapping) == 1 {
+				resolved = append(resolved, pair)
+				continue
+			}
+			if strategy == budgetMergeSum {
+				pair.Reason = "Budget periods or categories differ and cannot be summed"
+			}
+			conflicts = append(conflicts, pair)
+		}
+	}
+	return conflicts, resolved, nil
+}
+
+// sumMergedBudgets adds the amount of each source budget to the target budget it was paired with, then deletes the
+// source budget
+func sumMergedBudgets(tx *gorm.DB, resolved []budgetConflict, changedBy *uuid.UUID) error {
+	for _, pair := range resolved {
+		previous := models.SnapshotBudget(pair.TargetBudget)
+		if err := tx.Model(&pair.TargetBudget).Update("amount", pair.TargetBudget.Amount.Add(pair.SourceBudget.Amount)).Error; err != nil {
+			return err
+		}
+		if err := models.RecordBudgetVersion(tx, pair.TargetBudget, models.BudgetUpdated, previous, changedBy); err != nil {
+			return err
+		}
+		if err := tx.Delete(&pair.SourceBudget).Error; err != nil {
+			return err
+		}
+		if err := models.RecordBudgetVersion(tx, pair.SourceBudget, models.BudgetDeleted, models.SnapshotBudget(pair.SourceBudget), changedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Strategies for the expenses and budgets of a deleted category
+const (
+	categoryDeleteReassign = "reassign" // Move them to target_category_id
+	categoryDeleteOthers   = "others"   // Move them to the default "Others" category
+	categoryDeleteRefuse   = "refuse"   // Only delete a category nothing uses
+)
+
+// categoryMoves counts the records using a category, or moved off it
+type categoryMoves struct {
+	Expenses          int64 `json:"expenses"`
+	Allocations       int64 `json:"allocations"`
+	Budgets           int64 `json:"budgets"`
+	RecurringExpenses int64 `json:"recurring_expenses"`
+	Rules             int64 `json:"rules"`
+	TemplateLines     int64 `json:"budget_template_lines"`
+
+	dates []time.Time // Distinct dates of the expenses moved, whose budget periods are checked for alerts
+}
+
+func (m categoryMoves) total() int64 {
+	return m.Expenses + m.Allocations + m.Budgets + m.RecurringExpenses + m.Rules + m.TemplateLines
+}
+
+// countCategoryReferences counts the records using a category, including deleted budgets and rules. Budgets count
+// both single-category budgets and the budgets the category is one of several categories of.
+func countCategoryReferences(tx *gorm.DB, categoryID uuid.UUID) (categoryMoves, error) {
+	var references categoryMoves
+	counts := []struct {
+		query *gorm.DB
+		count *int64
+	}{
+		{tx.Model(&models.Expense{}), &references.Expenses},
+		{tx.Model(&models.ExpenseAllocation{}), &references.Allocations},
+		{tx.Unscoped().Model(&models.Budget{}), &references.Budgets},
+		{tx.Unscoped().Model(&models.RecurringExpense{}), &references.RecurringExpenses},
+		{tx.Unscoped().Model(&models.CategoryRule{}), &references.Rules},
+		{tx.Model(&models.BudgetTemplateLine{}), &references.TemplateLines},
+	}
+	for _, count := range counts {
+		if err := count.query.Where("category_id = ?", categoryID).Count(count.count).Error; err != nil {
+			return references, err
+		}
+	}
+	var shared int64
+	if err := tx.Model(&models.BudgetCategory{}).Where("category_id = ?", categoryID).Count(&shared).Error; err != nil {
+		return references, err
+	}
+	references.Budgets += shared
+	return references, nil
+}
+
+// moveCategoryReferences points every record using the category "from" at "to", including deleted budgets, recurring
+// expenses and rules. A split expense with allocations in both categories ends up with one allocation for their sum.
+// The budgets that are not deleted get a new version changed by the given user.
+func moveCategoryReferences(tx *gorm.DB, from, to uuid.UUID, changedBy *uuid.UUID) (categoryMoves, error) {
+	var moved categoryMoves
+
+	spending := tx.Model(&models.Expense{}).Where("category_id = ? OR expense_id IN (?)", from,
+		tx.Model(&models.ExpenseAllocation{}).Select("expense_id").Where("category_id = ?", from))
+	if err := spending.Distinct("date").Pluck("date", &moved.dates).Error; err != nil {
+		return moved, err
+	}
+
+	var affected []models.Budget
+	if err := models.BudgetsCovering(tx, []uuid.UUID{from}).Find(&affected).Error; err != nil {
+		return moved, err
+	}
+	if err := models.FillBudgetCategories(tx, affected); err != nil {
+		return moved, err
+	}
+
+	var allocations []models.ExpenseAllocation
+	if err := tx.Where("category_id = ?", from).Find(&allocations).Error; err != nil {
+		return moved, err
+	}
+	if len(allocations) > 0 {
+		expenseIDs := make([]uuid.UUID, len(allocations))
+		for i, allocation := range allocations {
+			expenseIDs[i] = allocation.ExpenseID
+		}
+		var existing []models.ExpenseAllocation
+		if err := tx.Where("category_id = ? AND expense_id IN ?", to, expenseIDs).Find(&existing).Error; err != nil {
+			return moved, err
+		}
+		byExpense := make(map[uuid.UUID]models.ExpenseAllocation, len(existing))
+		for _, allocation := range existing {
+			byExpense[allocation.ExpenseID] = allocation
+		}
+
+		var reassigned []uuid.UUID
+		for _, allocation := range allocations {
+			target, ok := byExpense[allocation.ExpenseID]
+			if !ok {
+				reassigned = append(reassigned, allocation.ID)
+				continue
+			}
+			if err := tx.Model(&target).Update("amount", target.Amount.Add(allocation.Amount)).Error; err != nil {
+				return moved, err
+			}
+			if err := tx.Delete(&allocation).Error; err != nil {
+				return moved, err
+			}
+		}
+		if len(reassigned) > 0 {
+			if err := tx.Model(&models.ExpenseAllocation{}).Where("id IN ?", reassigned).Update("category_id", to).Error; err != nil {
+				return moved, err
+			}
+		}
+		moved.Allocations = int64(len(allocations))
+	}
+
+	updates := []struct {
+		query *gorm.DB
+		count *int64
+	}{
+		{tx.Model(&models.Expense{}), &moved.Expenses},
+		{tx.Unscoped().Model(&models.Budget{}), &moved.Budgets},
+		{tx.Unscoped().Model(&models.RecurringExpense{}), &moved.RecurringExpenses},
+		{tx.Unscoped().Model(&models.CategoryRule{}), &moved.Rules},
+	}
+	for _, update := range updates {
+		result := update.query.Where("category_id = ?", from).Update("category_id", to)
+		if result.Error != nil {
+			return moved, result.Error
+		}
+		*update.count = result.RowsAffected
+	}
+
+	// A multi-category budget already covering "to" only loses "from"
+	result := tx.Where("category_id = ? AND budget_id IN (?)", from,
+		tx.Model(&models.BudgetCategory{}).Select("budget_id").Where("category_id = ?", to)).
+		Delete(&models.BudgetCategory{})
+	if result.Error != nil {
+		return moved, result.Error
+	}
+	moved.Budgets += result.RowsAffected
+	result = tx.Model(&models.BudgetCategory{}).Where("category_id = ?", from).Update("category_id", to)
+	if result.Error != nil {
+		return moved, result.Error
+	}
+	moved.Budgets += result.RowsAffected
+	if err := recordMovedBudgets(tx, affected, changedBy); err != nil {
+		return moved, err
+	}
+
+	// A template with lines for both categories ends up with one line for their sum
+	var templateLines []models.BudgetTemplateLine
+	if err := tx.Where("category_id = ?", from).Find(&templateLines).Error; err != nil {
+		return moved, err
+	}
+	for _, line := range templateLines {
+		var target models.BudgetTemplateLine
+		err := tx.Where("template_id = ? AND category_id = ?", line.TemplateID, to).First(&target).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Model(&line).Update("category_id", to).Error; err != nil {
+				return moved, err
+			}
+			continue
+		}
+		if err != nil {
+			return moved, err
+		}
+		if err := tx.Model(&target).Update("amount", target.Amount.Add(line.Amount)).Error; err != nil {
+			return moved, err
+		}
+		if err := tx.Delete(&line).Error; err != nil {
+			return moved, err
+		}
+	}
+	moved.TemplateLines = int64(len(templateLines))
+
+	// Envelope transfers keep pointing at the categories their money moved between
+	for _, column := range []string{"from_category_id", "to_category_id"} {
+		if err := tx.Model(&models.EnvelopeTransfer{}).Where(column+" = ?", from).Update(column, to).Error; err != nil {
+			return moved, err
+		}
+	}
+	return moved, nil
+}
+
+// recordMovedBudgets records a version of each budget whose categories were moved, comparing it with its values
+// before the move
+func recordMovedBudgets(tx *gorm.DB, before []models.Budget, changedBy *uuid.UUID) error {
+	if len(before) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(before))
+	for i, budget := range before {
+		ids[i] = budget.BudgetID
+	}
+	var after []models.Budget
+	if err := tx.Where("budget_id IN ?", ids).Find(&after).Error; err != nil {
+		return err
+	}
+	if err := models.FillBudgetCategories(tx, after); err != nil {
+		return err
+	}
+	previous := make(map[uuid.UUID]*models.BudgetSnapshot, len(before))
+	for _, budget := range before {
+		previous[budget.BudgetID] = models.SnapshotBudget(budget)
+	}
+	for _, budget := range after {
+		if err := models.RecordBudgetVersion(tx, budget, models.BudgetUpdated, previous[budget.BudgetID], changedBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ArchiveCategory retires one of the user's custom categories: it leaves the category list but keeps its history
+func ArchiveCategory(c *gin.Context) {
+	setCategoryArchived(c, true)
+}
+
+// UnarchiveCategory brings an archived custom category back into the category list
+func UnarchiveCategory(c *gin.Context) {
+	setCategoryArchived(c, false)
+}
+
+// setCategoryArchived archives or restores one of the user's custom categories
+func setCategoryArchived(c *gin.Context, archived bool) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	categoryID, err := uuid.Parse(c.Param("categoryId"))
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid category ID format", nil, nil)
+		return
+	}
+
+	// Default categories are shared; users hide them through their preferences instead
+	var category models.Category
+	if err := db.GetDBInstance().Where("id = ? AND user_id = ? AND is_default = ?", categoryID, userID, false).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Category not found or not allowed to archive", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch category", nil, nil)
+		}
+		return
+	}
 
-	utils.SendResponse(c, http.StatusOK, "Category deleted successfully", nil, nil)
+	var archivedAt *time.Time
+	if archived {
+		if category.ArchivedAt != nil {
+			utils.SendResponse(c, http.StatusOK, "Category archived successfully", category, nil)
+			return
+		}
+		now := time.Now()
+		archivedAt = &now
+	}
+	if err := db.GetDBInstance().Model(&category).Update("archived_at", archivedAt).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to update category", nil, nil)
+		return
+	}
+
+	if archived {
+		utils.SendResponse(c, http.StatusOK, "Category archived successfully", category, nil)
+	} else {
+		utils.SendResponse(c, http.StatusOK, "Category restored successfully", category, nil)
+	}
 }
 
+// SetCategoryPreference hides or renames a default category for the user only
+func SetCategoryPreference(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	category, ok := fetchDefaultCategory(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Hidden *bool   `json:"hidden"`
+		Name   *string `json:"name"` // "" restores the default name
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	if input.Hidden == nil && input.Name == nil {
+		utils.SendResponse(c, http.StatusBadRequest, "No valid fields to update", nil, nil)
+		return
+	}
+
+	preference := models.CategoryPreference{UserID: userID.(uuid.UUID), CategoryID: category.ID}
+	if err := db.GetDBInstance().Where(&preference).Limit(1).Find(&preference).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch category preference", nil, nil)
+		return
+	}
+	if input.Hidden != nil {
+		preference.Hidden = *input.Hidden
+	}
+	if input.Name != nil {
+		preference.Name = strings.TrimSpace(*input.Name)
+		if len(preference.Name) > 50 {
+			utils.SendResponse(c, http.StatusBadRequest, "Category name must be at most 50 characters", nil, nil)
+			return
+		}
+		if preference.Name == category.Name {
+			preference.Name = ""
+		}
+	}
+
+	if err := db.GetDBInstance().Save(&preference).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to save category preference", nil, nil)
+		return
+	}
+
+	preference.Apply(category)
+	utils.SendResponse(c, http.StatusOK, "Category preference saved successfully", category, nil)
+}
+
+// ResetCategoryPreference shows a default category to the user again under its default name
+func ResetCategoryPreference(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	category, ok := fetchDefaultCategory(c)
+	if !ok {
+		return
+	}
+
+	if err := db.GetDBInstance().Where("user_id = ? AND category_id = ?", userID, category.ID).Delete(&models.CategoryPreference{}).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to reset category preference", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Category preference reset successfully", category, nil)
+}
+
+// fetchDefaultCategory loads the default category from the URL, sending the error response itself
+func fetchDefaultCategory(c *gin.Context) (*models.Category, bool) {
+	categoryID, err := uuid.Parse(c.Param("categoryId"))
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid category ID format", nil, nil)
+		return nil, false
+	}
+
+	var category models.Category
+	if err := db.GetDBInstance().Where("id = ? AND is_default = ?", categoryID, true).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Default category not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch category", nil, nil)
+		}
+		return nil, false
+	}
+	return &category, true
+}
+
+// visibleCategories applies the user's preferences to the categories and leaves out hidden default categories and
+// archived categories, together with their subcategories, unless they are included
+func visibleCategories(database *gorm.DB, userID interface{}, categories []models.Category, includeHidden, includeArchived bool) ([]models.Category, error) {
+	preferences, err := models.FetchCategoryPreferences(database, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := map[uuid.UUID]uuid.UUID{}
+	excluded := map[uuid.UUID]bool{}
+	for i := range categories {
+		category := &categories[i]
+		if preference, ok := preferences[category.ID]; ok && category.IsDefault {
+			preference.Apply(category)
+		}
+		if category.ParentID != nil {
+			parents[category.ID] = *category.ParentID
+		}
+		if (category.Hidden && !includeHidden) || (category.ArchivedAt != nil && !includeArchived) {
+			excluded[category.ID] = true
+		}
+	}
+
+	visible := make([]models.Category, 0, len(categories))
+	for _, category := range categories {
+		if excluded[category.ID] {
+			continue
+		}
+		hiddenAncestor := false
+		for _, ancestorID := range models.CategoryAncestors(parents, category.ID) {
+			if excluded[ancestorID] {
+				hiddenAncestor = true
+				break
+			}
+		}
+		if !hiddenAncestor {
+			visible = append(visible, category)
+		}
+	}
+	return visible, nil
+}
+
+// validateCategoryParent checks a new parent for a category (uuid.Nil for a new one) and returns a message for the
+// client when it is invalid: the parent must be visible to the user and must not be the category or one of its descendants
+func validateCategoryParent(database *gorm.DB, userID interface{}, categoryID, parentID uuid.UUID) (string, error) {
+	var parentExists bool
+	err := database.Model(&models.Category{}).
+		Where("id = ? AND (is_default = ? OR user_id = ?)", parentID, true, userID).
+		Select("COUNT(1) > 0").
+		Scan(&parentExists).Error
+	if err != nil {
+		return "", err
+	}
+	if !parentExists {
+		return "Invalid parent category", nil
+	}
+
+	if categoryID == uuid.Nil {
+		return "", nil
+	}
+	var cycle bool
+	err = database.Model(&models.Category{}).
+		Where("id = ? AND id IN (?)", parentID, models.CategorySubtree(database, categoryID)).
+		Select("COUNT(1) > 0").
+		Scan(&cycle).Error
+	if err != nil {
+		return "", err
+	}
+	if cycle {
+		return "A category cannot be moved under itself or one of its subcategories", nil
+	}
+	return "", nil
+}
+
+// categoryTree nests the categories under their parents; categories whose parent is not in the list become roots
+func categoryTree(categories []models.Category) []models.Category {
+	children := map[uuid.UUID][]models.Category{}
+	present := make(map[uuid.UUID]bool, len(categories))
+	for _, category := range categories {
+		present[category.ID] = true
+	}
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID != nil && present[*category.ParentID] && *category.ParentID != category.ID {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	// Attach children depth first; visited guards against cycles
+	visited := map[uuid.UUID]bool{}
+	var attach func(category models.Category) models.Category
+	attach = func(category models.Category) models.Category {
+		visited[category.ID] = true
+		category.Children = nil
+		for _, child := range children[category.ID] {
+			if !visited[child.ID] {
+				category.Children = append(category.Children, attach(child))
+			}
+		}
+		return category
+	}
+	tree := make([]models.Category, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, attach(root))
+	}
+	return tree
+}
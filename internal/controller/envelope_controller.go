@@ -0,0 +1,598 @@
+package controller
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/fx"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"expense-mgmt/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// envelope is the allocation to one category of an envelope plan and what has been drawn from it
+type envelope struct {
+	BudgetID   uuid.UUID     `json:"budget_id"`
+	CategoryID *uuid.UUID    `json:"category_id"`
+	Category   string        `json:"category"`
+	Allocated  money.Decimal `json:"allocated"`
+	Spent      money.Decimal `json:"spent"`
+	Remaining  money.Decimal `json:"remaining"`
+	Overspent  bool          `json:"overspent"`
+}
+
+// envelopePlanSummary is an envelope plan with its envelopes and the income left to allocate
+type envelopePlanSummary struct {
+	models.EnvelopePlan
+	Allocated  money.Decimal `json:"allocated"`
+	Unassigned money.Decimal `json:"unassigned"` // Income not allocated yet; zero once every unit of income has a job
+	Spent      money.Decimal `json:"spent"`
+	Envelopes  []envelope    `json:"envelopes"`
+	Currency   string        `json:"currency"` // Home currency of the amounts
+}
+
+// CreateEnvelopePlan declares the income of a period to allocate across category envelopes
+func CreateEnvelopePlan(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var input struct {
+		StartDate string        `json:"start_date" binding:"required"`
+		EndDate   string        `json:"end_date" binding:"required"`
+		Income    money.Decimal `json:"income"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	if input.Income.Sign() < 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid input: income cannot be negative", nil, nil)
+		return
+	}
+	startDate, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid start_date format (expected YYYY-MM-DD): %v", err), nil, nil)
+		return
+	}
+	endDate, err := time.Parse("2006-01-02", input.EndDate)
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid end_date format (expected YYYY-MM-DD): %v", err), nil, nil)
+		return
+	}
+
+	plan := models.EnvelopePlan{
+		UserID:    userID.(uuid.UUID),
+		StartDate: startDate,
+		EndDate:   endDate,
+		Income:    input.Income,
+	}
+	message, err := checkEnvelopePlan(db.GetDBInstance(), &plan)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to validate envelope plan", nil, nil)
+		return
+	}
+	if message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+		return
+	}
+
+	if err := db.GetDBInstance().Create(&plan).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to create envelope plan", nil, nil)
+		return
+	}
+
+	summary, err := summarizeEnvelopePlan(db.GetDBInstance(), plan)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch envelope plan", nil, nil)
+		return
+	}
+	utils.SendResponse(c, http.StatusCreated, "Envelope plan created successfully", summary, nil)
+}
+
+// ListEnvelopePlans fetches the user's envelope plans, most recent period first
+func ListEnvelopePlans(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var plans []models.EnvelopePlan
+	if err := db.GetDBInstance().Where("user_id = ?", userID).Order("start_date DESC").Find(&plans).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch envelope plans", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Envelope plans fetched successfully", plans, nil)
+}
+
+// GetEnvelopePlan fetches an envelope plan with the allocation, spending and balance of each envelope
+func GetEnvelopePlan(c *gin.Context) {
+	plan, ok := fetchUserEnvelopePlan(c)
+	if !ok {
+		return
+	}
+
+	summary, err := summarizeEnvelopePlan(db.GetDBInstance(), *plan)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch envelope plan", nil, nil)
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "Envelope plan fetched successfully", summary, nil)
+}
+
+// GetEnvelopeUnassigned reports the income of a plan that is not allocated to an envelope yet
+func GetEnvelopeUnassigned(c *gin.Context) {
+	plan, ok := fetchUserEnvelopePlan(c)
+	if !ok {
+		return
+	}
+
+	allocated, err := envelopesAllocated(db.GetDBInstance(), plan.ID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch envelopes", nil, nil)
+		return
+	}
+	home, err := fx.HomeCurrency(db.GetDBInstance(), plan.UserID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch user currency", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Unassigned balance fetched successfully", gin.H{
+		"plan_id":    plan.ID,
+		"income":     plan.Income,
+		"allocated":  allocated,
+		"unassigned": plan.Income.Sub(allocated),
+		"currency":   home,
+	}, nil)
+}
+
+// UpdateEnvelopeIncome changes the income of a plan; it cannot drop below what is already allocated
+func UpdateEnvelopeIncome(c *gin.Context) {
+	plan, ok := fetchUserEnvelopePlan(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Income *money.Decimal `json:"income" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	if input.Income.Sign() < 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid input: income cannot be negative", nil, nil)
+		return
+	}
+
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := lockEnvelopePlan(tx, plan); err != nil {
+			return err
+		}
+		allocated, err := envelopesAllocated(tx, plan.ID)
+		if err != nil {
+			return err
+		}
+		if input.Income.LessThan(allocated) {
+			utils.SendResponse(c, http.StatusBadRequest, "Income cannot be lower than the amount already allocated; move money out of envelopes first",
+				gin.H{"allocated": allocated}, nil)
+			return errResponseSent
+		}
+		plan.Income = *input.Income
+		return tx.Model(plan).Update("income", plan.Income).Error
+	})
+	if errors.Is(err, errResponseSent) {
+		return
+	}
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to update income", nil, nil)
+		return
+	}
+
+	summary, err := summarizeEnvelopePlan(db.GetDBInstance(), *plan)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch envelope plan", nil, nil)
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "Income updated successfully", summary, nil)
+}
+
+// SetEnvelopeAllocation sets the amount allocated to a category's envelope, creating the envelope if needed. The
+// difference comes out of, or goes back to, the unassigned balance and is recorded as a transfer.
+func SetEnvelopeAllocation(c *gin.Context) {
+	plan, ok := fetchUserEnvelopePlan(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CategoryID uuid.UUID      `json:"category_id" binding:"required"`
+		Amount     *money.Decimal `json:"amount" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	if input.Amount.Sign() < 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid input: amount cannot be negative", nil, nil)
+		return
+	}
+
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := lockEnvelopePlan(tx, plan); err != nil {
+			return err
+		}
+		current, err := planEnvelope(tx, plan, input.CategoryID)
+		if err != nil {
+			return err
+		}
+		allocated := money.Zero
+		if current != nil {
+			allocated = current.Amount
+		}
+		change := input.Amount.Sub(allocated)
+		if change.IsZero() {
+			return nil
+		}
+
+		transfer := models.EnvelopeTransfer{PlanID: plan.ID, UserID: plan.UserID, Note: "Allocation"}
+		if change.Sign() > 0 {
+			transfer.ToCategoryID, transfer.Amount = &input.CategoryID, change
+			if message, err := withdrawUnassigned(tx, plan, change); err != nil || message != "" {
+				return sendEnvelopeError(c, message, err)
+			}
+		} else {
+			transfer.FromCategoryID, transfer.Amount = &input.CategoryID, change.Neg()
+		}
+		if message, err := setEnvelopeAmount(tx, plan, current, input.CategoryID, *input.Amount); err != nil || message != "" {
+			return sendEnvelopeError(c, message, err)
+		}
+		return tx.Create(&transfer).Error
+	})
+	if errors.Is(err, errResponseSent) {
+		return
+	}
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to allocate to envelope", nil, nil)
+		return
+	}
+
+	summary, err := summarizeEnvelopePlan(db.GetDBInstance(), *plan)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch envelope plan", nil, nil)
+		return
+	}
+	utils.SendResponse(c, http.StatusOK, "Envelope allocation updated successfully", summary, nil)
+}
+
+// CreateEnvelopeTransfer moves money between two envelopes of a plan, or between an envelope and the unassigned
+// balance when one side is left out
+func CreateEnvelopeTransfer(c *gin.Context) {
+	plan, ok := fetchUserEnvelopePlan(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		FromCategoryID *uuid.UUID    `json:"from_category_id"` // Leave out to take from the unassigned balance
+		ToCategoryID   *uuid.UUID    `json:"to_category_id"`   // Leave out to return money to the unassigned balance
+		Amount         money.Decimal `json:"amount"`
+		Note           string        `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	if input.Amount.Sign() <= 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid input: amount must be greater than zero", nil, nil)
+		return
+	}
+	if input.FromCategoryID == nil && input.ToCategoryID == nil ||
+		input.FromCategoryID != nil && input.ToCategoryID != nil && *input.FromCategoryID == *input.ToCategoryID {
+		utils.SendResponse(c, http.StatusBadRequest, "from_category_id and to_category_id must differ; leave one out for the unassigned balance", nil, nil)
+		return
+	}
+	if len(input.Note) > 255 {
+		utils.SendResponse(c, http.StatusBadRequest, "note must be at most 255 characters", nil, nil)
+		return
+	}
+
+	transfer := models.EnvelopeTransfer{
+		PlanID:         plan.ID,
+		UserID:         plan.UserID,
+		FromCategoryID: input.FromCategoryID,
+		ToCategoryID:   input.ToCategoryID,
+		Amount:         input.Amount,
+		Note:           input.Note,
+	}
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := lockEnvelopePlan(tx, plan); err != nil {
+			return err
+		}
+
+		if input.FromCategoryID == nil {
+			if message, err := withdrawUnassigned(tx, plan, input.Amount); err != nil || message != "" {
+				return sendEnvelopeError(c, message, err)
+			}
+		} else {
+			from, err := planEnvelope(tx, plan, *input.FromCategoryID)
+			if err != nil {
+				return err
+			}
+			if from == nil {
+				return sendEnvelopeError(c, "The plan has no envelope for from_category_id", nil)
+			}
+			if input.Amount.GreaterThan(from.Amount) {
+				return sendEnvelopeError(c, fmt.Sprintf("Only %s is allocated to the source envelope", from.Amount), nil)
+			}
+			if err := updateEnvelopeAmount(tx, plan, from, from.Amount.Sub(input.Amount)); err != nil {
+				return err
+			}
+		}
+
+		if input.ToCategoryID != nil {
+			to, err := planEnvelope(tx, plan, *input.ToCategoryID)
+			if err != nil {
+				return err
+			}
+			amount := input.Amount
+			if to != nil {
+				amount = to.Amount.Add(input.Amount)
+			}
+			if message, err := setEnvelopeAmount(tx, plan, to, *input.ToCategoryID, amount); err != nil || message != "" {
+				return sendEnvelopeError(c, message, err)
+			}
+		}
+		return tx.Create(&transfer).Error
+	})
+	if errors.Is(err, errResponseSent) {
+		return
+	}
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to transfer between envelopes", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusCreated, "Transfer recorded successfully", transfer, nil)
+}
+
+// ListEnvelopeTransfers fetches the transfer history of a plan, most recent first
+func ListEnvelopeTransfers(c *gin.Context) {
+	plan, ok := fetchUserEnvelopePlan(c)
+	if !ok {
+		return
+	}
+
+	var transfers []models.EnvelopeTransfer
+	if err := db.GetDBInstance().Where("plan_id = ?", plan.ID).Order("created_at DESC").Find(&transfers).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch transfers", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Transfers fetched successfully", transfers, nil)
+}
+
+// DeleteEnvelopePlan removes a plan together with its envelopes; the transfer history stays until purged
+func DeleteEnvelopePlan(c *gin.Context) {
+	plan, ok := fetchUserEnvelopePlan(c)
+	if !ok {
+		return
+	}
+
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		var envelopes []models.Budget
+		if err := tx.Where("envelope_plan_id = ?", plan.ID).Find(&envelopes).Error; err != nil {
+			return err
+		}
+		for _, envelope := range envelopes {
+			if err := tx.Delete(&envelope).Error; err != nil {
+				return err
+			}
+			if err := models.RecordBudgetVersion(tx, envelope, models.BudgetDeleted, models.SnapshotBudget(envelope), &plan.UserID); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(plan).Error
+	})
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete envelope plan", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Envelope plan deleted successfully", nil, nil)
+}
+
+// fetchUserEnvelopePlan loads the plan named in the URL if it belongs to the user, sending the error response otherwise
+func fetchUserEnvelopePlan(c *gin.Context) (*models.EnvelopePlan, bool) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return nil, false
+	}
+
+	// Validate the planId format
+	planID := c.Param("planId")
+	if _, err := uuid.Parse(planID); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid plan ID format", nil, nil)
+		return nil, false
+	}
+
+	var plan models.EnvelopePlan
+	if err := db.GetDBInstance().Where("user_id = ? AND id = ?", userID, planID).First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Envelope plan not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch envelope plan", nil, nil)
+		}
+		return nil, false
+	}
+
+	return &plan, true
+}
+
+// checkEnvelopePlan validates the dates of a plan the way those of a one-off budget are, and checks that it does not
+// overlap another plan of the user. It returns a message for the client when the plan is rejected.
+func checkEnvelopePlan(database *gorm.DB, plan *models.EnvelopePlan) (string, error) {
+	if message := validateBudgetSchedule(&models.Budget{StartDate: plan.StartDate, EndDate: &plan.EndDate}); message != "" {
+		return message, nil
+	}
+
+	var exists bool
+	query := models.BudgetsOverlapping(database.Model(&models.EnvelopePlan{}), plan.StartDate, &plan.EndDate).
+		Where("user_id = ?", plan.UserID)
+	if plan.ID != uuid.Nil {
+		query = query.Where("id <> ?", plan.ID)
+	}
+	if err := query.Select("COUNT(1) > 0").Scan(&exists).Error; err != nil {
+		return "", err
+	}
+	if exists {
+		return "Envelope plan period overlaps with an existing envelope plan", nil
+	}
+	return "", nil
+}
+
+// lockEnvelopePlan reloads the plan holding a row lock, so concurrent allocations cannot both spend the same income
+func lockEnvelopePlan(tx *gorm.DB, plan *models.EnvelopePlan) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(plan, "id = ?", plan.ID).Error
+}
+
+// planEnvelope returns the plan's envelope for the category, or nil when it has none
+func planEnvelope(tx *gorm.DB, plan *models.EnvelopePlan, categoryID uuid.UUID) (*models.Budget, error) {
+	var budget models.Budget
+	err := tx.Where("envelope_plan_id = ? AND category_id = ?", plan.ID, categoryID).First(&budget).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+// envelopesAllocated sums the amounts allocated to the envelopes of a plan
+func envelopesAllocated(database *gorm.DB, planID uuid.UUID) (money.Decimal, error) {
+	var allocated money.Decimal
+	err := database.Model(&models.Budget{}).
+		Where("envelope_plan_id = ?", planID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&allocated).Error
+	return allocated, err
+}
+
+// withdrawUnassigned checks that the unassigned balance of the plan covers the amount. It returns a message for the
+// client when it does not.
+func withdrawUnassigned(tx *gorm.DB, plan *models.EnvelopePlan, amount money.Decimal) (string, error) {
+	allocated, err := envelopesAllocated(tx, plan.ID)
+	if err != nil {
+		return "", err
+	}
+	if unassigned := plan.Income.Sub(allocated); amount.GreaterThan(unassigned) {
+		return fmt.Sprintf("Only %s of the income is unassigned", unassigned), nil
+	}
+	return "", nil
+}
+
+// setEnvelopeAmount updates the amount of an envelope, or creates the envelope for the category as a budget over the
+// plan's period. A new envelope goes through the same checks as CreateBudget, so it cannot overlap another budget
+// on the category. It returns a message for the client when the envelope is rejected.
+func setEnvelopeAmount(tx *gorm.DB, plan *models.EnvelopePlan, current *models.Budget, categoryID uuid.UUID, amount money.Decimal) (string, error) {
+	if current != nil {
+		return "", updateEnvelopeAmount(tx, plan, current, amount)
+	}
+
+	endDate := plan.EndDate
+	budget := models.Budget{
+		UserID:         plan.UserID,
+		CategoryID:     &categoryID,
+		Amount:         amount,
+		StartDate:      plan.StartDate,
+		EndDate:        &endDate,
+		EnvelopePlanID: &plan.ID,
+	}
+	message, err := checkBudget(tx, &budget)
+	if err != nil || message != "" {
+		return message, err
+	}
+	if err := tx.Create(&budget).Error; err != nil {
+		return "", err
+	}
+	return "", models.RecordBudgetVersion(tx, budget, models.BudgetCreated, nil, &plan.UserID)
+}
+
+// updateEnvelopeAmount changes the amount allocated to an envelope and records the new version of its budget
+func updateEnvelopeAmount(tx *gorm.DB, plan *models.EnvelopePlan, envelope *models.Budget, amount money.Decimal) error {
+	previous := models.SnapshotBudget(*envelope)
+	if err := tx.Model(envelope).Update("amount", amount).Error; err != nil {
+		return err
+	}
+	return models.RecordBudgetVersion(tx, *envelope, models.BudgetUpdated, previous, &plan.UserID)
+}
+
+// sendEnvelopeError ends an envelope transaction: an error rolls it back as is, a message is sent to the client as
+// a bad request
+func sendEnvelopeError(c *gin.Context, message string, err error) error {
+	if err != nil {
+		return err
+	}
+	utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+	return errResponseSent
+}
+
+// summarizeEnvelopePlan computes the allocation, spending and balance of each envelope of the plan. Each expense
+// draws down the envelope of its category, or of the nearest parent category with an envelope.
+func summarizeEnvelopePlan(database *gorm.DB, plan models.EnvelopePlan) (envelopePlanSummary, error) {
+	summary := envelopePlanSummary{EnvelopePlan: plan, Allocated: money.Zero, Spent: money.Zero, Envelopes: []envelope{}}
+
+	var list []models.Budget
+	if err := database.Where("envelope_plan_id = ?", plan.ID).Order("created_at ASC").Find(&list).Error; err != nil {
+		return summary, err
+	}
+	home, err := fx.HomeCurrency(database, plan.UserID)
+	if err != nil {
+		return summary, err
+	}
+	summary.Currency = home
+
+	if len(list) > 0 {
+		results, err := budgetPeriodResults(database, plan.UserID, home, list, plan.StartDate, 0, nil, nil)
+		if err != nil {
+			return summary, err
+		}
+		preferences, err := models.FetchCategoryPreferences(database, plan.UserID)
+		if err != nil {
+			return summary, err
+		}
+		for _, budget := range list {
+			result := results[budget.BudgetID][0]
+			summary.Allocated = summary.Allocated.Add(budget.Amount)
+			summary.Spent = summary.Spent.Add(result.TotalSpent)
+			summary.Envelopes = append(summary.Envelopes, envelope{
+				BudgetID:   budget.BudgetID,
+				CategoryID: budget.CategoryID,
+				Category:   budgetCategoryName(database, budget, preferences),
+				Allocated:  budget.Amount,
+				Spent:      result.TotalSpent,
+				Remaining:  result.Remaining,
+				Overspent:  result.Exceeds,
+			})
+		}
+	}
+	summary.Unassigned = plan.Income.Sub(summary.Allocated)
+	return summary, nil
+}
@@ -1,11 +1,18 @@
 package controller
 
 import (
+	"errors"
 	"expense-mgmt/db"
+	"expense-mgmt/internal/fx"
 	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"expense-mgmt/internal/storage"
+	"expense-mgmt/internal/suggest"
 	"expense-mgmt/utils"
 	"fmt"
+	"log"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -34,7 +41,7 @@ func CreateExpense(c *gin.Context) {
 	}
 
 	// Validate fields individually and provide specific error messages
-	if expense.Amount <= 0 {
+	if expense.Amount.Sign() <= 0 {
 		utils.SendResponse(c, http.StatusBadRequest, "Amount must be a positive number", nil, nil)
 		return
 	}
@@ -44,6 +51,44 @@ func CreateExpense(c *gin.Context) {
 		return
 	}
 
+	// The amount is in the given currency, the user's home currency by default
+	home, err := fx.HomeCurrency(DB, userID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while fetching user currency", nil, nil)
+		return
+	}
+	currency := home
+	if expense.Currency != "" {
+		normalized, ok := fx.NormalizeCurrency(expense.Currency)
+		if !ok {
+			utils.SendResponse(c, http.StatusBadRequest, "Currency must be an ISO-4217 code", nil, nil)
+			return
+		}
+		currency = normalized
+	}
+
+	// Validate the split; a split expense defaults to the category with the largest share
+	allocations := expense.Allocations
+	if len(allocations) > 0 {
+		if expense.CategoryID == uuid.Nil {
+			expense.CategoryID = primaryAllocationCategory(allocations)
+		}
+		message, err := validateAllocations(DB, expense.Amount, allocations)
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating allocations", nil, nil)
+			return
+		}
+		if message != "" {
+			utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+			return
+		}
+	}
+
+	// Without a category, the user's rules pick one
+	if expense.CategoryID == uuid.Nil && !sendAutoCategorizeError(c, autoCategorize(DB, userID, &expense, currency)) {
+		return
+	}
+
 	// Validate CategoryID by checking if it exists in the database
 	var category models.Category
 	if err := DB.First(&category, "id = ?", expense.CategoryID).Error; err != nil {
@@ -51,15 +96,53 @@ func CreateExpense(c *gin.Context) {
 		return
 	}
 
+	// Tags must belong to the user
+	tagIDs := expense.TagIDs
+	if message, err := validateTags(DB, userID, tagIDs); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating tags", nil, nil)
+		return
+	} else if message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+		return
+	}
+
+	// Convert into the home currency at the rate of the expense date
+	if !sendPricingError(c, priceExpense(fx.NewConverter(DB), home, &expense, currency, expense.Amount)) {
+		return
+	}
+	allocations = scaleAllocations(allocations, expense.Amount, home)
+
 	// Set the user_id
 	expense.UserID = userID.(uuid.UUID)
 
-	// Save the expense to the database
-	if err := DB.Create(&expense).Error; err != nil {
+	// Save the expense and its split, linking the receipt both ways if one was given
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		receiptID := expense.ReceiptID
+		expense.ReceiptID = nil
+		if err := tx.Create(&expense).Error; err != nil {
+			return err
+		}
+		if err := saveAllocations(tx, &expense, allocations); err != nil {
+			return err
+		}
+		if err := saveTags(tx, &expense, tagIDs); err != nil {
+			return err
+		}
+		if receiptID != nil {
+			return attachReceipt(tx, &expense, receiptID.String())
+		}
+		return nil
+	})
+	if err != nil {
+		if sendReceiptLinkError(c, err) {
+			return
+		}
 		// If saving fails, return the error message from the database
 		utils.SendResponse(c, http.StatusInternalServerError, "Error saving expense", nil, err.Error())
 		return
 	}
+	suggest.Learn(DB, expense.UserID, &expense)
+	CheckBudgetAlerts(expense.UserID, expense.Date)
 
 	// Respond with the created expense
 	utils.SendResponse(c, http.StatusOK, "Expense created successfully", expense, nil)
@@ -88,10 +171,19 @@ func ListUserExpenses(c *gin.Context) {
 	startDate := c.Query("start_date")
 	endDate := c.Query("end_date")
 	categoryID := c.Query("category_id")
+	recurringExpenseID := c.Query("recurring_expense_id")
 	minAmount := c.Query("min_amount")
 	maxAmount := c.Query("max_amount")
 	sort := c.DefaultQuery("sort", "date")
 	order := c.DefaultQuery("order", "asc")
+	tagIDs, tagMode, err := parseTagFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  http.StatusBadRequest,
+			"message": err.Error(),
+		})
+		return
+	}
 
 	// Build the query with filters
 	query := db.GetDBInstance().Model(&models.Expense{}).Where("user_id = ?", userID)
@@ -107,7 +199,13 @@ func ListUserExpenses(c *gin.Context) {
 		}
 	}
 	if categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+		// Subcategories are included, and split expenses match any of their allocated categories
+		subtree := models.CategorySubtree(db.GetDBInstance(), categoryID)
+		query = query.Where("category_id IN (?) OR expense_id IN (?)", subtree,
+			db.GetDBInstance().Model(&models.ExpenseAllocation{}).Select("expense_id").Where("category_id IN (?)", subtree))
+	}
+	if recurringExpenseID != "" {
+		query = query.Where("recurring_expense_id = ?", recurringExpenseID)
 	}
 	if minAmount != "" {
 		query = query.Where("amount >= ?", minAmount)
@@ -115,6 +213,8 @@ func ListUserExpenses(c *gin.Context) {
 	if maxAmount != "" {
 		query = query.Where("amount <= ?", maxAmount)
 	}
+	// Expenses carrying any (OR) or all (AND) of the tags
+	query = filterByTags(db.GetDBInstance(), query, tagIDs, tagMode)
 
 	// Count the total number of records
 	query.Count(&totalCount)
@@ -131,6 +231,26 @@ func ListUserExpenses(c *gin.Context) {
 		return
 	}
 
+	// Attach the split and the tags of each expense on the page
+	pageExpenses := make([]*models.Expense, len(expenses))
+	for i := range expenses {
+		pageExpenses[i] = &expenses[i]
+	}
+	if err := loadAllocations(db.GetDBInstance(), pageExpenses...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "Failed to fetch expense allocations",
+		})
+		return
+	}
+	if err := loadTags(db.GetDBInstance(), pageExpenses...); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  http.StatusInternalServerError,
+			"message": "Failed to fetch expense tags",
+		})
+		return
+	}
+
 	// Calculate total pages for pagination
 	totalPages := (int(totalCount) + limit - 1) / limit
 
@@ -182,6 +302,16 @@ func GetExpense(c *gin.Context) {
 		return
 	}
 
+	// Include the split across categories and the tags, if any
+	if err := loadAllocations(db.GetDBInstance(), &expense); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense allocations", nil, nil)
+		return
+	}
+	if err := loadTags(db.GetDBInstance(), &expense); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense tags", nil, nil)
+		return
+	}
+
 	// Send the response with the expense details
 	utils.SendResponse(c, http.StatusOK, "Expense fetched successfully", expense, nil)
 }
@@ -220,8 +350,8 @@ func UpdateExpense(c *gin.Context) {
 
 	// Track fields to update and validate inputs
 	updateFields := map[string]interface{}{}
-	if updateData.Amount != 0 {
-		if updateData.Amount < 0 {
+	if !updateData.Amount.IsZero() {
+		if updateData.Amount.Sign() < 0 {
 			utils.SendResponse(c, http.StatusBadRequest, "Amount must be a positive value", nil, nil)
 			return
 		}
@@ -236,6 +366,10 @@ func UpdateExpense(c *gin.Context) {
 		updateFields["description"] = updateData.Description
 	}
 
+	if updateData.Merchant != "" {
+		updateFields["merchant"] = updateData.Merchant
+	}
+
 	if updateData.CategoryID != uuid.Nil {
 		// Check if the provided CategoryID exists
 		var category models.Category
@@ -251,29 +385,124 @@ func UpdateExpense(c *gin.Context) {
 		updateFields["category_id"] = updateData.CategoryID
 	}
 
-	if updateData.ReceiptID != nil {
-		// Check if the provided ReceiptID exists
-		var receipt models.Receipt
-		if err := db.GetDBInstance().Where("receipt_id = ?", updateData.ReceiptID).First(&receipt).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				utils.SendResponse(c, http.StatusBadRequest, "Invalid receipt ID", nil, nil)
+	if len(updateFields) == 0 && updateData.ReceiptID == nil && updateData.Allocations == nil && updateData.TagIDs == nil && updateData.Currency == "" {
+		utils.SendResponse(c, http.StatusBadRequest, "No valid fields to update", nil, nil)
+		return
+	}
+
+	// Tags must belong to the user
+	if message, err := validateTags(db.GetDBInstance(), userID, updateData.TagIDs); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating tags", nil, nil)
+		return
+	} else if message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+		return
+	}
+
+	// Amounts in the request are in the expense's currency; reprice in the home currency when they,
+	// the currency or the date of a foreign expense change
+	home, err := fx.HomeCurrency(db.GetDBInstance(), userID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while fetching user currency", nil, nil)
+		return
+	}
+	currency := expense.Currency
+	if currency == "" {
+		currency = home
+	}
+	if updateData.Currency != "" {
+		normalized, ok := fx.NormalizeCurrency(updateData.Currency)
+		if !ok {
+			utils.SendResponse(c, http.StatusBadRequest, "Currency must be an ISO-4217 code", nil, nil)
+			return
+		}
+		currency = normalized
+	}
+	paid := expense.Amount
+	if expense.OriginalAmount != nil {
+		paid = *expense.OriginalAmount
+	}
+	if !updateData.Amount.IsZero() {
+		paid = updateData.Amount
+	}
+	amount := expense.Amount
+	if !updateData.Amount.IsZero() || updateData.Currency != "" || (currency != home && !updateData.Date.IsZero()) {
+		priced := models.Expense{Date: expense.Date}
+		if !updateData.Date.IsZero() {
+			priced.Date = updateData.Date
+		}
+		if !sendPricingError(c, priceExpense(fx.NewConverter(db.GetDBInstance()), home, &priced, currency, paid)) {
+			return
+		}
+		amount = priced.Amount
+		updateFields["amount"] = priced.Amount
+		updateFields["original_amount"] = priced.OriginalAmount
+		updateFields["currency"] = priced.Currency
+	}
+
+	// Keep the split in line with the amount: a new split is checked against the new amount, and the amount or
+	// category of a split expense can only change together with its allocations, since they decide its categories
+	if len(updateData.Allocations) > 0 {
+		message, err := validateAllocations(db.GetDBInstance(), paid, updateData.Allocations)
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating allocations", nil, nil)
+			return
+		}
+		if message != "" {
+			utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+			return
+		}
+		if updateData.CategoryID == uuid.Nil {
+			updateFields["category_id"] = primaryAllocationCategory(updateData.Allocations)
+		}
+		updateData.Allocations = scaleAllocations(updateData.Allocations, amount, home)
+	} else if updateData.Allocations == nil {
+		recategorized := updateData.CategoryID != uuid.Nil && updateData.CategoryID != expense.CategoryID
+		if !amount.Equal(expense.Amount) || recategorized {
+			var allocationCount int64
+			if err := db.GetDBInstance().Model(&models.ExpenseAllocation{}).Where("expense_id = ?", expense.ExpenseID).Count(&allocationCount).Error; err != nil {
+				utils.SendResponse(c, http.StatusInternalServerError, "Database error while fetching allocations", nil, nil)
 				return
-			} else {
-				utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating receipt", nil, nil)
+			}
+			if allocationCount > 0 && !amount.Equal(expense.Amount) {
+				utils.SendResponse(c, http.StatusBadRequest, "Expense is split across categories; send allocations that sum to the new amount", nil, nil)
+				return
+			}
+			if allocationCount > 0 {
+				utils.SendResponse(c, http.StatusConflict, "Expense is split across categories; send allocations with the new category, or an empty list to remove the split",
+					gin.H{"allocations": allocationCount}, nil)
 				return
 			}
 		}
-		updateFields["receipt_id"] = updateData.ReceiptID
 	}
 
-	// Apply the updates
-	if len(updateFields) > 0 {
-		if err := db.GetDBInstance().Model(&expense).Updates(updateFields).Error; err != nil {
+	// Apply the updates and keep the receipt link consistent on both sides
+	previous := expense
+	err = db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if len(updateFields) > 0 {
+			if err := tx.Model(&expense).Updates(updateFields).Error; err != nil {
+				return err
+			}
+		}
+		if updateData.Allocations != nil {
+			if err := saveAllocations(tx, &expense, updateData.Allocations); err != nil {
+				return err
+			}
+		}
+		if updateData.TagIDs != nil {
+			if err := saveTags(tx, &expense, updateData.TagIDs); err != nil {
+				return err
+			}
+		}
+		if updateData.ReceiptID != nil {
+			return attachReceipt(tx, &expense, updateData.ReceiptID.String())
+		}
+		return nil
+	})
+	if err != nil {
+		if !sendReceiptLinkError(c, err) {
 			utils.SendResponse(c, http.StatusInternalServerError, "Failed to update expense in database", nil, nil)
-			return
 		}
-	} else {
-		utils.SendResponse(c, http.StatusBadRequest, "No valid fields to update", nil, nil)
 		return
 	}
 
@@ -282,6 +511,18 @@ func UpdateExpense(c *gin.Context) {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch updated expense", nil, nil)
 		return
 	}
+	suggest.Relearn(db.GetDBInstance(), expense.UserID, &previous, &expense)
+	CheckBudgetAlerts(expense.UserID, previous.Date, expense.Date)
+	expense.Allocations = nil
+	expense.Tags = nil
+	if err := loadAllocations(db.GetDBInstance(), &expense); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch updated expense", nil, nil)
+		return
+	}
+	if err := loadTags(db.GetDBInstance(), &expense); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch updated expense", nil, nil)
+		return
+	}
 
 	utils.SendResponse(c, http.StatusOK, "Expense updated successfully", expense, nil)
 }
@@ -311,19 +552,41 @@ func DeleteExpense(c *gin.Context) {
 		return
 	}
 
-	// Check if there’s an associated receipt and delete it if it exists
-	if expense.ReceiptID != nil {
-		if err := db.GetDBInstance().Where("receipt_id = ?", expense.ReceiptID).Delete(&models.Receipt{}).Error; err != nil {
-			utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete associated receipt", nil, nil)
-			return
+	// Delete the expense together with its associated receipt, if any
+	var storageKeys []string // Images of the deleted receipts, removed once the transaction commits
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		// Receipts linked either way, including those that point at this expense without the expense pointing back
+		condition, args := "expense_id = ?", []interface{}{expense.ExpenseID}
+		if expense.ReceiptID != nil {
+			condition, args = "id = ? OR expense_id = ?", []interface{}{expense.ReceiptID, expense.ExpenseID}
 		}
-	}
-
-	// Delete the expense
-	if err := db.GetDBInstance().Delete(&expense).Error; err != nil {
+		if err := tx.Model(&models.Receipt{}).Where(condition, args...).Where("storage_key <> ''").
+			Pluck("storage_key", &storageKeys).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(condition, args...).Delete(&models.Receipt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("expense_id = ?", expense.ExpenseID).Delete(&models.ExpenseAllocation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("expense_id = ?", expense.ExpenseID).Delete(&models.ExpenseTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&expense).Error
+	})
+	if err != nil {
 		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete expense", nil, nil)
 		return
 	}
+	for _, key := range storageKeys {
+		if err := storage.GetStore().Delete(c.Request.Context(), key); err != nil {
+			// The records are already gone; log and keep going
+			log.Printf("Failed to remove receipt image %s: %v", key, err)
+		}
+	}
+	suggest.Unlearn(db.GetDBInstance(), expense.UserID, &expense)
+	CheckBudgetAlerts(expense.UserID, expense.Date)
 
 	utils.SendResponse(c, http.StatusOK, "Expense deleted successfully", nil, nil)
 }
@@ -342,9 +605,14 @@ func ExpenseAnalysis(c *gin.Context) {
 	endDate := c.DefaultQuery("end_date", "")
 	period := c.DefaultQuery("period", "month")
 	categoryID := c.DefaultQuery("category_id", "")
+	tagIDs, tagMode, err := parseTagFilter(c)
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, err.Error(), nil, nil)
+		return
+	}
 
-	// Base query
-	query := db.GetDBInstance().Table("expenses").Where("user_id = ?", userID)
+	// Base query over spending lines, so split expenses count towards each allocated category
+	query := models.SpendingLines(db.GetDBInstance()).Where("user_id = ?", userID)
 
 	// Apply filters
 	if startDate != "" {
@@ -354,77 +622,138 @@ func ExpenseAnalysis(c *gin.Context) {
 		query = query.Where("date <= ?", endDate)
 	}
 	if categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+		query = query.Where("category_id IN (?)", models.CategorySubtree(db.GetDBInstance(), categoryID))
+	}
+	query = filterByTags(db.GetDBInstance(), query, tagIDs, tagMode)
+
+	// Load the spending lines converted into the user's home currency
+	home, err := fx.HomeCurrency(db.GetDBInstance(), userID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch user currency", nil, nil)
+		return
+	}
+	byCategory, unconverted, err := homeSpendingTotals(query, home, "category_id", "recurring")
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense data", nil, nil)
+		return
+	}
+	byDay, _, err := homeSpendingTotals(query, home, "date")
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense data", nil, nil)
+		return
 	}
 
 	// Struct for analysis results
 	var result struct {
-		Period               string                 `json:"period"`
-		TotalSpending        float64                `json:"total_spending"`
-		AverageSpending      float64                `json:"average_spending"`
-		HighestExpense       float64                `json:"highest_expense"`
-		CategoryBreakdown    []map[string]any       `json:"category_breakdown"`
-		MostFrequentCategory map[string]any         `json:"most_frequent_category"`
-		DailyAverage         float64                `json:"daily_average"`
+		Period               string           `json:"period"`
+		Currency             string           `json:"currency"` // Home currency every amount is reported in
+		TotalSpending        money.Decimal    `json:"total_spending"`
+		AverageSpending      money.Decimal    `json:"average_spending"`
+		HighestExpense       money.Decimal    `json:"highest_expense"`
+		RecurringTotal       money.Decimal    `json:"recurring_total"` // Spending generated by recurring expenses
+		CategoryBreakdown    []map[string]any `json:"category_breakdown"`
+		CategoryRollup       []map[string]any `json:"category_rollup"` // Spending per category including its subcategories
+		TagBreakdown         []map[string]any `json:"tag_breakdown"` // Spending per tag; expenses with several tags count towards each
+		MostFrequentCategory map[string]any   `json:"most_frequent_category"`
+		DailyAverage         money.Decimal    `json:"daily_average"`
+		UnconvertedExpenses  int              `json:"unconverted_expenses,omitempty"` // Expenses counted at their stored amount for lack of a rate
 	}
 	result.Period = period
+	result.Currency = home
+	result.UnconvertedExpenses = unconverted
+
+	// Averages and maxima are per expense, not per allocation: an expense falls on a single day, so each day's
+	// largest expense is a whole one. Sums of line amounts are exact; only averages and percentages are rounded.
+	categoryTotals := map[uuid.UUID]money.Decimal{}
+	categoryCounts := map[uuid.UUID]int{}
+	for _, total := range byCategory {
+		categoryTotals[total.CategoryID] = categoryTotals[total.CategoryID].Add(total.Amount)
+		categoryCounts[total.CategoryID] += total.Lines
+		result.TotalSpending = result.TotalSpending.Add(total.Amount)
+		if total.Recurring {
+			result.RecurringTotal = result.RecurringTotal.Add(total.Amount)
+		}
+	}
+	expenses := 0
+	for _, day := range byDay {
+		expenses += day.Expenses
+		if day.Largest.GreaterThan(result.HighestExpense) {
+			result.HighestExpense = day.Largest
+		}
+	}
+	places := money.MinorUnits(home)
 
 	// Basic statistics
-	var stats struct {
-		TotalSpending   float64
-		AverageSpending float64
-		HighestExpense  float64
+	if expenses > 0 {
+		result.AverageSpending = result.TotalSpending.Div(money.NewFromInt(int64(expenses)), places, money.HalfEven)
 	}
-	query.Select(`SUM(amount) AS total_spending, AVG(amount) AS average_spending, MAX(amount) AS highest_expense`).
-		Scan(&stats)
-
-	// Assign basic stats to result
-	result.TotalSpending = stats.TotalSpending
-	result.AverageSpending = stats.AverageSpending
-	result.HighestExpense = stats.HighestExpense
 
 	// Daily average spending
-	var daysWithExpenses int
-	query.Select("COUNT(DISTINCT date) AS days").Row().Scan(&daysWithExpenses)
-	if daysWithExpenses > 0 {
-		result.DailyAverage = result.TotalSpending / float64(daysWithExpenses)
+	if len(byDay) > 0 {
+		result.DailyAverage = result.TotalSpending.Div(money.NewFromInt(int64(len(byDay))), places, money.HalfEven)
 	} else {
-		result.DailyAverage = 0
-	}
-
-	// Category breakdown
-	var categoryData []struct {
-		CategoryID string  `json:"category_id"`
-		Total      float64 `json:"total"`
-		Percentage float64 `json:"percentage"`
+		result.DailyAverage = money.Zero
 	}
 
-	if stats.TotalSpending > 0 {
-		query.Select("category_id, SUM(amount) AS total, SUM(amount) * 100 / ? AS percentage", stats.TotalSpending).
-			Group("category_id").Scan(&categoryData)
-
-		for _, cat := range categoryData {
+	// Category breakdown, largest first
+	if result.TotalSpending.Sign() > 0 {
+		for catID, total := range categoryTotals {
 			result.CategoryBreakdown = append(result.CategoryBreakdown, map[string]any{
-				"category_id": cat.CategoryID,
-				"total":       cat.Total,
-				"percentage":  cat.Percentage,
+				"category_id": catID.String(),
+				"total":       total,
+				"percentage":  total.Mul(money.NewFromInt(100)).Div(result.TotalSpending, 2, money.HalfUp),
 			})
 		}
+		sort.Slice(result.CategoryBreakdown, func(i, j int) bool {
+			return result.CategoryBreakdown[i]["total"].(money.Decimal).GreaterThan(result.CategoryBreakdown[j]["total"].(money.Decimal))
+		})
 	} else {
 		result.CategoryBreakdown = nil // No spending, no breakdown
 	}
 
-	// Most frequent category
-	var mostFrequent struct {
-		CategoryID string `json:"category_id"`
-		Count      int    `json:"count"`
+	// Category rollup: subcategory spending also counts towards every ancestor
+	if result.TotalSpending.Sign() > 0 {
+		parents, err := models.FetchCategoryParents(db.GetDBInstance(), userID)
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch categories", nil, nil)
+			return
+		}
+		for catID, total := range rollUpCategoryTotals(categoryTotals, parents) {
+			entry := map[string]any{
+				"category_id": catID.String(),
+				"total":       total,
+				"own_total":   categoryTotals[catID],
+				"percentage":  total.Mul(money.NewFromInt(100)).Div(result.TotalSpending, 2, money.HalfUp),
+			}
+			if parentID, ok := parents[catID]; ok {
+				entry["parent_id"] = parentID.String()
+			}
+			result.CategoryRollup = append(result.CategoryRollup, entry)
+		}
+		sort.Slice(result.CategoryRollup, func(i, j int) bool {
+			return result.CategoryRollup[i]["total"].(money.Decimal).GreaterThan(result.CategoryRollup[j]["total"].(money.Decimal))
+		})
+	}
+
+	// Tag breakdown, largest first
+	result.TagBreakdown, err = tagBreakdown(db.GetDBInstance(), query, home, result.TotalSpending)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense tags", nil, nil)
+		return
 	}
-	query.Select("category_id, COUNT(*) AS count").Group("category_id").Order("count DESC").Limit(1).Scan(&mostFrequent)
 
-	if mostFrequent.CategoryID != "" {
+	// Most frequent category
+	var mostFrequentID uuid.UUID
+	mostFrequentCount := 0
+	for catID, count := range categoryCounts {
+		if count > mostFrequentCount || (count == mostFrequentCount && catID.String() < mostFrequentID.String()) {
+			mostFrequentID, mostFrequentCount = catID, count
+		}
+	}
+	if mostFrequentCount > 0 {
 		result.MostFrequentCategory = map[string]any{
-			"category_id": mostFrequent.CategoryID,
-			"count":       mostFrequent.Count,
+			"category_id": mostFrequentID.String(),
+			"count":       mostFrequentCount,
 		}
 	} else {
 		result.MostFrequentCategory = nil // No categories found
@@ -433,3 +762,160 @@ func ExpenseAnalysis(c *gin.Context) {
 	// Send the response
 	utils.SendResponse(c, http.StatusOK, "Expense analysis fetched successfully", result, nil)
 }
+
+// rollUpCategoryTotals adds the total of each category to the category itself and to all of its ancestors
+func rollUpCategoryTotals(totals map[uuid.UUID]money.Decimal, parents map[uuid.UUID]uuid.UUID) map[uuid.UUID]money.Decimal {
+	rolled := make(map[uuid.UUID]money.Decimal, len(totals))
+	for categoryID, total := range totals {
+		rolled[categoryID] = rolled[categoryID].Add(total)
+		for _, ancestorID := range models.CategoryAncestors(parents, categoryID) {
+			rolled[ancestorID] = rolled[ancestorID].Add(total)
+		}
+	}
+	return rolled
+}
+
+// validateAllocations checks a split before it is saved and returns a message for the client when it is invalid
+func validateAllocations(database *gorm.DB, amount money.Decimal, allocations []models.ExpenseAllocation) (string, error) {
+	seen := map[uuid.UUID]bool{}
+	categoryIDs := []uuid.UUID{}
+	sum := money.Zero
+	for _, allocation := range allocations {
+		if allocation.CategoryID == uuid.Nil {
+			return "Every allocation needs a category_id", nil
+		}
+		if allocation.Amount.Sign() <= 0 {
+			return "Allocation amounts must be positive numbers", nil
+		}
+		if seen[allocation.CategoryID] {
+			return "A category can only appear once in allocations", nil
+		}
+		seen[allocation.CategoryID] = true
+		categoryIDs = append(categoryIDs, allocation.CategoryID)
+		sum = sum.Add(allocation.Amount)
+	}
+
+	if !sum.Equal(amount) {
+		return fmt.Sprintf("Allocations must sum to the expense amount (%s), got %s", amount, sum), nil
+	}
+
+	var found int64
+	if err := database.Model(&models.Category{}).Where("id IN ?", categoryIDs).Count(&found).Error; err != nil {
+		return "", err
+	}
+	if int(found) != len(categoryIDs) {
+		return "Invalid category ID in allocations", nil
+	}
+	return "", nil
+}
+
+// primaryAllocationCategory returns the category receiving the largest share of a split
+func primaryAllocationCategory(allocations []models.ExpenseAllocation) uuid.UUID {
+	primary := allocations[0]
+	for _, allocation := range allocations[1:] {
+		if allocation.Amount.GreaterThan(primary.Amount) {
+			primary = allocation
+		}
+	}
+	return primary.CategoryID
+}
+
+// saveAllocations replaces the split of an expense; an empty slice removes it
+func saveAllocations(tx *gorm.DB, expense *models.Expense, allocations []models.ExpenseAllocation) error {
+	if err := tx.Where("expense_id = ?", expense.ExpenseID).Delete(&models.ExpenseAllocation{}).Error; err != nil {
+		return err
+	}
+	expense.Allocations = nil
+	if len(allocations) == 0 {
+		return nil
+	}
+
+	saved := make([]models.ExpenseAllocation, len(allocations))
+	for i, allocation := range allocations {
+		saved[i] = models.ExpenseAllocation{
+			ExpenseID:   expense.ExpenseID,
+			CategoryID:  allocation.CategoryID,
+			Amount:      allocation.Amount,
+			Description: allocation.Description,
+		}
+	}
+	if err := tx.Create(&saved).Error; err != nil {
+		return err
+	}
+	expense.Allocations = saved
+	return nil
+}
+
+// loadAllocations fills in the split of each expense with a single query
+func loadAllocations(database *gorm.DB, expenses ...*models.Expense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*models.Expense, len(expenses))
+	ids := make([]uuid.UUID, 0, len(expenses))
+	for _, expense := range expenses {
+		byID[expense.ExpenseID] = expense
+		ids = append(ids, expense.ExpenseID)
+	}
+
+	var allocations []models.ExpenseAllocation
+	if err := database.Where("expense_id IN ?", ids).Order("amount DESC").Find(&allocations).Error; err != nil {
+		return err
+	}
+	for _, allocation := range allocations {
+		expense := byID[allocation.ExpenseID]
+		expense.Allocations = append(expense.Allocations, allocation)
+	}
+	return nil
+}
+
+// priceExpense records what was paid in currency and sets the expense amount in the home currency,
+// converted at the rate of the expense date
+func priceExpense(converter *fx.Converter, home string, expense *models.Expense, currency string, paid money.Decimal) error {
+	paid = money.NewMoney(paid, currency).Round(money.HalfEven).Amount
+	amount, err := converter.Convert(paid, currency, home, expense.Date)
+	if err != nil {
+		return err
+	}
+	expense.Amount = amount
+	expense.OriginalAmount = &paid
+	expense.Currency = currency
+	return nil
+}
+
+// sendPricingError reports a failed conversion and returns false, or returns true when there was no error
+func sendPricingError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, fx.ErrNoRate) {
+		utils.SendResponse(c, http.StatusBadRequest, "Cannot convert the amount into your home currency", nil, err.Error())
+	} else {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while converting the amount", nil, nil)
+	}
+	return false
+}
+
+// scaleAllocations converts a split given in the paid currency so it sums to the home-currency total, rounded to
+// the home currency's minor units; the rounding difference goes to the largest allocation
+func scaleAllocations(allocations []models.ExpenseAllocation, total money.Decimal, home string) []models.ExpenseAllocation {
+	if len(allocations) == 0 {
+		return allocations
+	}
+	weights := make([]money.Decimal, len(allocations))
+	sum := money.Zero
+	for i, allocation := range allocations {
+		weights[i] = allocation.Amount
+		sum = sum.Add(allocation.Amount)
+	}
+	if sum.Equal(total) {
+		return allocations
+	}
+
+	scaled := make([]models.ExpenseAllocation, len(allocations))
+	for i, share := range money.Allocate(total, weights, money.MinorUnits(home), money.HalfEven) {
+		scaled[i] = allocations[i]
+		scaled[i].Amount = share
+	}
+	return scaled
+}
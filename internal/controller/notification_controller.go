@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Default and largest page size of the inbox
+const (
+	defaultNotificationsLimit = 20
+	maxNotificationsLimit     = 100
+)
+
+// ListNotifications fetches the user's in-app inbox, newest first, with the number of unread notifications
+func ListNotifications(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	page := utils.ParseQueryInt(c, "page", 1)
+	limit := utils.ParseQueryInt(c, "limit", defaultNotificationsLimit)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = defaultNotificationsLimit
+	} else if limit > maxNotificationsLimit {
+		limit = maxNotificationsLimit
+	}
+
+	query := db.GetDBInstance().Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&notifications).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch notifications", nil, nil)
+		return
+	}
+	var unread int64
+	if err := db.GetDBInstance().Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch notifications", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Notifications fetched successfully", gin.H{
+		"notifications": notifications,
+		"unread":        unread,
+		"page":          page,
+		"limit":         limit,
+	}, nil)
+}
+
+// MarkNotificationRead marks one notification of the user as read
+func MarkNotificationRead(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var notification models.Notification
+	if err := db.GetDBInstance().Where("user_id = ? AND id = ?", userID, c.Param("notificationId")).First(&notification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Notification not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch notification", nil, nil)
+		}
+		return
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := db.GetDBInstance().Model(&notification).Update("read_at", now).Error; err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to update notification", nil, nil)
+			return
+		}
+		notification.ReadAt = &now
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Notification marked as read", notification, nil)
+}
+
+// MarkAllNotificationsRead marks every unread notification of the user as read
+func MarkAllNotificationsRead(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	result := db.GetDBInstance().Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to update notifications", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Notifications marked as read", gin.H{"updated": result.RowsAffected}, nil)
+}
+
+// ListBudgetAlerts lists the alerts a budget has fired, most recent period first
+func ListBudgetAlerts(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var budget models.Budget
+	if err := db.GetDBInstance().Where("user_id = ? AND budget_id = ?", userID, c.Param("budgetId")).First(&budget).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Budget not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget", nil, nil)
+		}
+		return
+	}
+
+	var events []models.BudgetAlertEvent
+	if err := db.GetDBInstance().Where("budget_id = ?", budget.BudgetID).Order("period_start DESC, threshold ASC").Find(&events).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch budget alerts", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Budget alerts fetched successfully", gin.H{
+		"budget_id":        budget.BudgetID,
+		"alert_thresholds": budget.AlertThresholds,
+		"alerts":           events,
+	}, nil)
+}
@@ -0,0 +1,599 @@
+package controller
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/fx"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"expense-mgmt/internal/ocr"
+	"expense-mgmt/internal/storage"
+	"expense-mgmt/internal/suggest"
+	"expense-mgmt/utils"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errReceiptNotFound      = errors.New("receipt not found")
+	errReceiptAlreadyLinked = errors.New("receipt is already attached to another expense")
+)
+
+// allowedReceiptTypes maps the accepted (sniffed) MIME types to the extension used in storage
+var allowedReceiptTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// UploadReceipt stores a receipt image sent as multipart form data and records its metadata
+func UploadReceipt(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	// Cap the request body so oversized uploads are rejected before being buffered
+	maxBytes := storage.MaxUploadBytes()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+(1<<20))
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.SendResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Receipt image must not exceed %d bytes", maxBytes), nil, nil)
+			return
+		}
+		utils.SendResponse(c, http.StatusBadRequest, "A receipt image must be sent in the 'file' form field", nil, err.Error())
+		return
+	}
+
+	if fileHeader.Size > maxBytes {
+		utils.SendResponse(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Receipt image must not exceed %d bytes", maxBytes), nil, nil)
+		return
+	}
+	if fileHeader.Size == 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Receipt image is empty", nil, nil)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Failed to read uploaded file", nil, err.Error())
+		return
+	}
+	defer file.Close()
+
+	// Detect the content type from the file itself instead of trusting the client header
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		utils.SendResponse(c, http.StatusBadRequest, "Failed to read uploaded file", nil, err.Error())
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+	extension, allowed := allowedReceiptTypes[contentType]
+	if !allowed {
+		utils.SendResponse(c, http.StatusUnsupportedMediaType, "Unsupported receipt file type: "+contentType, nil, gin.H{"allowed_types": []string{"image/jpeg", "image/png", "image/webp", "application/pdf"}})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to read uploaded file", nil, nil)
+		return
+	}
+
+	// Optionally link the receipt to one of the user's expenses
+	var expense *models.Expense
+	if expenseID := c.PostForm("expense_id"); expenseID != "" {
+		if _, err := uuid.Parse(expenseID); err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid expense ID format", nil, nil)
+			return
+		}
+		expense = &models.Expense{}
+		if err := db.GetDBInstance().Where("user_id = ? AND expense_id = ?", userID, expenseID).First(expense).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.SendResponse(c, http.StatusNotFound, "Expense not found", nil, nil)
+			} else {
+				utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch expense", nil, nil)
+			}
+			return
+		}
+		if expense.ReceiptID != nil {
+			utils.SendResponse(c, http.StatusConflict, "Expense already has a receipt attached", nil, nil)
+			return
+		}
+	}
+
+	// Store the image in the blob store
+	receiptID := uuid.New()
+	storageKey := fmt.Sprintf("%s/%s%s", userID, receiptID, extension)
+	written, err := storage.GetStore().Put(c.Request.Context(), storageKey, file)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to store receipt image", nil, nil)
+		return
+	}
+
+	receipt := models.Receipt{
+		ID:          receiptID.String(),
+		UserID:      userID.(uuid.UUID),
+		ImageURL:    "/api/v1/receipts/" + receiptID.String() + "/image",
+		StorageKey:  storageKey,
+		FileName:    filepath.Base(fileHeader.Filename),
+		ContentType: contentType,
+		FileSize:    written,
+		Status:      models.ReceiptStatusPending,
+		ScannedDate: time.Now(),
+	}
+	if expense != nil {
+		linkedExpenseID := expense.ExpenseID.String()
+		receipt.ExpenseID = &linkedExpenseID
+	}
+
+	// Save the receipt and the expense back-link together
+	err = db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&receipt).Error; err != nil {
+			return err
+		}
+		if expense != nil {
+			return tx.Model(expense).Update("receipt_id", receiptID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		// Do not leave an orphaned image behind
+		if delErr := storage.GetStore().Delete(c.Request.Context(), storageKey); delErr != nil {
+			log.Printf("Failed to remove receipt image %s: %v", storageKey, delErr)
+		}
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to save receipt", nil, nil)
+		return
+	}
+
+	// Let the OCR worker pick the receipt up right away; clients poll GET /receipts/:id for the result
+	ocr.Notify()
+
+	utils.SendResponse(c, http.StatusCreated, "Receipt uploaded successfully", receipt, nil)
+}
+
+// ListReceipts fetches the receipts uploaded by the user with pagination
+func ListReceipts(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	page := utils.ParseQueryInt(c, "page", 1)
+	limit := utils.ParseQueryInt(c, "limit", 10)
+	pagination := utils.CalculatePagination(0, page, limit)
+
+	// Optional filters
+	expenseID := c.Query("expense_id")
+	unlinked := c.Query("unlinked")
+
+	query := db.GetDBInstance().Model(&models.Receipt{}).Where("user_id = ?", userID)
+	if expenseID != "" {
+		if _, err := uuid.Parse(expenseID); err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid expense ID format", nil, nil)
+			return
+		}
+		query = query.Where("expense_id = ?", expenseID)
+	}
+	if unlinked == "true" {
+		query = query.Where("expense_id IS NULL")
+	}
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch receipts", nil, nil)
+		return
+	}
+
+	var receipts []models.Receipt
+	if err := query.Order("scanned_date DESC").
+		Offset((pagination.Page - 1) * pagination.PerPage).
+		Limit(pagination.PerPage).
+		Find(&receipts).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch receipts", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Receipts fetched successfully", gin.H{
+		"receipts":   receipts,
+		"pagination": utils.CalculatePagination(int(totalCount), pagination.Page, pagination.PerPage),
+	}, nil)
+}
+
+// GetReceipt fetches the metadata of a single receipt
+func GetReceipt(c *gin.Context) {
+	receipt, ok := fetchUserReceipt(c)
+	if !ok {
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Receipt fetched successfully", receipt, nil)
+}
+
+// GetReceiptDraft returns the parsed receipt fields and an expense draft the client can send to CreateExpense
+func GetReceiptDraft(c *gin.Context) {
+	receipt, ok := fetchUserReceipt(c)
+	if !ok {
+		return
+	}
+
+	if receipt.Status != models.ReceiptStatusDone {
+		utils.SendResponse(c, http.StatusConflict, "Receipt has not been processed yet", gin.H{"status": receipt.Status}, nil)
+		return
+	}
+
+	parse, err := ocr.LoadParse(db.GetDBInstance(), receipt.ID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch parsed receipt", nil, nil)
+		return
+	}
+
+	// Receipts processed before parsing existed only have raw text; parse them now
+	if parse == nil {
+		if err := ocr.SaveParse(db.GetDBInstance(), receipt.ID, ocr.ParseReceipt(receipt.OCRData)); err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to parse receipt", nil, nil)
+			return
+		}
+		if parse, err = ocr.LoadParse(db.GetDBInstance(), receipt.ID); err != nil || parse == nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch parsed receipt", nil, nil)
+			return
+		}
+	}
+
+	// Prefill the CreateExpense payload; fields that could not be parsed are left out
+	draft := gin.H{
+		"receipt_id":  receipt.ID,
+		"description": parse.Merchant,
+	}
+	if parse.Total != nil {
+		draft["amount"] = *parse.Total
+	}
+	if parse.TransactionDate != nil {
+		draft["date"] = *parse.TransactionDate
+	} else {
+		draft["date"] = receipt.ScannedDate
+	}
+	if parse.Currency != "" {
+		draft["currency"] = parse.Currency
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Receipt draft fetched successfully", gin.H{
+		"receipt_id":    receipt.ID,
+		"status":        receipt.Status,
+		"parsed":        parse,
+		"expense_draft": draft,
+	}, nil)
+}
+
+// CreateExpenseFromReceipt turns a processed receipt into an expense, prefilled from the parsed fields.
+// Any field sent in the body overrides the parsed value.
+func CreateExpenseFromReceipt(c *gin.Context) {
+	receipt, ok := fetchUserReceipt(c)
+	if !ok {
+		return
+	}
+
+	if receipt.ExpenseID != nil {
+		utils.SendResponse(c, http.StatusConflict, "Receipt is already attached to an expense", gin.H{"expense_id": receipt.ExpenseID}, nil)
+		return
+	}
+	if receipt.Status != models.ReceiptStatusDone {
+		utils.SendResponse(c, http.StatusConflict, "Receipt has not been processed yet", gin.H{"status": receipt.Status}, nil)
+		return
+	}
+
+	// Optional overrides; an empty body keeps every parsed value
+	var input struct {
+		CategoryID  *uuid.UUID                 `json:"category_id"`
+		Amount      *money.Decimal             `json:"amount"`
+		Date        *time.Time                 `json:"date"`
+		Description *string                    `json:"description"`
+		Currency    *string                    `json:"currency"`    // Overrides the parsed currency
+		Allocations []models.ExpenseAllocation `json:"allocations"` // Optional split across categories
+		TagIDs      []uuid.UUID                `json:"tag_ids"`     // Optional tags
+	}
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid input: Check JSON format and fields", nil, err.Error())
+		return
+	}
+
+	parse, err := ocr.LoadParse(db.GetDBInstance(), receipt.ID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch parsed receipt", nil, nil)
+		return
+	}
+
+	// Start from the parsed values
+	expense := models.Expense{
+		UserID: receipt.UserID,
+		Date:   receipt.ScannedDate,
+	}
+	if parse != nil {
+		expense.Currency = parse.Currency
+		expense.Description = parse.Merchant
+		expense.Merchant = parse.Merchant
+		if parse.Total != nil {
+			expense.Amount = *parse.Total
+		}
+		if parse.TransactionDate != nil {
+			expense.Date = *parse.TransactionDate
+		}
+	}
+
+	// Apply the client overrides
+	if input.CategoryID != nil {
+		expense.CategoryID = *input.CategoryID
+	}
+	if input.Amount != nil {
+		expense.Amount = *input.Amount
+	}
+	if input.Date != nil {
+		expense.Date = *input.Date
+	}
+	if input.Description != nil {
+		expense.Description = *input.Description
+	}
+	if input.Currency != nil {
+		expense.Currency = *input.Currency
+	}
+
+	// The amount is in the receipt's currency, the user's home currency by default
+	home, err := fx.HomeCurrency(db.GetDBInstance(), receipt.UserID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while fetching user currency", nil, nil)
+		return
+	}
+	currency := home
+	if expense.Currency != "" {
+		normalized, ok := fx.NormalizeCurrency(expense.Currency)
+		if !ok {
+			utils.SendResponse(c, http.StatusBadRequest, "Currency must be an ISO-4217 code", nil, nil)
+			return
+		}
+		currency = normalized
+	}
+
+	// Validate the resulting expense like CreateExpense does
+	if len(input.Allocations) > 0 && expense.CategoryID == uuid.Nil {
+		expense.CategoryID = primaryAllocationCategory(input.Allocations)
+	}
+	if expense.CategoryID == uuid.Nil && !sendAutoCategorizeError(c, autoCategorize(db.GetDBInstance(), receipt.UserID, &expense, currency)) {
+		return
+	}
+	if expense.Amount.Sign() <= 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Amount could not be read from the receipt; provide a positive amount", nil, nil)
+		return
+	}
+	if expense.Date.After(time.Now()) {
+		utils.SendResponse(c, http.StatusBadRequest, "Date cannot be in the future", nil, nil)
+		return
+	}
+	var category models.Category
+	if err := db.GetDBInstance().First(&category, "id = ?", expense.CategoryID).Error; err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid category ID", nil, err.Error())
+		return
+	}
+	if len(input.Allocations) > 0 {
+		message, err := validateAllocations(db.GetDBInstance(), expense.Amount, input.Allocations)
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating allocations", nil, nil)
+			return
+		}
+		if message != "" {
+			utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+			return
+		}
+	}
+	if message, err := validateTags(db.GetDBInstance(), receipt.UserID, input.TagIDs); err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while validating tags", nil, nil)
+		return
+	} else if message != "" {
+		utils.SendResponse(c, http.StatusBadRequest, message, nil, nil)
+		return
+	}
+
+	// Convert from the receipt's currency into the home currency
+	if !sendPricingError(c, priceExpense(fx.NewConverter(db.GetDBInstance()), home, &expense, currency, expense.Amount)) {
+		return
+	}
+	input.Allocations = scaleAllocations(input.Allocations, expense.Amount, home)
+
+	// Create the expense with its split and link both sides in one transaction
+	err = db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&expense).Error; err != nil {
+			return err
+		}
+		if err := saveAllocations(tx, &expense, input.Allocations); err != nil {
+			return err
+		}
+		if err := saveTags(tx, &expense, input.TagIDs); err != nil {
+			return err
+		}
+		return attachReceipt(tx, &expense, receipt.ID)
+	})
+	if err != nil {
+		if !sendReceiptLinkError(c, err) {
+			utils.SendResponse(c, http.StatusInternalServerError, "Error saving expense", nil, err.Error())
+		}
+		return
+	}
+
+	suggest.Learn(db.GetDBInstance(), expense.UserID, &expense)
+	CheckBudgetAlerts(expense.UserID, expense.Date)
+
+	utils.SendResponse(c, http.StatusCreated, "Expense created from receipt successfully", expense, nil)
+}
+
+// ReprocessReceipt queues a receipt for another OCR run, e.g. after it failed
+func ReprocessReceipt(c *gin.Context) {
+	receipt, ok := fetchUserReceipt(c)
+	if !ok {
+		return
+	}
+
+	if receipt.Status == models.ReceiptStatusPending || receipt.Status == models.ReceiptStatusProcessing {
+		utils.SendResponse(c, http.StatusConflict, "Receipt is already queued for processing", nil, nil)
+		return
+	}
+
+	if err := db.GetDBInstance().Model(receipt).Updates(map[string]interface{}{
+		"status":          models.ReceiptStatusPending,
+		"attempts":        0,
+		"last_error":      "",
+		"next_attempt_at": nil,
+	}).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to queue receipt", nil, nil)
+		return
+	}
+
+	ocr.Notify()
+
+	utils.SendResponse(c, http.StatusAccepted, "Receipt queued for processing", receipt, nil)
+}
+
+// DownloadReceipt streams the stored receipt image back to the client
+func DownloadReceipt(c *gin.Context) {
+	receipt, ok := fetchUserReceipt(c)
+	if !ok {
+		return
+	}
+
+	reader, err := storage.GetStore().Get(c.Request.Context(), receipt.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Receipt image not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to read receipt image", nil, nil)
+		}
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, receipt.FileSize, receipt.ContentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf("inline; filename=%q", receipt.FileName),
+	})
+}
+
+// DeleteReceipt removes a receipt, unlinks it from its expense and deletes the stored image
+func DeleteReceipt(c *gin.Context) {
+	receipt, ok := fetchUserReceipt(c)
+	if !ok {
+		return
+	}
+
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		// Clear the back-link on any expense pointing at this receipt
+		if err := tx.Model(&models.Expense{}).
+			Where("user_id = ? AND receipt_id = ?", receipt.UserID, receipt.ID).
+			Update("receipt_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(receipt).Error
+	})
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete receipt", nil, nil)
+		return
+	}
+
+	if receipt.StorageKey != "" {
+		if err := storage.GetStore().Delete(c.Request.Context(), receipt.StorageKey); err != nil {
+			// The record is already gone; log and keep going
+			log.Printf("Failed to remove receipt image %s: %v", receipt.StorageKey, err)
+		}
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Receipt deleted successfully", nil, nil)
+}
+
+// fetchUserReceipt loads the receipt from the URL for the authenticated user, sending the error response itself
+func fetchUserReceipt(c *gin.Context) (*models.Receipt, bool) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return nil, false
+	}
+
+	// Validate the receiptId format
+	receiptID := c.Param("receiptId")
+	if _, err := uuid.Parse(receiptID); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid receipt ID format", nil, nil)
+		return nil, false
+	}
+
+	var receipt models.Receipt
+	if err := db.GetDBInstance().Where("user_id = ? AND id = ?", userID, receiptID).First(&receipt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Receipt not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch receipt", nil, nil)
+		}
+		return nil, false
+	}
+
+	return &receipt, true
+}
+
+// attachReceipt links a receipt and an expense in both directions, detaching any receipt the expense pointed to before.
+// It must run inside a transaction.
+func attachReceipt(tx *gorm.DB, expense *models.Expense, receiptID string) error {
+	// Lock the receipt so two requests cannot attach it to different expenses
+	var receipt models.Receipt
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND user_id = ?", receiptID, expense.UserID).
+		First(&receipt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errReceiptNotFound
+		}
+		return err
+	}
+	if receipt.ExpenseID != nil && *receipt.ExpenseID != expense.ExpenseID.String() {
+		return errReceiptAlreadyLinked
+	}
+
+	// Detach the receipt previously attached to this expense
+	if err := tx.Model(&models.Receipt{}).
+		Where("expense_id = ? AND id <> ?", expense.ExpenseID, receiptID).
+		Update("expense_id", nil).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(&receipt).Update("expense_id", expense.ExpenseID).Error; err != nil {
+		return err
+	}
+	parsedReceiptID, err := uuid.Parse(receiptID)
+	if err != nil {
+		return errReceiptNotFound
+	}
+	expense.ReceiptID = &parsedReceiptID
+	return tx.Model(expense).Update("receipt_id", parsedReceiptID).Error
+}
+
+// sendReceiptLinkError sends the response for attachReceipt errors and reports whether err was one of them
+func sendReceiptLinkError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, errReceiptNotFound):
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid receipt ID", nil, nil)
+	case errors.Is(err, errReceiptAlreadyLinked):
+		utils.SendResponse(c, http.StatusConflict, "Receipt is already attached to another expense", nil, nil)
+	default:
+		return false
+	}
+	return true
+}
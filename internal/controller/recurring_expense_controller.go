@@ -0,0 +1,526 @@
+package controller
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/fx"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"expense-mgmt/internal/recurrence"
+	"expense-mgmt/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errResponseSent aborts a transaction whose error response was already written
+var errResponseSent = errors.New("response already sent")
+
+// upcomingOccurrencesPreview is the number of future dates returned with a single template
+const upcomingOccurrencesPreview = 5
+
+// CreateRecurringExpense creates a recurring expense template and materializes the occurrences already due
+func CreateRecurringExpense(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	// Intermediary struct to capture incoming JSON
+	type RecurringExpenseInput struct {
+		CategoryID  uuid.UUID     `json:"category_id" binding:"required"`
+		Amount      money.Decimal `json:"amount"`
+		Description string        `json:"description"`
+		Rule        string        `json:"rule" binding:"required"`       // e.g. FREQ=MONTHLY;BYMONTHDAY=1
+		StartDate   string        `json:"start_date" binding:"required"` // YYYY-MM-DD
+	}
+
+	var input RecurringExpenseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	if input.Amount.Sign() <= 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Amount must be greater than zero", nil, nil)
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid start_date format. Use YYYY-MM-DD", nil, nil)
+		return
+	}
+
+	rule, err := recurrence.Parse(input.Rule)
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid rule: "+err.Error(), nil, nil)
+		return
+	}
+	if _, ok := rule.First(startDate); !ok {
+		utils.SendResponse(c, http.StatusBadRequest, "Rule has no occurrences on or after start_date", nil, nil)
+		return
+	}
+
+	// Validate CategoryID by checking if it exists in the database
+	var category models.Category
+	if err := db.GetDBInstance().First(&category, "id = ?", input.CategoryID).Error; err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid category ID", nil, nil)
+		return
+	}
+
+	templateID := uuid.New()
+	template := models.RecurringExpense{
+		ID:          templateID,
+		UserID:      userID.(uuid.UUID),
+		CategoryID:  input.CategoryID,
+		Amount:      input.Amount,
+		Description: input.Description,
+		Rule:        rule.String(),
+		StartDate:   startDate,
+		SeriesID:    templateID,
+		Active:      true,
+	}
+
+	// Save the template and catch up on occurrences when it starts in the past
+	var created []time.Time
+	err = db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+		if err := recurrence.Refresh(tx, &template, rule); err != nil {
+			return err
+		}
+		var err error
+		created, err = recurrence.Materialize(tx, &template, time.Now())
+		return err
+	})
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to create recurring expense", nil, nil)
+		return
+	}
+	if len(created) > 0 {
+		CheckBudgetAlerts(template.UserID, created...)
+	}
+
+	utils.SendResponse(c, http.StatusCreated, "Recurring expense created successfully", template, nil)
+}
+
+// ListRecurringExpenses fetches the user's recurring expense templates
+func ListRecurringExpenses(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	query := db.GetDBInstance().Where("user_id = ?", userID)
+
+	// Optional filters
+	if active := c.Query("active"); active != "" {
+		query = query.Where("active = ?", active == "true")
+	}
+	if seriesID := c.Query("series_id"); seriesID != "" {
+		if _, err := uuid.Parse(seriesID); err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid series ID format", nil, nil)
+			return
+		}
+		query = query.Where("series_id = ?", seriesID)
+	}
+
+	var templates []models.RecurringExpense
+	if err := query.Order("start_date ASC").Find(&templates).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch recurring expenses", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Recurring expenses fetched successfully", templates, nil)
+}
+
+// GetRecurringExpense fetches a template together with its next few occurrences
+func GetRecurringExpense(c *gin.Context) {
+	template, ok := fetchUserRecurringExpense(c, db.GetDBInstance())
+	if !ok {
+		return
+	}
+
+	upcoming := []time.Time{}
+	if rule, err := recurrence.Parse(template.Rule); err == nil && template.Active && template.NextOccurrence != nil {
+		rule.Each(template.StartDate, func(_ int, occurrence time.Time) bool {
+			if !occurrence.Before(*template.NextOccurrence) {
+				upcoming = append(upcoming, occurrence)
+			}
+			return len(upcoming) < upcomingOccurrencesPreview
+		})
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Recurring expense fetched successfully", gin.H{
+		"recurring_expense":    template,
+		"upcoming_occurrences": upcoming,
+	}, nil)
+}
+
+// UpdateRecurringExpense edits a template. With scope "all" (default) the template and every expense it generated are
+// updated; with scope "future" the series is split at effective_date so earlier occurrences keep their old values.
+func UpdateRecurringExpense(c *gin.Context) {
+	// Bind the JSON request data
+	var updateData struct {
+		CategoryID    *uuid.UUID     `json:"category_id"`
+		Amount        *money.Decimal `json:"amount"`
+		Description   *string        `json:"description"`
+		Rule          *string        `json:"rule"`
+		Scope         string         `json:"scope"`          // "all" or "future"
+		EffectiveDate *string        `json:"effective_date"` // First date affected by a "future" edit (default: today)
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+
+	if updateData.Scope == "" {
+		updateData.Scope = "all"
+	}
+	if updateData.Scope != "all" && updateData.Scope != "future" {
+		utils.SendResponse(c, http.StatusBadRequest, "scope must be 'all' or 'future'", nil, nil)
+		return
+	}
+
+	// Validate each provided field
+	if updateData.Amount != nil && updateData.Amount.Sign() <= 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "Amount must be greater than zero", nil, nil)
+		return
+	}
+	var newRule *recurrence.Rule
+	if updateData.Rule != nil {
+		rule, err := recurrence.Parse(*updateData.Rule)
+		if err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid rule: "+err.Error(), nil, nil)
+			return
+		}
+		newRule = &rule
+	}
+	if updateData.CategoryID != nil {
+		var category models.Category
+		if err := db.GetDBInstance().First(&category, "id = ?", *updateData.CategoryID).Error; err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid category ID", nil, nil)
+			return
+		}
+	}
+	effectiveDate := time.Now().UTC().Truncate(24 * time.Hour)
+	if updateData.EffectiveDate != nil {
+		parsed, err := time.Parse("2006-01-02", *updateData.EffectiveDate)
+		if err != nil {
+			utils.SendResponse(c, http.StatusBadRequest, "Invalid effective_date format. Use YYYY-MM-DD", nil, nil)
+			return
+		}
+		effectiveDate = parsed
+	}
+
+	// Changes copied onto the template and onto the expenses it generated
+	expenseFields := map[string]interface{}{}
+	if updateData.CategoryID != nil {
+		expenseFields["category_id"] = *updateData.CategoryID
+	}
+	if updateData.Amount != nil {
+		expenseFields["amount"] = *updateData.Amount
+	}
+	if updateData.Description != nil {
+		expenseFields["description"] = *updateData.Description
+	}
+	if len(expenseFields) == 0 && newRule == nil {
+		utils.SendResponse(c, http.StatusBadRequest, "No valid fields to update", nil, nil)
+		return
+	}
+
+	var result *models.RecurringExpense
+	var changed []time.Time // Dates of the expenses created or changed, whose budget periods are checked for alerts
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		template, ok := fetchUserRecurringExpense(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+		if !ok {
+			return errResponseSent
+		}
+
+		currentRule, err := recurrence.Parse(template.Rule)
+		if err != nil {
+			return err
+		}
+
+		// A "future" edit that starts before any occurrence is the same as editing the whole series
+		if updateData.Scope == "future" {
+			if effectiveDate.Before(template.StartDate) {
+				utils.SendResponse(c, http.StatusBadRequest, "effective_date cannot be before the template start_date", nil, nil)
+				return errResponseSent
+			}
+			if currentRule.CountBefore(template.StartDate, effectiveDate) > 0 {
+				result, changed, err = splitRecurringExpense(c, tx, template, currentRule, newRule, expenseFields, effectiveDate)
+				return err
+			}
+		}
+
+		// Scope "all": update the template in place and every expense it generated
+		rule := currentRule
+		if newRule != nil {
+			rule = *newRule
+			template.Rule = rule.String()
+		}
+		applyRecurringFields(template, updateData.CategoryID, updateData.Amount, updateData.Description)
+		if err := tx.Save(template).Error; err != nil {
+			return err
+		}
+		if len(expenseFields) > 0 {
+			if changed, err = spendingChangeDates(tx, expenseFields, "recurring_expense_id = ?", template.ID); err != nil {
+				return err
+			}
+			if err := rescaleRecurringSplits(c, tx, expenseFields, "recurring_expense_id = ?", template.ID); err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Expense{}).Where("recurring_expense_id = ?", template.ID).Updates(expenseFields).Error; err != nil {
+				return err
+			}
+		}
+		if err := recurrence.Refresh(tx, template, rule); err != nil {
+			return err
+		}
+		created, err := recurrence.Materialize(tx, template, time.Now())
+		if err != nil {
+			return err
+		}
+		changed = append(changed, created...)
+		result = template
+		return nil
+	})
+	if errors.Is(err, errResponseSent) {
+		return
+	}
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to update recurring expense", nil, nil)
+		return
+	}
+	if len(changed) > 0 {
+		CheckBudgetAlerts(result.UserID, changed...)
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Recurring expense updated successfully", result, nil)
+}
+
+// splitRecurringExpense ends the template the day before effectiveDate and continues the series with a new template
+// carrying the changes. Expenses already generated on or after effectiveDate move to the new template. It also returns
+// the dates of the expenses whose amount or category changed or that were created.
+func splitRecurringExpense(c *gin.Context, tx *gorm.DB, template *models.RecurringExpense, currentRule recurrence.Rule,
+	newRule *recurrence.Rule, expenseFields map[string]interface{}, effectiveDate time.Time) (*models.RecurringExpense, []time.Time, error) {
+	occurrencesBefore := currentRule.CountBefore(template.StartDate, effectiveDate)
+
+	// Work out the schedule of the continuation
+	continuationStart := effectiveDate
+	continuationRule := currentRule
+	if newRule != nil {
+		continuationRule = *newRule
+	} else {
+		// Keep the original cadence: start on the next original occurrence and pin the day of month
+		next, ok := currentRule.Next(template.StartDate, effectiveDate.AddDate(0, 0, -1))
+		if !ok {
+			utils.SendResponse(c, http.StatusBadRequest, "Schedule has no occurrences on or after effective_date", nil, nil)
+			return nil, nil, errResponseSent
+		}
+		continuationStart = next
+		if continuationRule.Frequency == recurrence.Monthly && continuationRule.ByMonthDay == 0 {
+			continuationRule.ByMonthDay = template.StartDate.Day()
+		}
+		if continuationRule.Count > 0 {
+			continuationRule.Count -= occurrencesBefore
+		}
+	}
+
+	// End the current template right before the split
+	endedRule := currentRule
+	if endedRule.Count > 0 {
+		endedRule.Count = occurrencesBefore
+	} else {
+		until := effectiveDate.AddDate(0, 0, -1)
+		endedRule.Until = &until
+	}
+	template.Rule = endedRule.String()
+	if err := tx.Model(template).Update("rule", template.Rule).Error; err != nil {
+		return nil, nil, err
+	}
+
+	continuation := *template
+	continuation.ID = uuid.New()
+	continuation.Rule = continuationRule.String()
+	continuation.StartDate = continuationStart
+	continuation.OccurrenceCount = 0
+	continuation.NextOccurrence = nil
+	continuation.CreatedAt = time.Time{}
+	continuation.UpdatedAt = time.Time{}
+	if v, ok := expenseFields["category_id"]; ok {
+		continuation.CategoryID = v.(uuid.UUID)
+	}
+	if v, ok := expenseFields["amount"]; ok {
+		continuation.Amount = v.(money.Decimal)
+	}
+	if v, ok := expenseFields["description"]; ok {
+		continuation.Description = v.(string)
+	}
+	if err := tx.Create(&continuation).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// Move the already generated "future" occurrences to the continuation
+	moved := map[string]interface{}{"recurring_expense_id": continuation.ID}
+	for field, value := range expenseFields {
+		moved[field] = value
+	}
+	changed, err := spendingChangeDates(tx, expenseFields, "recurring_expense_id = ? AND date >= ?", template.ID, effectiveDate)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := rescaleRecurringSplits(c, tx, expenseFields, "recurring_expense_id = ? AND date >= ?", template.ID, effectiveDate); err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Model(&models.Expense{}).
+		Where("recurring_expense_id = ? AND date >= ?", template.ID, effectiveDate).
+		Updates(moved).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if err := recurrence.Refresh(tx, template, endedRule); err != nil {
+		return nil, nil, err
+	}
+	if err := recurrence.Refresh(tx, &continuation, continuationRule); err != nil {
+		return nil, nil, err
+	}
+	created, err := recurrence.Materialize(tx, &continuation, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	return &continuation, append(changed, created...), nil
+}
+
+// spendingChangeDates returns the distinct dates of the matching expenses when the edit changes their amount or
+// category, i.e. the spending budgets see
+func spendingChangeDates(tx *gorm.DB, expenseFields map[string]interface{}, condition string, args ...interface{}) ([]time.Time, error) {
+	_, amount := expenseFields["amount"]
+	_, category := expenseFields["category_id"]
+	if !amount && !category {
+		return nil, nil
+	}
+	var dates []time.Time
+	err := tx.Model(&models.Expense{}).Where(condition, args...).Distinct("date").Pluck("date", &dates).Error
+	return dates, err
+}
+
+// rescaleRecurringSplits keeps the category splits of the matching expenses consistent with an edit of their series:
+// a new amount scales each split in proportion, and a new category is refused with 409 while any of them is split,
+// since the split decides their categories. It sends the error response itself.
+func rescaleRecurringSplits(c *gin.Context, tx *gorm.DB, expenseFields map[string]interface{}, condition string, args ...interface{}) error {
+	splitIDs := tx.Model(&models.Expense{}).Select("expense_id").Where(condition, args...).
+		Where("expense_id IN (?)", tx.Model(&models.ExpenseAllocation{}).Select("expense_id"))
+
+	if _, ok := expenseFields["category_id"]; ok {
+		var split int64
+		if err := tx.Table("(?) AS split", splitIDs).Count(&split).Error; err != nil {
+			return err
+		}
+		if split > 0 {
+			utils.SendResponse(c, http.StatusConflict, "Some expenses of the series are split across categories; change their category one by one",
+				gin.H{"split_expenses": split}, nil)
+			return errResponseSent
+		}
+	}
+
+	amount, ok := expenseFields["amount"]
+	if !ok {
+		return nil
+	}
+	userID, _ := c.Get("userId")
+	home, err := fx.HomeCurrency(tx, userID)
+	if err != nil {
+		return err
+	}
+	var allocations []models.ExpenseAllocation
+	if err := tx.Where("expense_id IN (?)", splitIDs).Order("created_at ASC").Find(&allocations).Error; err != nil {
+		return err
+	}
+	byExpense := map[uuid.UUID][]models.ExpenseAllocation{}
+	for _, allocation := range allocations {
+		byExpense[allocation.ExpenseID] = append(byExpense[allocation.ExpenseID], allocation)
+	}
+	for _, split := range byExpense {
+		for _, allocation := range scaleAllocations(split, amount.(money.Decimal), home) {
+			if err := tx.Model(&allocation).Update("amount", allocation.Amount).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyRecurringFields copies the provided values onto the template
+func applyRecurringFields(template *models.RecurringExpense, categoryID *uuid.UUID, amount *money.Decimal, description *string) {
+	if categoryID != nil {
+		template.CategoryID = *categoryID
+	}
+	if amount != nil {
+		template.Amount = *amount
+	}
+	if description != nil {
+		template.Description = *description
+	}
+}
+
+// DeleteRecurringExpense stops a template; expenses it already generated are kept
+func DeleteRecurringExpense(c *gin.Context) {
+	template, ok := fetchUserRecurringExpense(c, db.GetDBInstance())
+	if !ok {
+		return
+	}
+
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(template).Updates(map[string]interface{}{"active": false, "next_occurrence": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(template).Error
+	})
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete recurring expense", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Recurring expense deleted successfully", nil, nil)
+}
+
+// fetchUserRecurringExpense loads the template from the URL for the authenticated user, sending the error response itself
+func fetchUserRecurringExpense(c *gin.Context, query *gorm.DB) (*models.RecurringExpense, bool) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return nil, false
+	}
+
+	// Validate the recurringExpenseId format
+	templateID := c.Param("recurringExpenseId")
+	if _, err := uuid.Parse(templateID); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid recurring expense ID format", nil, nil)
+		return nil, false
+	}
+
+	var template models.RecurringExpense
+	if err := query.Where("user_id = ? AND id = ?", userID, templateID).First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Recurring expense not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch recurring expense", nil, nil)
+		}
+		return nil, false
+	}
+
+	return &template, true
+}
@@ -0,0 +1,367 @@
+package controller
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/fx"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/rules"
+	"expense-mgmt/utils"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateRule creates a category rule for the user
+func CreateRule(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var input struct {
+		Name       string                `json:"name" binding:"required"`
+		CategoryID uuid.UUID             `json:"category_id" binding:"required"`
+		Priority   *int                  `json:"priority"` // Defaults to after the user's last rule
+		Match      string                `json:"match"`    // "all" (default) or "any"
+		Conditions models.RuleConditions `json:"conditions" binding:"required"`
+		Active     *bool                 `json:"active"` // Defaults to true
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+
+	rule := models.CategoryRule{
+		UserID:     userID.(uuid.UUID),
+		Name:       strings.TrimSpace(input.Name),
+		CategoryID: input.CategoryID,
+		Match:      input.Match,
+		Conditions: input.Conditions,
+		Active:     input.Active == nil || *input.Active,
+	}
+	if rule.Match == "" {
+		rule.Match = rules.MatchAll
+	}
+	if !checkRule(c, db.GetDBInstance(), &rule) {
+		return
+	}
+
+	if input.Priority != nil {
+		rule.Priority = *input.Priority
+	} else {
+		var last *int
+		if err := db.GetDBInstance().Model(&models.CategoryRule{}).Where("user_id = ?", userID).Select("MAX(priority)").Scan(&last).Error; err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Database error while ordering the rule", nil, nil)
+			return
+		}
+		if last != nil {
+			rule.Priority = *last + 1
+		}
+	}
+
+	if err := db.GetDBInstance().Create(&rule).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to create rule", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusCreated, "Rule created successfully", rule, nil)
+}
+
+// ListRules fetches the user's category rules in the order they are tried
+func ListRules(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	query := db.GetDBInstance().Where("user_id = ?", userID)
+	if active := c.Query("active"); active != "" {
+		query = query.Where("active = ?", active == "true")
+	}
+
+	var categoryRules []models.CategoryRule
+	if err := query.Order("priority ASC, created_at ASC").Find(&categoryRules).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch rules", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Rules fetched successfully", categoryRules, nil)
+}
+
+// GetRule fetches a single category rule
+func GetRule(c *gin.Context) {
+	rule, ok := fetchUserRule(c)
+	if !ok {
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Rule fetched successfully", rule, nil)
+}
+
+// UpdateRule changes a category rule; it applies to expenses created from then on
+func UpdateRule(c *gin.Context) {
+	rule, ok := fetchUserRule(c)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Name       *string               `json:"name"`
+		CategoryID *uuid.UUID            `json:"category_id"`
+		Priority   *int                  `json:"priority"`
+		Match      *string               `json:"match"`
+		Conditions models.RuleConditions `json:"conditions"` // Replaces every condition when given
+		Active     *bool                 `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+
+	if updateData.Name != nil {
+		rule.Name = strings.TrimSpace(*updateData.Name)
+	}
+	if updateData.CategoryID != nil {
+		rule.CategoryID = *updateData.CategoryID
+	}
+	if updateData.Priority != nil {
+		rule.Priority = *updateData.Priority
+	}
+	if updateData.Match != nil {
+		rule.Match = *updateData.Match
+	}
+	if updateData.Conditions != nil {
+		rule.Conditions = updateData.Conditions
+	}
+	if updateData.Active != nil {
+		rule.Active = *updateData.Active
+	}
+	if !checkRule(c, db.GetDBInstance(), rule) {
+		return
+	}
+
+	if err := db.GetDBInstance().Save(rule).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to update rule", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Rule updated successfully", rule, nil)
+}
+
+// DeleteRule deletes a category rule; expenses it already categorized keep their category
+func DeleteRule(c *gin.Context) {
+	rule, ok := fetchUserRule(c)
+	if !ok {
+		return
+	}
+
+	if err := db.GetDBInstance().Delete(rule).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete rule", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Rule deleted successfully", nil, nil)
+}
+
+// DryRunRules lists the existing expenses whose category the rules would change, without changing anything
+func DryRunRules(c *gin.Context) {
+	engine, scope, ok := ruleRun(c)
+	if !ok {
+		return
+	}
+
+	changes, err := rules.Preview(db.GetDBInstance(), engine, scope)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to run rules", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Rules dry run completed successfully", gin.H{
+		"changes":       changes,
+		"changed_count": len(changes),
+	}, nil)
+}
+
+// ApplyRules runs the rules over existing expenses and recategorizes the ones that match
+func ApplyRules(c *gin.Context) {
+	engine, scope, ok := ruleRun(c)
+	if !ok {
+		return
+	}
+
+	changes, err := rules.Apply(db.GetDBInstance(), engine, scope)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to apply rules", nil, nil)
+		return
+	}
+	if len(changes) > 0 {
+		dates := make([]time.Time, len(changes))
+		for i, change := range changes {
+			dates[i] = change.Date
+		}
+		CheckBudgetAlerts(scope.UserID, dates...)
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Rules applied successfully", gin.H{
+		"changes":       changes,
+		"changed_count": len(changes),
+	}, nil)
+}
+
+// ruleRun builds the engine and scope of a dry run or retroactive run from the query parameters: start_date and
+// end_date narrow the expenses, and rule_id runs a single rule (active or not) instead of all active rules.
+// It sends the error response itself.
+func ruleRun(c *gin.Context) (*rules.Engine, rules.Scope, bool) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return nil, rules.Scope{}, false
+	}
+
+	scope := rules.Scope{UserID: userID.(uuid.UUID)}
+	for param, target := range map[string]**time.Time{"start_date": &scope.StartDate, "end_date": &scope.EndDate} {
+		if value := c.Query(param); value != "" {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid %s format. Use YYYY-MM-DD", param), nil, nil)
+				return nil, rules.Scope{}, false
+			}
+			*target = &parsed
+		}
+	}
+
+	home, err := fx.HomeCurrency(db.GetDBInstance(), userID)
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch user currency", nil, nil)
+		return nil, rules.Scope{}, false
+	}
+	scope.HomeCurrency = home
+
+	ruleID := c.Query("rule_id")
+	if ruleID == "" {
+		engine, err := rules.Load(db.GetDBInstance(), userID)
+		if err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch rules", nil, nil)
+			return nil, rules.Scope{}, false
+		}
+		return engine, scope, true
+	}
+
+	if _, err := uuid.Parse(ruleID); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid rule ID format", nil, nil)
+		return nil, rules.Scope{}, false
+	}
+	var rule models.CategoryRule
+	if err := db.GetDBInstance().Where("user_id = ? AND id = ?", userID, ruleID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Rule not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch rule", nil, nil)
+		}
+		return nil, rules.Scope{}, false
+	}
+	compiled, err := rules.Compile(rule)
+	if err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid rule: "+err.Error(), nil, nil)
+		return nil, rules.Scope{}, false
+	}
+	return rules.NewEngine(compiled), scope, true
+}
+
+// fetchUserRule loads the rule from the URL for the authenticated user, sending the error response itself
+func fetchUserRule(c *gin.Context) (*models.CategoryRule, bool) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return nil, false
+	}
+
+	// Validate the ruleId format
+	ruleID := c.Param("ruleId")
+	if _, err := uuid.Parse(ruleID); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid rule ID format", nil, nil)
+		return nil, false
+	}
+
+	var rule models.CategoryRule
+	if err := db.GetDBInstance().Where("user_id = ? AND id = ?", userID, ruleID).First(&rule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Rule not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch rule", nil, nil)
+		}
+		return nil, false
+	}
+
+	return &rule, true
+}
+
+// checkRule validates a new or changed rule, sending the error response itself
+func checkRule(c *gin.Context, database *gorm.DB, rule *models.CategoryRule) bool {
+	if rule.Name == "" {
+		utils.SendResponse(c, http.StatusBadRequest, "Rule name is required", nil, nil)
+		return false
+	}
+	if _, err := rules.Compile(*rule); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid rule: "+err.Error(), nil, nil)
+		return false
+	}
+
+	// The category must be a default category or one of the user's own
+	var categoryExists bool
+	err := database.Model(&models.Category{}).
+		Where("id = ? AND (is_default = ? OR user_id = ?)", rule.CategoryID, true, rule.UserID).
+		Select("COUNT(1) > 0").
+		Scan(&categoryExists).Error
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while verifying category_id", nil, nil)
+		return false
+	}
+	if !categoryExists {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid category_id", nil, nil)
+		return false
+	}
+	return true
+}
+
+// autoCategorize gives an expense sent without a category the category of the first matching rule, or the
+// fallback category. The expense amount is still the amount as paid in currency.
+func autoCategorize(database *gorm.DB, userID interface{}, expense *models.Expense, currency string) error {
+	engine, err := rules.Load(database, userID)
+	if err != nil {
+		return err
+	}
+	probe := *expense
+	probe.Currency = currency
+	categoryID, _, err := engine.Categorize(database, &probe)
+	if err != nil {
+		return err
+	}
+	expense.CategoryID = categoryID
+	return nil
+}
+
+// sendAutoCategorizeError reports a failed automatic categorization and returns false, or returns true when there was no error
+func sendAutoCategorizeError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, rules.ErrNoFallbackCategory) {
+		utils.SendResponse(c, http.StatusBadRequest, "No rule matched the expense; provide a category_id", nil, nil)
+	} else {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while categorizing the expense", nil, nil)
+	}
+	return false
+}
@@ -0,0 +1,193 @@
+package controller
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/fx"
+	"expense-mgmt/internal/money"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// spendingGroups maps the columns homeSpendingTotals can group spending lines by to their SQL expression
+var spendingGroups = map[string]string{
+	"expense_id":  "expense_id",
+	"category_id": "category_id",
+	"tag_id":      "tag_id", // Only on queries built by taggedSpendingLines
+	"date":        "CAST(date AS date)",
+	"recurring":   "recurring_expense_id IS NOT NULL",
+}
+
+// spendingKey identifies a group of spending lines; only the columns the lines were grouped by are set
+type spendingKey struct {
+	ExpenseID  uuid.UUID
+	CategoryID uuid.UUID
+	TagID      uuid.UUID
+	Date       time.Time // Day of the expenses
+	Recurring  bool      // Generated by a recurring expense
+}
+
+// spendingTotal is the spending of one group of lines in the home currency
+type spendingTotal struct {
+	spendingKey
+	Amount   money.Decimal
+	Lines    int
+	Expenses int           // Distinct expenses among the lines
+	Largest  money.Decimal // Largest amount a single expense contributes to the group
+}
+
+// foreignSpendingLine is a line of an expense paid in another currency than the home currency
+type foreignSpendingLine struct {
+	spendingKey
+	LineExpenseID  uuid.UUID
+	AllocationID   *uuid.UUID
+	LineDate       time.Time
+	Amount         money.Decimal // Line amount as stored
+	ExpenseAmount  money.Decimal // Stored amount of the whole expense
+	OriginalAmount money.Decimal // Amount of the whole expense as paid
+	Currency       string
+}
+
+// homeSpendingCondition selects the lines already in the home currency, including those recorded before
+// currencies were tracked
+const homeSpendingCondition = "(COALESCE(currency, '') IN ('', ?) OR original_amount IS NULL OR expense_amount = 0)"
+
+// homeSpendingTotals sums the lines selected by a models.SpendingLines query per group of the given columns
+// (see spendingGroups), in the home currency. Lines in the home currency are summed by the database. Lines of
+// expenses paid in another currency are converted at the rate of their date, one expense at a time; those without
+// a usable rate keep their stored amount and are reported as unconverted expenses.
+func homeSpendingTotals(query *gorm.DB, home string, groups ...string) ([]spendingTotal, int, error) {
+	var columns, expressions []string
+	for _, group := range groups {
+		columns = append(columns, spendingGroups[group]+" AS "+group)
+		expressions = append(expressions, spendingGroups[group])
+	}
+
+	// Lines in the home currency, summed per expense and then per group
+	perExpense := query.Session(&gorm.Session{}).
+		Select(strings.Join(append(columns, "SUM(amount) AS amount", "COUNT(*) AS lines"), ", ")).
+		Where(homeSpendingCondition, home).
+		Group(strings.Join(append(expressions, "expense_id"), ", "))
+	totals := query.Session(&gorm.Session{NewDB: true}).Table("(?) AS per_expense", perExpense).
+		Select(strings.Join(append(groups, "SUM(amount) AS amount", "SUM(lines) AS lines", "COUNT(*) AS expenses", "MAX(amount) AS largest"), ", "))
+	if len(groups) > 0 {
+		totals = totals.Group(strings.Join(groups, ", "))
+	}
+	var homeTotals []spendingTotal
+	if err := totals.Scan(&homeTotals).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Lines in other currencies, ordered so each expense's lines are together and rates are looked up once per day
+	var foreign []foreignSpendingLine
+	if err := query.Session(&gorm.Session{}).
+		Select(strings.Join(append(columns, "expense_id AS line_expense_id", "allocation_id", "date AS line_date",
+			"amount", "expense_amount", "original_amount", "currency"), ", ")).
+		Where("NOT "+homeSpendingCondition, home).
+		Order("currency, date, expense_id").
+		Scan(&foreign).Error; err != nil {
+		return nil, 0, err
+	}
+
+	byKey := make(map[spendingKey]*spendingTotal, len(homeTotals))
+	for i := range homeTotals {
+		byKey[homeTotals[i].spendingKey] = &homeTotals[i]
+	}
+	var added []*spendingTotal
+	converter := fx.NewConverter(db.GetDBInstance())
+	unconverted := 0
+	for start := 0; start < len(foreign); {
+		end := start + 1
+		for end < len(foreign) && foreign[end].LineExpenseID == foreign[start].LineExpenseID {
+			end++
+		}
+		lines := foreign[start:end]
+		start = end
+
+		amounts, converted, err := homeLineAmounts(converter, lines, home)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !converted {
+			unconverted++
+		}
+		expenseTotals := map[spendingKey]money.Decimal{}
+		for i, line := range lines {
+			expenseTotals[line.spendingKey] = expenseTotals[line.spendingKey].Add(amounts[i])
+			total, ok := byKey[line.spendingKey]
+			if !ok {
+				total = &spendingTotal{spendingKey: line.spendingKey}
+				byKey[line.spendingKey] = total
+				added = append(added, total)
+			}
+			total.Amount = total.Amount.Add(amounts[i])
+			total.Lines++
+		}
+		for key, amount := range expenseTotals {
+			total := byKey[key]
+			total.Expenses++
+			if total.Expenses == 1 || amount.GreaterThan(total.Largest) {
+				total.Largest = amount
+			}
+		}
+	}
+
+	for _, total := range added {
+		homeTotals = append(homeTotals, *total)
+	}
+	return homeTotals, unconverted, nil
+}
+
+// homeLineAmounts converts the lines of one foreign expense into the home currency. The converted total is
+// allocated over the lines, plus whatever part of the expense the query left out, so the lines of a whole expense
+// add up to its converted amount exactly. Without a usable rate the lines keep their stored amounts.
+func homeLineAmounts(converter *fx.Converter, lines []foreignSpendingLine, home string) ([]money.Decimal, bool, error) {
+	amounts := make([]money.Decimal, len(lines))
+	first := lines[0]
+	total, err := converter.Convert(first.OriginalAmount, first.Currency, home, first.LineDate)
+	if errors.Is(err, fx.ErrNoRate) {
+		for i, line := range lines {
+			amounts[i] = line.Amount
+		}
+		return amounts, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	// A query joining several groups to a line, such as tags, repeats it
+	lineIDs := make([]uuid.UUID, len(lines)) // uuid.Nil stands for an expense that is not split
+	shareOf := map[uuid.UUID]int{}
+	weights := make([]money.Decimal, 0, len(lines)+1)
+	rest := first.ExpenseAmount
+	for i, line := range lines {
+		if line.AllocationID != nil {
+			lineIDs[i] = *line.AllocationID
+		}
+		if _, ok := shareOf[lineIDs[i]]; !ok {
+			shareOf[lineIDs[i]] = len(weights)
+			weights = append(weights, line.Amount)
+			rest = rest.Sub(line.Amount)
+		}
+	}
+	if rest.Sign() > 0 {
+		weights = append(weights, rest)
+	}
+	shares := money.Allocate(total, weights, money.MinorUnits(home), money.HalfEven)
+	for i, lineID := range lineIDs {
+		amounts[i] = shares[shareOf[lineID]]
+	}
+	return amounts, true, nil
+}
+
+// taggedSpendingLines joins the lines selected by a models.SpendingLines query to the tags of their expense,
+// repeating a line for each tag, so grouping by tag_id counts an expense towards every tag it carries
+func taggedSpendingLines(database *gorm.DB, query *gorm.DB) *gorm.DB {
+	lines := database.Table("(?) AS l", query.Session(&gorm.Session{}).Select("*")).
+		Select("l.*, t.tag_id").
+		Joins("JOIN expense_tags t ON t.expense_id = l.expense_id")
+	return database.Table("(?) AS expenses", lines)
+}
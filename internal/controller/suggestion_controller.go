@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"expense-mgmt/db"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/suggest"
+	"expense-mgmt/utils"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Number of category suggestions returned by default and at most
+const (
+	defaultSuggestionLimit = 3
+	maxSuggestionLimit     = 10
+)
+
+// categorySuggestion is a suggested category with its probability
+type categorySuggestion struct {
+	CategoryID  uuid.UUID  `json:"category_id"`
+	Name        string     `json:"name"`
+	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+	Probability float64    `json:"probability"`
+}
+
+// SuggestCategories ranks the categories a draft expense likely belongs to, learned from the user's own expenses
+func SuggestCategories(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var input struct {
+		Description string `json:"description"`
+		Merchant    string `json:"merchant"`
+		Limit       int    `json:"limit"` // Number of suggestions (default 3, at most 10)
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+	draft := models.Expense{Description: input.Description, Merchant: input.Merchant}
+	if len(suggest.Tokenize(suggest.Text(&draft))) == 0 {
+		utils.SendResponse(c, http.StatusBadRequest, "A description or merchant is required", nil, nil)
+		return
+	}
+	if input.Limit == 0 {
+		input.Limit = defaultSuggestionLimit
+	}
+	if input.Limit < 0 || input.Limit > maxSuggestionLimit {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxSuggestionLimit), nil, nil)
+		return
+	}
+
+	ranked, examples, err := suggest.Suggest(db.GetDBInstance(), userID.(uuid.UUID), suggest.Text(&draft))
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to train category suggestions", nil, nil)
+		return
+	}
+
+	// Expenses may still point at categories deleted since; only suggest categories the user can pick
+	suggestions := []categorySuggestion{}
+	if len(ranked) > 0 {
+		categoryIDs := make([]uuid.UUID, len(ranked))
+		for i, suggestion := range ranked {
+			categoryIDs[i] = suggestion.CategoryID
+		}
+		var categories []models.Category
+		if err := db.GetDBInstance().
+			Where("id IN ? AND (is_default = ? OR user_id = ?)", categoryIDs, true, userID).
+			Find(&categories).Error; err != nil {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch categories", nil, nil)
+			return
+		}
+		byID := make(map[uuid.UUID]models.Category, len(categories))
+		for _, category := range categories {
+			byID[category.ID] = category
+		}
+
+		var total float64
+		for _, suggestion := range ranked {
+			if category, ok := byID[suggestion.CategoryID]; ok {
+				suggestions = append(suggestions, categorySuggestion{
+					CategoryID:  category.ID,
+					Name:        category.Name,
+					ParentID:    category.ParentID,
+					Probability: suggestion.Probability,
+				})
+				total += suggestion.Probability
+			}
+		}
+		for i := range suggestions {
+			suggestions[i].Probability = math.Round(suggestions[i].Probability/total*1e4) / 1e4
+		}
+		if len(suggestions) > input.Limit {
+			suggestions = suggestions[:input.Limit]
+		}
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Category suggestions fetched successfully", gin.H{
+		"suggestions": suggestions,
+		"trained_on":  examples, // Number of the user's expenses the suggestions are learned from
+	}, nil)
+}
@@ -0,0 +1,363 @@
+package controller
+
+import (
+	"errors"
+	"expense-mgmt/db"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"expense-mgmt/utils"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxTagNameLength matches the size of the tags.name column
+const maxTagNameLength = 50
+
+// tagWithUsage is a tag together with the number of expenses carrying it
+type tagWithUsage struct {
+	models.Tag
+	ExpenseCount int `json:"expense_count"`
+}
+
+// CreateTag creates a new tag for the user
+func CreateTag(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	var input struct {
+		Name      string `json:"name" binding:"required"`
+		ColorCode string `json:"color_code"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+
+	tag := models.Tag{UserID: userID.(uuid.UUID), Name: strings.TrimSpace(input.Name), ColorCode: input.ColorCode}
+	if !checkTagName(c, db.GetDBInstance(), &tag) {
+		return
+	}
+
+	if err := db.GetDBInstance().Create(&tag).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to create tag", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusCreated, "Tag created successfully", tag, nil)
+}
+
+// ListTags fetches the user's tags with the number of expenses carrying each
+func ListTags(c *gin.Context) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return
+	}
+
+	tags := []tagWithUsage{}
+	if err := db.GetDBInstance().Model(&models.Tag{}).
+		Select("tags.*, COUNT(expense_tags.expense_id) AS expense_count").
+		Joins("LEFT JOIN expense_tags ON expense_tags.tag_id = tags.id").
+		Where("tags.user_id = ?", userID).
+		Group("tags.id").
+		Order("tags.name ASC").
+		Scan(&tags).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch tags", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Tags fetched successfully", tags, nil)
+}
+
+// GetTag fetches a single tag with the number of expenses carrying it
+func GetTag(c *gin.Context) {
+	tag, ok := fetchUserTag(c)
+	if !ok {
+		return
+	}
+
+	var count int64
+	if err := db.GetDBInstance().Model(&models.ExpenseTag{}).Where("tag_id = ?", tag.ID).Count(&count).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch tag usage", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Tag fetched successfully", tagWithUsage{Tag: *tag, ExpenseCount: int(count)}, nil)
+}
+
+// UpdateTag renames or recolors a tag; expenses keep carrying it
+func UpdateTag(c *gin.Context) {
+	tag, ok := fetchUserTag(c)
+	if !ok {
+		return
+	}
+
+	var updateData struct {
+		Name      *string `json:"name"`
+		ColorCode *string `json:"color_code"`
+	}
+	if err := c.ShouldBindJSON(&updateData); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Invalid input: %v", err), nil, nil)
+		return
+	}
+
+	if updateData.Name != nil {
+		tag.Name = strings.TrimSpace(*updateData.Name)
+		if !checkTagName(c, db.GetDBInstance(), tag) {
+			return
+		}
+	}
+	if updateData.ColorCode != nil {
+		tag.ColorCode = *updateData.ColorCode
+	}
+
+	if err := db.GetDBInstance().Save(tag).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to update tag", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Tag updated successfully", tag, nil)
+}
+
+// DeleteTag deletes a tag and removes it from every expense; the expenses themselves are kept
+func DeleteTag(c *gin.Context) {
+	tag, ok := fetchUserTag(c)
+	if !ok {
+		return
+	}
+
+	err := db.GetDBInstance().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&models.ExpenseTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+	if err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Failed to delete tag", nil, nil)
+		return
+	}
+
+	utils.SendResponse(c, http.StatusOK, "Tag deleted successfully", nil, nil)
+}
+
+// fetchUserTag loads the tag from the URL for the authenticated user, sending the error response itself
+func fetchUserTag(c *gin.Context) (*models.Tag, bool) {
+	// Get user_id from context
+	userID, ok := c.Get("userId")
+	if !ok {
+		utils.SendResponse(c, http.StatusUnauthorized, "User ID not found", nil, nil)
+		return nil, false
+	}
+
+	// Validate the tagId format
+	tagID := c.Param("tagId")
+	if _, err := uuid.Parse(tagID); err != nil {
+		utils.SendResponse(c, http.StatusBadRequest, "Invalid tag ID format", nil, nil)
+		return nil, false
+	}
+
+	var tag models.Tag
+	if err := db.GetDBInstance().Where("user_id = ? AND id = ?", userID, tagID).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendResponse(c, http.StatusNotFound, "Tag not found", nil, nil)
+		} else {
+			utils.SendResponse(c, http.StatusInternalServerError, "Failed to fetch tag", nil, nil)
+		}
+		return nil, false
+	}
+
+	return &tag, true
+}
+
+// checkTagName validates the name of a new or renamed tag, sending the error response itself
+func checkTagName(c *gin.Context, database *gorm.DB, tag *models.Tag) bool {
+	if tag.Name == "" {
+		utils.SendResponse(c, http.StatusBadRequest, "Tag name is required", nil, nil)
+		return false
+	}
+	if len(tag.Name) > maxTagNameLength {
+		utils.SendResponse(c, http.StatusBadRequest, fmt.Sprintf("Tag name must be at most %d characters", maxTagNameLength), nil, nil)
+		return false
+	}
+
+	// Names are unique per user, ignoring case
+	var count int64
+	if err := database.Model(&models.Tag{}).
+		Where("user_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", tag.UserID, tag.Name, tag.ID).
+		Count(&count).Error; err != nil {
+		utils.SendResponse(c, http.StatusInternalServerError, "Database error while checking tag name", nil, nil)
+		return false
+	}
+	if count > 0 {
+		utils.SendResponse(c, http.StatusConflict, "Tag name already exists", nil, nil)
+		return false
+	}
+	return true
+}
+
+// validateTags checks that every tag exists and belongs to the user, and returns a message for the client when not
+func validateTags(database *gorm.DB, userID interface{}, tagIDs []uuid.UUID) (string, error) {
+	unique := uniqueTagIDs(tagIDs)
+	if len(unique) == 0 {
+		return "", nil
+	}
+
+	var found int64
+	if err := database.Model(&models.Tag{}).Where("user_id = ? AND id IN ?", userID, unique).Count(&found).Error; err != nil {
+		return "", err
+	}
+	if int(found) != len(unique) {
+		return "Invalid tag ID in tag_ids", nil
+	}
+	return "", nil
+}
+
+// saveTags replaces the tags of an expense and fills in expense.Tags; an empty slice removes them all
+func saveTags(tx *gorm.DB, expense *models.Expense, tagIDs []uuid.UUID) error {
+	if err := tx.Where("expense_id = ?", expense.ExpenseID).Delete(&models.ExpenseTag{}).Error; err != nil {
+		return err
+	}
+	expense.TagIDs = nil
+	expense.Tags = nil
+
+	unique := uniqueTagIDs(tagIDs)
+	if len(unique) == 0 {
+		return nil
+	}
+	links := make([]models.ExpenseTag, len(unique))
+	for i, tagID := range unique {
+		links[i] = models.ExpenseTag{ExpenseID: expense.ExpenseID, TagID: tagID}
+	}
+	if err := tx.Create(&links).Error; err != nil {
+		return err
+	}
+	return loadTags(tx, expense)
+}
+
+// loadTags fills in the tags of each expense with a single query
+func loadTags(database *gorm.DB, expenses ...*models.Expense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*models.Expense, len(expenses))
+	ids := make([]uuid.UUID, 0, len(expenses))
+	for _, expense := range expenses {
+		byID[expense.ExpenseID] = expense
+		ids = append(ids, expense.ExpenseID)
+	}
+
+	var rows []struct {
+		ExpenseID uuid.UUID
+		models.Tag
+	}
+	if err := database.Table("expense_tags").
+		Select("expense_tags.expense_id, tags.*").
+		Joins("JOIN tags ON tags.id = expense_tags.tag_id").
+		Where("expense_tags.expense_id IN ?", ids).
+		Order("tags.name ASC").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		expense := byID[row.ExpenseID]
+		expense.Tags = append(expense.Tags, row.Tag)
+	}
+	return nil
+}
+
+// uniqueTagIDs drops duplicates and nil IDs, keeping the original order
+func uniqueTagIDs(tagIDs []uuid.UUID) []uuid.UUID {
+	seen := map[uuid.UUID]bool{}
+	unique := make([]uuid.UUID, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		if tagID != uuid.Nil && !seen[tagID] {
+			seen[tagID] = true
+			unique = append(unique, tagID)
+		}
+	}
+	return unique
+}
+
+// parseTagFilter reads the tag_ids (comma-separated) and tag_mode ("any" or "all") query parameters
+func parseTagFilter(c *gin.Context) ([]uuid.UUID, string, error) {
+	mode := c.DefaultQuery("tag_mode", "any")
+	if mode != "any" && mode != "all" {
+		return nil, "", errors.New("tag_mode must be 'any' or 'all'")
+	}
+
+	var tagIDs []uuid.UUID
+	for _, value := range strings.Split(c.Query("tag_ids"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		tagID, err := uuid.Parse(value)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid tag ID %q in tag_ids", value)
+		}
+		tagIDs = append(tagIDs, tagID)
+	}
+	return uniqueTagIDs(tagIDs), mode, nil
+}
+
+// filterByTags restricts a query with an expense_id column to expenses carrying any or all of the tags
+func filterByTags(database *gorm.DB, query *gorm.DB, tagIDs []uuid.UUID, mode string) *gorm.DB {
+	if len(tagIDs) == 0 {
+		return query
+	}
+	tagged := database.Model(&models.ExpenseTag{}).Select("expense_id").Where("tag_id IN ?", tagIDs)
+	if mode == "all" {
+		tagged = tagged.Group("expense_id").Having("COUNT(DISTINCT tag_id) = ?", len(tagIDs))
+	}
+	return query.Where("expense_id IN (?)", tagged)
+}
+
+// tagBreakdown attributes the spending selected by a models.SpendingLines query to every tag of each expense,
+// largest first. An expense with several tags counts towards each of them, so the percentages can add up to more
+// than 100.
+func tagBreakdown(database *gorm.DB, query *gorm.DB, home string, totalSpending money.Decimal) ([]map[string]any, error) {
+	if totalSpending.Sign() <= 0 {
+		return nil, nil
+	}
+	byTag, _, err := homeSpendingTotals(taggedSpendingLines(database, query), home, "tag_id")
+	if err != nil || len(byTag) == 0 {
+		return nil, err
+	}
+	tagIDs := make([]uuid.UUID, len(byTag))
+	for i, total := range byTag {
+		tagIDs[i] = total.TagID
+	}
+	var tags []models.Tag
+	if err := database.Where("id IN ?", tagIDs).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(tags))
+	for _, tag := range tags {
+		names[tag.ID] = tag.Name
+	}
+
+	breakdown := make([]map[string]any, 0, len(byTag))
+	for _, total := range byTag {
+		breakdown = append(breakdown, map[string]any{
+			"tag_id":     total.TagID.String(),
+			"name":       names[total.TagID],
+			"total":      total.Amount,
+			"count":      total.Expenses,
+			"percentage": total.Amount.Mul(money.NewFromInt(100)).Div(totalSpending, 2, money.HalfUp),
+		})
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		return breakdown[i]["total"].(money.Decimal).GreaterThan(breakdown[j]["total"].(money.Decimal))
+	})
+	return breakdown, nil
+}
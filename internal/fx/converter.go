@@ -0,0 +1,101 @@
+package fx
+
+import (
+	"errors"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoRate is returned when the rate table has no usable rate for a conversion
+var ErrNoRate = errors.New("no exchange rate available")
+
+// rateScale is the precision of derived (inverse and cross) rates
+const rateScale = 10
+
+// defaultMaxRateAge is how many days old a rate may be by default, enough to bridge weekends and holidays
+// when no rates are published
+const defaultMaxRateAge = 7
+
+// Converter converts amounts with the stored rates, using the latest rate published on or before the
+// transaction date and at most maxAge days before it. Rates are cached, so a converter should live for a
+// single request.
+type Converter struct {
+	db     *gorm.DB
+	base   string
+	maxAge int
+	cache  map[string]money.Decimal
+}
+
+// NewConverter returns a converter reading rates from the given database
+func NewConverter(database *gorm.DB) *Converter {
+	return &Converter{db: database, base: crossBase, maxAge: maxRateAge, cache: map[string]money.Decimal{}}
+}
+
+// Convert converts amount from one currency to another on the given date, rounded half-even to the minor units
+// of the target currency
+func (c *Converter) Convert(amount money.Decimal, from, to string, date time.Time) (money.Decimal, error) {
+	rate, err := c.Rate(from, to, date)
+	if err != nil {
+		return money.Zero, err
+	}
+	return money.NewMoney(amount, from).Convert(rate, to, money.HalfEven).Amount, nil
+}
+
+// Rate returns how many units of to one unit of from was worth on the given date.
+// It uses a direct rate, the inverse of the opposite rate, or a cross rate through the provider's base currency.
+func (c *Converter) Rate(from, to string, date time.Time) (money.Decimal, error) {
+	if from == to {
+		return money.NewFromInt(1), nil
+	}
+
+	day := date.Format("2006-01-02")
+	key := from + to + day
+	if rate, ok := c.cache[key]; ok {
+		return rate, nil
+	}
+
+	rate, err := c.pairRate(from, to, date)
+	if errors.Is(err, ErrNoRate) && from != c.base && to != c.base {
+		var fromBase, toBase money.Decimal
+		if fromBase, err = c.pairRate(c.base, from, date); err == nil {
+			if toBase, err = c.pairRate(c.base, to, date); err == nil {
+				rate = toBase.Div(fromBase, rateScale, money.HalfEven)
+			}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, ErrNoRate) {
+			return money.Zero, fmt.Errorf("%w for %s to %s on %s", ErrNoRate, from, to, day)
+		}
+		return money.Zero, err
+	}
+
+	c.cache[key] = rate
+	return rate, nil
+}
+
+// pairRate looks up the latest direct or inverse rate between two currencies on or before the date, and no
+// older than the maximum age when there is one
+func (c *Converter) pairRate(from, to string, date time.Time) (money.Decimal, error) {
+	var rate models.ExchangeRate
+	query := c.db.Where("((base = ? AND quote = ?) OR (base = ? AND quote = ?)) AND date <= ?",
+		from, to, to, from, date.Format("2006-01-02"))
+	if c.maxAge > 0 {
+		query = query.Where("date >= ?", date.AddDate(0, 0, -c.maxAge).Format("2006-01-02"))
+	}
+	err := query.Order("date DESC").First(&rate).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return money.Zero, ErrNoRate
+	}
+	if err != nil {
+		return money.Zero, err
+	}
+	if rate.Base == from {
+		return rate.Rate, nil
+	}
+	return money.NewFromInt(1).Div(rate.Rate, rateScale, money.HalfEven), nil
+}
@@ -0,0 +1,51 @@
+package fx
+
+import (
+	"expense-mgmt/internal/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultHomeCurrency is used for users without a currency preference, matching the users table default
+	DefaultHomeCurrency = "CAD"
+	defaultBaseCurrency = "EUR"
+)
+
+// isoCurrencies lists the active ISO-4217 currency codes
+var isoCurrencies = toSet(`AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BRL BSD BTN BWP BYN
+	BZD CAD CDF CHF CLP CNY COP CRC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ
+	GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL
+	LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN
+	PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD
+	TWD TZS UAH UGX USD UYU UZS VES VND VUV WST XAF XCD XOF XPF YER ZAR ZMW ZWL`)
+
+func toSet(codes string) map[string]bool {
+	set := map[string]bool{}
+	for _, code := range strings.Fields(codes) {
+		set[code] = true
+	}
+	return set
+}
+
+// NormalizeCurrency upper-cases a currency code and reports whether it is a known ISO-4217 code
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return code, isoCurrencies[code]
+}
+
+// HomeCurrency returns the currency the user's analysis is reported in
+func HomeCurrency(database *gorm.DB, userID interface{}) (string, error) {
+	var currencies []string
+	if err := database.Model(&models.User{}).Where("user_id = ?", userID).Pluck("currency", &currencies).Error; err != nil {
+		return "", err
+	}
+	if len(currencies) == 0 {
+		return DefaultHomeCurrency, nil
+	}
+	if currency, ok := NormalizeCurrency(currencies[0]); ok {
+		return currency, nil
+	}
+	return DefaultHomeCurrency, nil
+}
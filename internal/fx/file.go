@@ -0,0 +1,85 @@
+package fx
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// FileProvider reads rates from a local CSV file with the columns date,base,quote,rate, e.g.
+//
+//	2024-11-01,USD,CAD,1.3921
+//
+// A header row and lines starting with # are ignored.
+type FileProvider struct {
+	Path string
+}
+
+// Name identifies the provider in logs
+func (p *FileProvider) Name() string {
+	return "file"
+}
+
+// Fetch returns the rates in the file dated on or after since
+func (p *FileProvider) Fetch(ctx context.Context, since time.Time) ([]models.ExchangeRate, error) {
+	file, err := os.Open(p.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	var rates []models.ExchangeRate
+	for index := 1; ; index++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if index == 1 && strings.EqualFold(record[0], "date") {
+			continue // Header
+		}
+
+		rate, err := parseRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("%s record %d: %w", p.Path, index, err)
+		}
+		if rate.Date.Before(since) {
+			continue
+		}
+		rates = append(rates, rate)
+	}
+}
+
+func parseRecord(record []string) (models.ExchangeRate, error) {
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(record[0]))
+	if err != nil {
+		return models.ExchangeRate{}, fmt.Errorf("invalid date %q", record[0])
+	}
+	base, ok := NormalizeCurrency(record[1])
+	if !ok {
+		return models.ExchangeRate{}, fmt.Errorf("invalid base currency %q", record[1])
+	}
+	quote, ok := NormalizeCurrency(record[2])
+	if !ok {
+		return models.ExchangeRate{}, fmt.Errorf("invalid quote currency %q", record[2])
+	}
+	value, err := money.Parse(record[3])
+	if err != nil || value.Sign() <= 0 {
+		return models.ExchangeRate{}, fmt.Errorf("invalid rate %q", record[3])
+	}
+	return models.ExchangeRate{Base: base, Quote: quote, Date: date, Rate: value}, nil
+}
@@ -0,0 +1,86 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// initialHistory is how far back the HTTP provider goes when the rate table has nothing from it yet
+const initialHistory = 365 * 24 * time.Hour
+
+// HTTPProvider fetches daily rates from a Frankfurter-compatible API:
+// GET {Endpoint}/{since}..?from={Base} answers with {"base": "EUR", "rates": {"2024-11-01": {"USD": 1.08, ...}}}.
+type HTTPProvider struct {
+	Endpoint string
+	Base     string
+	Client   *http.Client
+}
+
+// Name identifies the provider in logs
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+// Fetch returns every rate published from since until today
+func (p *HTTPProvider) Fetch(ctx context.Context, since time.Time) ([]models.ExchangeRate, error) {
+	client := p.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	if since.IsZero() {
+		since = time.Now().Add(-initialHistory)
+	}
+
+	url := fmt.Sprintf("%s/%s..?from=%s", strings.TrimRight(p.Endpoint, "/"), since.Format("2006-01-02"), p.Base)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("exchange rate request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("exchange rate endpoint returned %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Base  string                              `json:"base"`
+		Rates map[string]map[string]money.Decimal `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid exchange rate response: %w", err)
+	}
+
+	base, ok := NormalizeCurrency(result.Base)
+	if !ok {
+		return nil, fmt.Errorf("invalid base currency %q in exchange rate response", result.Base)
+	}
+
+	var rates []models.ExchangeRate
+	for day, quotes := range result.Rates {
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q in exchange rate response", day)
+		}
+		for code, value := range quotes {
+			quote, ok := NormalizeCurrency(code)
+			if !ok || value.Sign() <= 0 {
+				continue // Skip currencies we do not support
+			}
+			rates = append(rates, models.ExchangeRate{Base: base, Quote: quote, Date: date, Rate: value})
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,55 @@
+package fx
+
+import (
+	"context"
+	"expense-mgmt/configs"
+	"expense-mgmt/internal/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RateProvider loads exchange rates from an external source into the rate table
+type RateProvider interface {
+	// Name identifies the provider in logs and in the source column of stored rates
+	Name() string
+	// Fetch returns the rates published on or after since; a zero since means everything the provider offers
+	Fetch(ctx context.Context, since time.Time) ([]models.ExchangeRate, error)
+}
+
+// NewProvider builds the rate provider selected in the configuration; it returns nil when none is configured
+func NewProvider(config *configs.Config) (RateProvider, error) {
+	switch config.FX.Provider {
+	case "", "none":
+		return nil, nil
+	case "file":
+		if config.FX.RatesFile == "" {
+			return nil, fmt.Errorf("fx.rates_file is required for the file provider")
+		}
+		return &FileProvider{Path: config.FX.RatesFile}, nil
+	case "http":
+		if config.FX.Endpoint == "" {
+			return nil, fmt.Errorf("fx.endpoint is required for the http provider")
+		}
+		return &HTTPProvider{Endpoint: config.FX.Endpoint, Base: BaseCurrency(config)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported exchange rate provider %q", config.FX.Provider)
+	}
+}
+
+// BaseCurrency returns the currency the configured provider quotes its rates against
+func BaseCurrency(config *configs.Config) string {
+	if base := strings.ToUpper(strings.TrimSpace(config.FX.BaseCurrency)); base != "" {
+		return base
+	}
+	return defaultBaseCurrency
+}
+
+// MaxRateAge returns how long before a transaction date a rate may have been published to still be used;
+// 0 means any earlier rate is used
+func MaxRateAge(config *configs.Config) int {
+	if config.FX.MaxRateAgeDays >= 0 {
+		return config.FX.MaxRateAgeDays
+	}
+	return defaultMaxRateAge
+}
@@ -0,0 +1,100 @@
+package fx
+
+import (
+	"context"
+	"expense-mgmt/configs"
+	"expense-mgmt/internal/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultSyncInterval = 24 * time.Hour
+	syncTimeout         = 2 * time.Minute
+)
+
+// crossBase is the currency used to derive rates between two quoted currencies, e.g. USD->CAD through EUR
+var crossBase = defaultBaseCurrency
+
+// maxRateAge is how many days before a transaction date the rate used for it may have been published
+var maxRateAge = defaultMaxRateAge
+
+// StartSync loads rates from the configured provider now and then on every sync interval.
+// Without a provider the rate table is left as it is, e.g. for rates inserted by hand.
+func StartSync(database *gorm.DB) (RateProvider, error) {
+	config := configs.LoadConfig() // Load the configuration
+	crossBase = BaseCurrency(config)
+	maxRateAge = MaxRateAge(config)
+
+	provider, err := NewProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	if provider == nil {
+		log.Println("Exchange rate provider not configured, using stored rates only")
+		return nil, nil
+	}
+
+	interval := time.Duration(config.FX.SyncIntervalHours) * time.Hour
+	if interval <= 0 {
+		interval = defaultSyncInterval
+	}
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), syncTimeout)
+			loaded, err := Sync(ctx, database, provider)
+			cancel()
+			if err != nil {
+				log.Printf("Exchange rate sync with %s failed: %v", provider.Name(), err)
+			} else if loaded > 0 {
+				log.Printf("Loaded %d exchange rates from %s", loaded, provider.Name())
+			}
+			time.Sleep(interval)
+		}
+	}()
+
+	return provider, nil
+}
+
+// Sync stores the provider's rates, replacing rates already stored for the same pair and day.
+// Only rates newer than the provider's latest stored day are requested, except from the file provider
+// which is always read in full so corrections to the file are picked up.
+func Sync(ctx context.Context, database *gorm.DB, provider RateProvider) (int, error) {
+	var since time.Time
+	if _, isFile := provider.(*FileProvider); !isFile {
+		var latest *time.Time
+		if err := database.Model(&models.ExchangeRate{}).
+			Where("source = ?", provider.Name()).
+			Select("MAX(date)").
+			Scan(&latest).Error; err != nil {
+			return 0, err
+		}
+		if latest != nil {
+			since = *latest
+		}
+	}
+
+	rates, err := provider.Fetch(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+	if len(rates) == 0 {
+		return 0, nil
+	}
+	for i := range rates {
+		rates[i].Source = provider.Name()
+	}
+
+	err = database.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+	}).CreateInBatches(rates, 500).Error
+	if err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
@@ -1,21 +1,56 @@
 package models
 
 import (
+	"expense-mgmt/internal/money"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// Budget represents a spending cap set by a user on a specific category
+// Budget represents a spending cap set by a user on a specific category, on a set of categories (see
+// BudgetCategory) or, with neither, on the user's total spending
 type Budget struct {
 	BudgetID   uuid.UUID      `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"budget_id"`
 	UserID     uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`           // Foreign key to the User
-	CategoryID uuid.UUID      `gorm:"type:uuid" json:"category_id"`                // Foreign key to the Category (nullable)
-	Amount     float64        `gorm:"type:decimal(10,2);check:amount >= 0;not null" json:"amount"` // Budget amount
-	StartDate  time.Time      `gorm:"type:date;not null" json:"start_date"`
-	EndDate    time.Time      `gorm:"type:date;not null" json:"end_date"`
+	CategoryID *uuid.UUID     `gorm:"type:uuid" json:"category_id"`                // Foreign key to the Category (nullable)
+	CategoryIDs []uuid.UUID   `gorm:"-" json:"category_ids,omitempty"`             // Categories of a multi-category budget, from budget_categories
+	Amount     money.Decimal  `gorm:"type:decimal(12,3);check:amount >= 0;not null" json:"amount"` // Budget amount
+	StartDate  time.Time      `gorm:"type:date;not null" json:"start_date"`      // First day of the budget, and of its first period when it recurs
+	EndDate    *time.Time     `gorm:"type:date" json:"end_date"`                 // Last day of the budget; nil for a recurring budget without an end
+	Recurrence string         `gorm:"size:10" json:"recurrence,omitempty"`       // weekly, monthly, quarterly or yearly; empty for a one-off budget
+	Rollover   string         `gorm:"size:10" json:"rollover,omitempty"`         // What a period carries into the next: unspent, overspent or both
+	AlertThresholds AlertThresholds `gorm:"type:jsonb;not null;default:'[]'" json:"alert_thresholds"` // Percentages of the available amount that trigger an alert
+	EnvelopePlanID *uuid.UUID     `gorm:"type:uuid;index" json:"envelope_plan_id,omitempty"` // Envelope plan the budget is an envelope of
 	CreatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Soft delete
-}
\ No newline at end of file
+}
+
+// BudgetsOverlapping narrows a budget query to the budgets whose span overlaps start..end. A nil end, like the
+// end date of a recurring budget without one, is open-ended.
+func BudgetsOverlapping(db *gorm.DB, start time.Time, end *time.Time) *gorm.DB {
+	db = db.Where("(end_date IS NULL OR end_date >= ?)", start)
+	if end != nil {
+		db = db.Where("start_date <= ?", *end)
+	}
+	return db
+}
+
+// Categories returns the categories the budget covers, or nil for an overall budget
+func (b Budget) Categories() []uuid.UUID {
+	if b.CategoryID != nil {
+		return []uuid.UUID{*b.CategoryID}
+	}
+	return b.CategoryIDs
+}
+
+// BudgetsCovering narrows a budget query to the budgets covering any of the categories, or to overall budgets when
+// there are none. Overall budgets cap the total and never compete with budgets on categories.
+func BudgetsCovering(db *gorm.DB, categoryIDs []uuid.UUID) *gorm.DB {
+	if len(categoryIDs) == 0 {
+		return db.Where("budgets.category_id IS NULL AND NOT EXISTS (SELECT 1 FROM budget_categories bc WHERE bc.budget_id = budgets.budget_id)")
+	}
+	return db.Where("(budgets.category_id IN ? OR EXISTS (SELECT 1 FROM budget_categories bc WHERE bc.budget_id = budgets.budget_id AND bc.category_id IN ?))",
+		categoryIDs, categoryIDs)
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"expense-mgmt/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery statuses of a budget alert
+const (
+	AlertDeliveryPending   = "pending"
+	AlertDeliverySending   = "sending"
+	AlertDeliveryDelivered = "delivered"
+	AlertDeliveryFailed    = "failed"
+)
+
+// BudgetAlertEvent records that the spending in a budget period reached one of the budget's alert thresholds.
+// The unique index on budget, period and threshold makes each alert fire once per period; the alert worker
+// delivers it and retries until every notifier succeeded.
+type BudgetAlertEvent struct {
+	ID            uuid.UUID     `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"event_id"`
+	BudgetID      uuid.UUID     `gorm:"type:uuid;not null;uniqueIndex:idx_budget_alert_events_period" json:"budget_id"`
+	UserID        uuid.UUID     `gorm:"type:uuid;not null;index" json:"user_id"`
+	PeriodStart   time.Time     `gorm:"type:date;not null;uniqueIndex:idx_budget_alert_events_period" json:"period_start"`
+	Threshold     int           `gorm:"not null;uniqueIndex:idx_budget_alert_events_period" json:"threshold"` // Percentage of the available amount
+	Spent         money.Decimal `gorm:"type:decimal(12,3);not null" json:"total_spent"`                       // Spending when the threshold was reached
+	Available     money.Decimal `gorm:"type:decimal(12,3);not null" json:"available"`
+	CreatedAt     time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	Status        string        `gorm:"size:20;not null;default:pending;index" json:"delivery_status"` // pending, sending, delivered or failed
+	Payload       string        `gorm:"type:jsonb" json:"-"`                                           // The alert to deliver, as JSON
+	Attempts      int           `gorm:"not null;default:0" json:"delivery_attempts"`
+	DeliveredTo   string        `gorm:"type:text" json:"-"`                    // Comma-separated notifiers that already delivered it
+	LastError     string        `gorm:"type:text" json:"last_error,omitempty"` // Error of the last failed delivery
+	NextAttemptAt *time.Time    `json:"-"`                                     // When a failed delivery is retried
+	DeliveredAt   *time.Time    `json:"delivered_at,omitempty"`
+	UpdatedAt     time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"-"`
+}
+
+// AlertThresholds are the percentages of a budget's available amount that trigger an alert, stored as a JSON array
+type AlertThresholds []int
+
+// Value implements driver.Valuer
+func (t AlertThresholds) Value() (driver.Value, error) {
+	if t == nil {
+		t = AlertThresholds{}
+	}
+	data, err := json.Marshal(t)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (t *AlertThresholds) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	case nil:
+		*t = nil
+		return nil
+	}
+	return errors.New("unsupported type for alert thresholds")
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BudgetCategory is one of the categories covered by a multi-category budget
+type BudgetCategory struct {
+	BudgetID   uuid.UUID `gorm:"type:uuid;primaryKey" json:"budget_id"`
+	CategoryID uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"category_id"`
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// FillBudgetCategories sets CategoryIDs on the multi-category budgets among the given ones
+func FillBudgetCategories(db *gorm.DB, budgets []Budget) error {
+	if len(budgets) == 0 {
+		return nil
+	}
+	budgetIDs := make([]uuid.UUID, len(budgets))
+	for i, budget := range budgets {
+		budgetIDs[i] = budget.BudgetID
+	}
+
+	var rows []BudgetCategory
+	if err := db.Where("budget_id IN ?", budgetIDs).Order("created_at, category_id").Find(&rows).Error; err != nil {
+		return err
+	}
+	byBudget := make(map[uuid.UUID][]uuid.UUID, len(budgets))
+	for _, row := range rows {
+		byBudget[row.BudgetID] = append(byBudget[row.BudgetID], row.CategoryID)
+	}
+	for i := range budgets {
+		budgets[i].CategoryIDs = byBudget[budgets[i].BudgetID]
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"expense-mgmt/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BudgetTemplate is a named set of category amounts, e.g. "Regular month", applied to a date range to create one
+// budget per line
+type BudgetTemplate struct {
+	ID        uuid.UUID            `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"template_id"`
+	UserID    uuid.UUID            `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name      string               `gorm:"size:100;not null" json:"name"` // Unique per user, case-insensitively (idx_budget_templates_user_name)
+	Lines     []BudgetTemplateLine `gorm:"-" json:"lines"`
+	CreatedAt time.Time            `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time            `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// BudgetTemplateLine is the amount a template budgets for one category
+type BudgetTemplateLine struct {
+	ID         uuid.UUID     `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"line_id"`
+	TemplateID uuid.UUID     `gorm:"type:uuid;not null;index" json:"template_id"`
+	CategoryID uuid.UUID     `gorm:"type:uuid;not null" json:"category_id"` // Unique per template
+	Amount     money.Decimal `gorm:"type:decimal(12,3);check:amount > 0;not null" json:"amount"`
+}
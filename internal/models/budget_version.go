@@ -0,0 +1,164 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"expense-mgmt/internal/money"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Changes recorded in budget_versions
+const (
+	BudgetCreated = "created"
+	BudgetUpdated = "updated"
+	BudgetDeleted = "deleted"
+)
+
+// BudgetVersion records one change to a budget: who made it, when, and the budget before and after. Versions are
+// numbered from 1 per budget (idx_budget_versions_budget_version).
+type BudgetVersion struct {
+	ID        uuid.UUID       `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"version_id"`
+	BudgetID  uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_budget_versions_budget_version" json:"budget_id"`
+	UserID    uuid.UUID       `gorm:"type:uuid;not null;index" json:"user_id"` // Owner of the budget
+	Version   int             `gorm:"not null;uniqueIndex:idx_budget_versions_budget_version" json:"version"`
+	Action    string          `gorm:"size:10;not null" json:"action"` // created, updated or deleted
+	ChangedBy *uuid.UUID      `gorm:"type:uuid" json:"changed_by"`    // User who made the change; nil for changes made outside a request
+	OldValues *BudgetSnapshot `gorm:"type:jsonb" json:"old_values"`   // Nil when the budget was created
+	NewValues *BudgetSnapshot `gorm:"type:jsonb" json:"new_values"`   // Nil when the budget was deleted
+	CreatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"changed_at"`
+}
+
+// BudgetSnapshot is the part of a budget a user can change, as recorded in its versions
+type BudgetSnapshot struct {
+	CategoryID      *uuid.UUID      `json:"category_id"`
+	CategoryIDs     []uuid.UUID     `json:"category_ids,omitempty"`
+	Amount          money.Decimal   `json:"amount"`
+	StartDate       time.Time       `json:"start_date"`
+	EndDate         *time.Time      `json:"end_date"`
+	Recurrence      string          `json:"recurrence,omitempty"`
+	Rollover        string          `json:"rollover,omitempty"`
+	AlertThresholds AlertThresholds `json:"alert_thresholds"`
+}
+
+// SnapshotBudget captures the current values of a budget; CategoryIDs must be filled
+func SnapshotBudget(budget Budget) *BudgetSnapshot {
+	return &BudgetSnapshot{
+		CategoryID:      budget.CategoryID,
+		CategoryIDs:     budget.CategoryIDs,
+		Amount:          budget.Amount,
+		StartDate:       budget.StartDate,
+		EndDate:         budget.EndDate,
+		Recurrence:      budget.Recurrence,
+		Rollover:        budget.Rollover,
+		AlertThresholds: budget.AlertThresholds,
+	}
+}
+
+// Restore sets the values of the snapshot on the budget
+func (s BudgetSnapshot) Restore(budget *Budget) {
+	budget.CategoryID = s.CategoryID
+	budget.CategoryIDs = s.CategoryIDs
+	budget.Amount = s.Amount
+	budget.StartDate = s.StartDate
+	budget.EndDate = s.EndDate
+	budget.Recurrence = s.Recurrence
+	budget.Rollover = s.Rollover
+	budget.AlertThresholds = s.AlertThresholds
+}
+
+// Value implements driver.Valuer
+func (s BudgetSnapshot) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (s *BudgetSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	}
+	return errors.New("unsupported type for budget snapshot")
+}
+
+// RecordBudgetVersion adds the next version of a budget, comparing it with its values before the change (nil when
+// it was just created). An update that changed nothing is not recorded.
+func RecordBudgetVersion(tx *gorm.DB, budget Budget, action string, old *BudgetSnapshot, changedBy *uuid.UUID) error {
+	version := BudgetVersion{BudgetID: budget.BudgetID, UserID: budget.UserID, Action: action, ChangedBy: changedBy, OldValues: old}
+	if action != BudgetDeleted {
+		version.NewValues = SnapshotBudget(budget)
+	}
+	if action == BudgetUpdated && SameSnapshot(old, version.NewValues) {
+		return nil
+	}
+
+	// Lock the budget, deleted or not, so changes made concurrently are numbered one after the other
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("budget_id").
+		First(&Budget{}, "budget_id = ?", budget.BudgetID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&BudgetVersion{}).
+		Where("budget_id = ?", budget.BudgetID).
+		Select("COALESCE(MAX(version), 0) + 1").
+		Scan(&version.Version).Error; err != nil {
+		return err
+	}
+	return tx.Create(&version).Error
+}
+
+// BudgetsAsOf returns the budgets as they stood at the given time, from their latest version up to then. Budgets
+// created later or deleted by then are left out. The budgets should be loaded with their soft-deleted ones.
+func BudgetsAsOf(db *gorm.DB, budgets []Budget, asOf time.Time) ([]Budget, error) {
+	if len(budgets) == 0 {
+		return budgets, nil
+	}
+	ids := make([]uuid.UUID, len(budgets))
+	for i, budget := range budgets {
+		ids[i] = budget.BudgetID
+	}
+
+	var versions []BudgetVersion
+	if err := db.Where("budget_id IN ? AND created_at <= ?", ids, asOf).Order("version ASC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	latest := make(map[uuid.UUID]BudgetVersion, len(budgets))
+	for _, version := range versions {
+		latest[version.BudgetID] = version
+	}
+
+	var result []Budget
+	for _, budget := range budgets {
+		version, ok := latest[budget.BudgetID]
+		if !ok || version.NewValues == nil {
+			continue
+		}
+		version.NewValues.Restore(&budget)
+		budget.DeletedAt = gorm.DeletedAt{}
+		result = append(result, budget)
+	}
+	return result, nil
+}
+
+// SameSnapshot reports whether two snapshots hold the same values
+func SameSnapshot(a, b *BudgetSnapshot) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	sameEnd := a.EndDate == nil && b.EndDate == nil || a.EndDate != nil && b.EndDate != nil && a.EndDate.Equal(*b.EndDate)
+	sameCategory := a.CategoryID == nil && b.CategoryID == nil || a.CategoryID != nil && b.CategoryID != nil && *a.CategoryID == *b.CategoryID
+	return sameCategory && sameEnd &&
+		slices.Equal(a.CategoryIDs, b.CategoryIDs) &&
+		a.Amount.Equal(b.Amount) &&
+		a.StartDate.Equal(b.StartDate) &&
+		a.Recurrence == b.Recurrence &&
+		a.Rollover == b.Rollover &&
+		slices.Equal(a.AlertThresholds, b.AlertThresholds)
+}
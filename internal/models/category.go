@@ -15,6 +15,11 @@ type Category struct {
 	Description string         `gorm:"type:text" json:"description"`                 // Optional description
 	ColorCode   string         `gorm:"size:7" json:"color_code"`                     // Optional color code (e.g., #FFFFFF)
 	IsDefault   bool           `gorm:"default:false" json:"is_default"`              // True if the category is default
+	ParentID    *uuid.UUID     `gorm:"type:uuid;index" json:"parent_id,omitempty"`   // Parent category (nullable for top-level categories)
+	Children    []Category     `gorm:"-" json:"children,omitempty"`                  // Subcategories, filled when listing as a tree
+	ArchivedAt  *time.Time     `gorm:"index" json:"archived_at,omitempty"`           // Set when the category is retired, e.g. merged into another
+	Hidden      bool           `gorm:"-" json:"hidden,omitempty"`                    // Default category hidden by the user, see CategoryPreference
+	SeedKey     *string        `gorm:"size:100;uniqueIndex" json:"seed_key,omitempty"` // Stable key of a default category in the seed manifests
 	CreatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt   time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`            // Soft delete
@@ -23,7 +28,47 @@ type Category struct {
 // FetchDefaultCategories retrieves all categories marked as default from the database.
 func FetchDefaultCategories(db *gorm.DB) ([]Category, error) {
 	var categories []Category
-	err := db.Where("is_default = ?", true).Find(&categories).Error
+	err := db.Where("is_default = ? AND archived_at IS NULL", true).Find(&categories).Error
 	return categories, err
 }
 
+// CategorySubtree returns a subquery selecting the ID of a category and of all its descendants, for use as
+// db.Where("category_id IN (?)", CategorySubtree(db, id))
+func CategorySubtree(db *gorm.DB, categoryID interface{}) *gorm.DB {
+	return db.Raw(`WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id WHERE c.deleted_at IS NULL
+		) SELECT id FROM subtree`, categoryID)
+}
+
+// FetchCategoryParents maps every category visible to the user (defaults and their own) that has a parent to that parent
+func FetchCategoryParents(db *gorm.DB, userID interface{}) (map[uuid.UUID]uuid.UUID, error) {
+	var categories []Category
+	err := db.Select("id, parent_id").
+		Where("(is_default = ? OR user_id = ?) AND parent_id IS NOT NULL", true, userID).
+		Find(&categories).Error
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[uuid.UUID]uuid.UUID, len(categories))
+	for _, category := range categories {
+		parents[category.ID] = *category.ParentID
+	}
+	return parents, nil
+}
+
+// CategoryAncestors returns the parent, grandparent and so on of a category, nearest first
+func CategoryAncestors(parents map[uuid.UUID]uuid.UUID, categoryID uuid.UUID) []uuid.UUID {
+	var ancestors []uuid.UUID
+	seen := map[uuid.UUID]bool{categoryID: true}
+	for {
+		parentID, ok := parents[categoryID]
+		if !ok || seen[parentID] {
+			return ancestors // Top level, or a cycle left by concurrent edits
+		}
+		ancestors = append(ancestors, parentID)
+		seen[parentID] = true
+		categoryID = parentID
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CategoryPreference is how one user sees a default category. Default categories are shared by every user,
+// so hiding or renaming one is recorded here instead of on the category row.
+type CategoryPreference struct {
+	UserID     uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	CategoryID uuid.UUID `gorm:"type:uuid;primaryKey" json:"category_id"`
+	Hidden     bool      `gorm:"not null;default:false" json:"hidden"` // Left out of the user's category list
+	Name       string    `gorm:"size:50" json:"name,omitempty"`        // Shown instead of the default name when set
+	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// FetchCategoryPreferences returns the user's preferences keyed by category
+func FetchCategoryPreferences(db *gorm.DB, userID interface{}) (map[uuid.UUID]CategoryPreference, error) {
+	var preferences []CategoryPreference
+	if err := db.Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+	byCategory := make(map[uuid.UUID]CategoryPreference, len(preferences))
+	for _, preference := range preferences {
+		byCategory[preference.CategoryID] = preference
+	}
+	return byCategory, nil
+}
+
+// Apply renames and flags the category as the user sees it
+func (p CategoryPreference) Apply(category *Category) {
+	if p.Name != "" {
+		category.Name = p.Name
+	}
+	category.Hidden = p.Hidden
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CategoryRule assigns CategoryID to expenses matching its conditions. A user's active rules are tried in
+// ascending Priority and the first match wins.
+type CategoryRule struct {
+	ID         uuid.UUID      `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"rule_id"`
+	UserID     uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name       string         `gorm:"size:100;not null" json:"name"`            // e.g. "Uber rides"
+	CategoryID uuid.UUID      `gorm:"type:uuid;not null" json:"category_id"`    // Category assigned on a match
+	Priority   int            `gorm:"not null;default:0" json:"priority"`       // Lower runs first
+	Match      string         `gorm:"size:3;not null;default:all" json:"match"` // "all" (AND) or "any" (OR) of the conditions
+	Conditions RuleConditions `gorm:"type:jsonb;not null" json:"conditions"`
+	Active     bool           `gorm:"not null" json:"active"` // Inactive rules are skipped
+	CreatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Soft delete
+}
+
+// RuleCondition tests one field of an expense, e.g. {"field": "description", "operator": "contains", "value": "UBER"}
+type RuleCondition struct {
+	Field    string `json:"field"`    // description, merchant, amount or currency
+	Operator string `json:"operator"` // contains, equals, starts_with, ends_with, regex; eq, lt, lte, gt, gte for amounts
+	Value    string `json:"value"`
+}
+
+// RuleConditions is stored as a JSON array
+type RuleConditions []RuleCondition
+
+// Value implements driver.Valuer
+func (c RuleConditions) Value() (driver.Value, error) {
+	if c == nil {
+		c = RuleConditions{}
+	}
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner
+func (c *RuleConditions) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = nil
+		return nil
+	}
+	return errors.New("unsupported type for rule conditions")
+}
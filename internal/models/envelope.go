@@ -0,0 +1,35 @@
+package models
+
+import (
+	"expense-mgmt/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EnvelopePlan is a zero-based budget for one period: the income declared for it is allocated across category
+// envelopes, each of them a Budget with EnvelopePlanID set. Income not allocated yet is the unassigned balance.
+type EnvelopePlan struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"plan_id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	StartDate time.Time      `gorm:"type:date;not null" json:"start_date"`
+	EndDate   time.Time      `gorm:"type:date;not null" json:"end_date"`
+	Income    money.Decimal  `gorm:"type:decimal(12,3);check:income >= 0;not null" json:"income"` // In the home currency
+	CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Soft delete
+}
+
+// EnvelopeTransfer records money moved between the envelopes of a plan. A nil category is the unassigned
+// balance, so allocating income is a transfer from it and releasing an envelope a transfer back to it.
+type EnvelopeTransfer struct {
+	ID             uuid.UUID     `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"transfer_id"`
+	PlanID         uuid.UUID     `gorm:"type:uuid;not null;index" json:"plan_id"`
+	UserID         uuid.UUID     `gorm:"type:uuid;not null" json:"user_id"`
+	FromCategoryID *uuid.UUID    `gorm:"type:uuid" json:"from_category_id"` // Nil for the unassigned balance
+	ToCategoryID   *uuid.UUID    `gorm:"type:uuid" json:"to_category_id"`   // Nil for the unassigned balance
+	Amount         money.Decimal `gorm:"type:decimal(12,3);check:amount > 0;not null" json:"amount"`
+	Note           string        `gorm:"size:255" json:"note,omitempty"`
+	CreatedAt      time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
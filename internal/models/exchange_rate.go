@@ -0,0 +1,20 @@
+package models
+
+import (
+	"expense-mgmt/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExchangeRate is the value of one unit of Base expressed in Quote on a given day
+type ExchangeRate struct {
+	ID        uuid.UUID     `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"exchange_rate_id"`
+	Base      string        `gorm:"type:char(3);not null;uniqueIndex:idx_exchange_rates_pair_date" json:"base"`
+	Quote     string        `gorm:"type:char(3);not null;uniqueIndex:idx_exchange_rates_pair_date" json:"quote"`
+	Date      time.Time     `gorm:"type:date;not null;uniqueIndex:idx_exchange_rates_pair_date" json:"date"`
+	Rate      money.Decimal `gorm:"type:decimal(18,8);not null;check:rate > 0" json:"rate"`
+	Source    string        `gorm:"size:50" json:"source"` // Provider the rate was loaded from (file, http)
+	CreatedAt time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
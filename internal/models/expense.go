@@ -1,6 +1,7 @@
 package models
 
 import (
+	"expense-mgmt/internal/money"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,26 +9,30 @@ import (
 
 // Expense represents an individual expense entry associated with a user and category
 type Expense struct {
-	ExpenseID          uuid.UUID     `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"expense_id"`
-	UserID             uuid.UUID     `gorm:"type:uuid;not null" json:"user_id"`
-	CategoryID         uuid.UUID     `gorm:"type:uuid;not null" json:"category_id"`
-	Amount             float64       `gorm:"type:decimal(10,2);not null" json:"amount"`
-	Date               time.Time     `gorm:"type:timestamp;not null" json:"date"`
-	Description        string        `gorm:"type:text" json:"description"`
-	ReceiptID          *uuid.UUID    `gorm:"type:uuid" json:"receipt_id"`
-	CreatedAt          time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt          time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ExpenseID          uuid.UUID           `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"expense_id"`
+	UserID             uuid.UUID           `gorm:"type:uuid;not null" json:"user_id"`
+	CategoryID         uuid.UUID           `gorm:"type:uuid;not null" json:"category_id"`
+	Amount             money.Decimal       `gorm:"type:decimal(12,3);not null" json:"amount"`           // In the user's home currency
+	OriginalAmount     *money.Decimal      `gorm:"type:decimal(12,3)" json:"original_amount,omitempty"` // Amount as paid, in Currency
+	Currency           string              `gorm:"type:char(3)" json:"currency,omitempty"`              // ISO-4217 currency the expense was paid in
+	Date               time.Time           `gorm:"type:timestamp;not null" json:"date"`
+	Description        string              `gorm:"type:text" json:"description"`
+	Merchant           string              `gorm:"size:255" json:"merchant,omitempty"` // Where the money was spent, e.g. from a receipt
+	ReceiptID          *uuid.UUID          `gorm:"type:uuid" json:"receipt_id"`
+	RecurringExpenseID *uuid.UUID          `gorm:"type:uuid;index" json:"recurring_expense_id,omitempty"` // Template that generated this expense (nullable)
+	Allocations        []ExpenseAllocation `gorm:"-" json:"allocations,omitempty"`                        // Split across categories; empty when the whole amount goes to CategoryID
+	TagIDs             []uuid.UUID         `gorm:"-" json:"tag_ids,omitempty"`                            // Tags to set on create or update
+	Tags               []Tag               `gorm:"-" json:"tags,omitempty"`                               // Tags of the expense, filled in responses
+	CreatedAt          time.Time           `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt          time.Time           `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
-
-
 // ExpenseAnalysisResult represents the result of the expense analysis query.
 type ExpenseAnalysisResult struct {
-	Period            string             `json:"period"`
-	TotalSpending     float64            `json:"total_spending"`
-	AverageSpending   float64            `json:"average_spending"`
-	CategoryBreakdown map[string]float64 `json:"category_breakdown,omitempty"`
-	HighestExpense    float64            `json:"highest_expense,omitempty"`
-	RecurringTotal    float64            `json:"recurring_total,omitempty"`
+	Period            string                   `json:"period"`
+	TotalSpending     money.Decimal            `json:"total_spending"`
+	AverageSpending   money.Decimal            `json:"average_spending"`
+	CategoryBreakdown map[string]money.Decimal `json:"category_breakdown,omitempty"`
+	HighestExpense    money.Decimal            `json:"highest_expense,omitempty"`
+	RecurringTotal    money.Decimal            `json:"recurring_total,omitempty"`
 }
-
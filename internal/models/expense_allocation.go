@@ -0,0 +1,34 @@
+package models
+
+import (
+	"expense-mgmt/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ExpenseAllocation is the part of a split expense attributed to one category.
+// The allocations of an expense always sum to Expense.Amount.
+type ExpenseAllocation struct {
+	ID          uuid.UUID     `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"allocation_id"`
+	ExpenseID   uuid.UUID     `gorm:"type:uuid;not null;index" json:"expense_id"` // Foreign key to the Expense
+	CategoryID  uuid.UUID     `gorm:"type:uuid;not null;index" json:"category_id"`
+	Amount      money.Decimal `gorm:"type:decimal(12,3);not null" json:"amount"`
+	Description string        `gorm:"type:text" json:"description"` // Optional note, e.g. the items covered
+	CreatedAt   time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time     `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// SpendingLines returns a query over the "expenses" relation with one row per attributed amount: a split expense
+// contributes one row per allocation, identified by allocation_id, and any other expense contributes itself.
+// Aggregating category_id and amount over it attributes split expenses to their own categories; expense_amount,
+// original_amount and currency describe the whole expense so a line can be converted into another currency.
+func SpendingLines(db *gorm.DB) *gorm.DB {
+	lines := db.Table("expenses AS e").
+		Select(`e.expense_id, e.user_id, e.date, e.description, e.receipt_id, e.recurring_expense_id,
+			e.amount AS expense_amount, e.original_amount, e.currency,
+			a.id AS allocation_id, COALESCE(a.category_id, e.category_id) AS category_id, COALESCE(a.amount, e.amount) AS amount`).
+		Joins("LEFT JOIN expense_allocations a ON a.expense_id = e.expense_id")
+	return db.Table("(?) AS expenses", lines)
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of notifications
+const (
+	NotificationBudgetAlert = "budget_alert"
+)
+
+// Notification is a message in a user's in-app inbox
+type Notification struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"notification_id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Kind      string     `gorm:"size:30;not null" json:"kind"` // What the notification is about, e.g. budget_alert
+	Title     string     `gorm:"size:255;not null" json:"title"`
+	Message   string     `gorm:"type:text" json:"message"`
+	BudgetID  *uuid.UUID `gorm:"type:uuid" json:"budget_id,omitempty"` // Budget a budget alert is about
+	ReadAt    *time.Time `json:"read_at"`                              // Nil until the user reads it
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
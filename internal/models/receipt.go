@@ -3,17 +3,36 @@ package models
 import (
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Receipt OCR processing statuses
+const (
+	ReceiptStatusPending    = "pending"
+	ReceiptStatusProcessing = "processing"
+	ReceiptStatusDone       = "done"
+	ReceiptStatusFailed     = "failed"
+)
+
 // Receipt represents a receipt linked to a specific expense, with OCR and image metadata
 type Receipt struct {
-	ID         string         `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"receipt_id"`
-	ImageURL   string         `gorm:"type:text;not null" json:"image_url"`              // URL or path to the stored image
-	OCRData    string         `gorm:"type:text" json:"ocr_data"`                        // Text extracted from the receipt via OCR
-	ScannedDate time.Time     `gorm:"type:timestamp with time zone;default:CURRENT_TIMESTAMP" json:"scanned_date"`
-	ExpenseID  *string        `gorm:"type:uuid" json:"expense_id,omitempty"`            // Foreign key to the Expense (nullable)
-	Date  			time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt  time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Soft delete
-}
\ No newline at end of file
+	ID            string         `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"receipt_id"`
+	UserID        uuid.UUID      `gorm:"type:uuid;index" json:"user_id"`        // Owner of the receipt
+	ImageURL      string         `gorm:"type:text;not null" json:"image_url"`   // URL or path to the stored image
+	StorageKey    string         `gorm:"type:text" json:"-"`                    // Key of the image in the blob store
+	FileName      string         `gorm:"size:255" json:"file_name"`             // Original file name sent by the client
+	ContentType   string         `gorm:"size:100" json:"content_type"`          // Detected MIME type of the image
+	FileSize      int64          `json:"file_size"`                             // Size of the image in bytes
+	OCRData       string         `gorm:"type:text" json:"ocr_data"`             // Text extracted from the receipt via OCR
+	Status        string         `gorm:"size:20;index" json:"status"`           // OCR processing status (pending, processing, done, failed)
+	Attempts      int            `gorm:"default:0" json:"attempts"`             // Number of OCR attempts made so far
+	LastError     string         `gorm:"type:text" json:"last_error,omitempty"` // Error of the last failed OCR attempt
+	NextAttemptAt *time.Time     `json:"next_attempt_at,omitempty"`             // Earliest time the next OCR attempt may run
+	ProcessedAt   *time.Time     `json:"processed_at,omitempty"`                // When OCR finished successfully
+	ScannedDate   time.Time      `gorm:"type:timestamp with time zone;default:CURRENT_TIMESTAMP" json:"scanned_date"`
+	ExpenseID     *string        `gorm:"type:uuid" json:"expense_id,omitempty"` // Foreign key to the Expense (nullable)
+	Date          time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Soft delete
+}
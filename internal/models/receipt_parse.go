@@ -0,0 +1,41 @@
+package models
+
+import (
+	"expense-mgmt/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReceiptParse holds the structured fields parsed from a receipt's OCR text, each with a confidence score between 0 and 1
+type ReceiptParse struct {
+	ID                 uuid.UUID         `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"parse_id"`
+	ReceiptID          string            `gorm:"type:uuid;not null;uniqueIndex" json:"receipt_id"` // Foreign key to the Receipt
+	Merchant           string            `gorm:"size:255" json:"merchant"`
+	MerchantConfidence float64           `json:"merchant_confidence"`
+	TransactionDate    *time.Time        `gorm:"type:date" json:"transaction_date"`
+	DateConfidence     float64           `json:"date_confidence"`
+	Subtotal           *money.Decimal    `gorm:"type:decimal(12,3)" json:"subtotal"`
+	SubtotalConfidence float64           `json:"subtotal_confidence"`
+	Tax                *money.Decimal    `gorm:"type:decimal(12,3)" json:"tax"`
+	TaxConfidence      float64           `json:"tax_confidence"`
+	Total              *money.Decimal    `gorm:"type:decimal(12,3)" json:"total"`
+	TotalConfidence    float64           `json:"total_confidence"`
+	Currency           string            `gorm:"size:3" json:"currency"` // ISO-4217 code, empty when unknown
+	CurrencyConfidence float64           `json:"currency_confidence"`
+	LineItems          []ReceiptLineItem `gorm:"-" json:"line_items"`
+	CreatedAt          time.Time         `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt          time.Time         `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// ReceiptLineItem is a single purchased item parsed from a receipt
+type ReceiptLineItem struct {
+	ID          uuid.UUID      `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"line_item_id"`
+	ReceiptID   string         `gorm:"type:uuid;not null;index" json:"receipt_id"` // Foreign key to the Receipt
+	Position    int            `gorm:"not null" json:"position"`                   // Order of the item on the receipt
+	Description string         `gorm:"type:text" json:"description"`
+	Quantity    money.Decimal  `gorm:"type:decimal(10,3);default:1" json:"quantity"`
+	UnitPrice   *money.Decimal `gorm:"type:decimal(12,3)" json:"unit_price,omitempty"`
+	Amount      money.Decimal  `gorm:"type:decimal(12,3);not null" json:"amount"`
+	Confidence  float64        `json:"confidence"`
+}
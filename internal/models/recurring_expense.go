@@ -0,0 +1,27 @@
+package models
+
+import (
+	"expense-mgmt/internal/money"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecurringExpense is a template that materializes real expenses on an RRULE-style schedule
+type RecurringExpense struct {
+	ID              uuid.UUID      `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"recurring_expense_id"`
+	UserID          uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	CategoryID      uuid.UUID      `gorm:"type:uuid;not null" json:"category_id"`
+	Amount          money.Decimal  `gorm:"type:decimal(12,3);not null" json:"amount"`
+	Description     string         `gorm:"type:text" json:"description"`
+	Rule            string         `gorm:"size:255;not null" json:"rule"`          // e.g. FREQ=MONTHLY;BYMONTHDAY=1;COUNT=12
+	StartDate       time.Time      `gorm:"type:date;not null" json:"start_date"`   // Anchor of the schedule and first possible occurrence
+	NextOccurrence  *time.Time     `gorm:"type:date;index" json:"next_occurrence"` // Next date to materialize, nil once the schedule ended
+	OccurrenceCount int            `gorm:"default:0" json:"occurrence_count"`      // Expenses materialized from this template so far
+	SeriesID        uuid.UUID      `gorm:"type:uuid;index" json:"series_id"`       // Shared by the templates produced by "this and future" edits
+	Active          bool           `gorm:"default:true" json:"active"`             // Inactive templates are never materialized
+	CreatedAt       time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"` // Soft delete
+}
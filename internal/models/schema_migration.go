@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// SchemaMigration records a schema migration that has been applied, see db/migrations
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Name      string    `gorm:"type:text;not null" json:"name"`
+	Checksum  string    `gorm:"size:64;not null" json:"checksum"` // SHA-256 of the up step as applied
+	AppliedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"applied_at"`
+}
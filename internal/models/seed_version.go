@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// SeedVersion records a seed manifest that has been applied, so each manifest runs once per database
+type SeedVersion struct {
+	Version     int       `gorm:"primaryKey;autoIncrement:false" json:"version"`
+	Description string    `gorm:"type:text" json:"description"`
+	Checksum    string    `gorm:"size:64;not null" json:"checksum"` // SHA-256 of the manifest as applied
+	AppliedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"applied_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tag is a free-form user label such as "wedding" or "work-trip-oct"; unlike categories an expense can carry many
+type Tag struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"tag_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name      string    `gorm:"size:50;not null" json:"name"` // Unique per user, case-insensitively (idx_tags_user_name)
+	ColorCode string    `gorm:"size:7" json:"color_code"`     // Optional color code (e.g., #FFFFFF)
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// ExpenseTag links an expense to one of its owner's tags
+type ExpenseTag struct {
+	ExpenseID uuid.UUID `gorm:"type:uuid;primaryKey" json:"expense_id"`
+	TagID     uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"tag_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
@@ -17,5 +17,5 @@ type User struct {
 	CreatedAt         time.Time `gorm:"autoCreateTime" json:"created_at"`
 	ResetPasswordToken string    `gorm:"size:255" json:"-"`
 	ResetPasswordExpires time.Time `json:"reset_password_expires"`
-	Currency          string    `gorm:"type:char(3);default:CAD;check:currency in ('CAD', 'USD')" json:"currency"`
+	Currency          string    `gorm:"type:char(3);default:CAD;check:currency ~ '^[A-Z]{3}$'" json:"currency"` // ISO-4217 home currency
 }
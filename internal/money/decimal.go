@@ -0,0 +1,300 @@
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number: an arbitrary-precision integer coefficient scaled by a power of ten.
+// The zero value is 0 and every operation returns a new value, so Decimals can be copied freely.
+type Decimal struct {
+	coef  *big.Int // nil means zero
+	scale int32    // Digits after the decimal point
+}
+
+// ErrInvalidDecimal is returned when a value cannot be read as a decimal number
+var ErrInvalidDecimal = errors.New("invalid decimal number")
+
+var (
+	bigOne = big.NewInt(1)
+	bigTen = big.NewInt(10)
+)
+
+// Zero is the decimal 0
+var Zero = Decimal{}
+
+// New returns coef × 10^-scale, e.g. New(1999, 2) is 19.99
+func New(coef int64, scale int32) Decimal {
+	if scale < 0 {
+		return Decimal{coef: new(big.Int).Mul(big.NewInt(coef), pow10(-scale))}
+	}
+	return Decimal{coef: big.NewInt(coef), scale: scale}
+}
+
+// NewFromInt returns the integer as a decimal
+func NewFromInt(value int64) Decimal {
+	return New(value, 0)
+}
+
+// NewFromFloat returns the shortest decimal that reads back as the float, e.g. 0.1 becomes exactly 0.1.
+// Use it only at the boundary with code that still produces floats.
+func NewFromFloat(value float64) Decimal {
+	d, err := Parse(strconv.FormatFloat(value, 'f', -1, 64))
+	if err != nil {
+		return Zero // NaN and infinities have no decimal representation
+	}
+	return d
+}
+
+// Parse reads a plain decimal such as "-1234.50"; exponents are not accepted
+func Parse(value string) (Decimal, error) {
+	value = strings.TrimSpace(value)
+	digits := strings.TrimLeft(value, "+-")
+	if digits == "" || len(value)-len(digits) > 1 {
+		return Zero, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+	}
+
+	intPart, fracPart, _ := strings.Cut(digits, ".")
+	if intPart == "" && fracPart == "" {
+		return Zero, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+	}
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return Zero, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+		}
+	}
+
+	coef, _ := new(big.Int).SetString("0"+intPart+fracPart, 10)
+	if strings.HasPrefix(value, "-") {
+		coef.Neg(coef)
+	}
+	return Decimal{coef: coef, scale: int32(len(fracPart))}, nil
+}
+
+// MustParse is like Parse but panics on invalid input; meant for constants
+func MustParse(value string) Decimal {
+	d, err := Parse(value)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) coefficient() *big.Int {
+	if d.coef == nil {
+		return new(big.Int)
+	}
+	return d.coef
+}
+
+// rescale returns the coefficient expressed with a larger scale
+func (d Decimal) rescale(scale int32) *big.Int {
+	coef := d.coefficient()
+	if scale == d.scale {
+		return coef
+	}
+	return new(big.Int).Mul(coef, pow10(scale-d.scale))
+}
+
+// Add returns d + other
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	return Decimal{coef: new(big.Int).Add(d.rescale(scale), other.rescale(scale)), scale: scale}
+}
+
+// Sub returns d - other
+func (d Decimal) Sub(other Decimal) Decimal {
+	return d.Add(other.Neg())
+}
+
+// Mul returns d × other, exactly
+func (d Decimal) Mul(other Decimal) Decimal {
+	return Decimal{coef: new(big.Int).Mul(d.coefficient(), other.coefficient()), scale: d.scale + other.scale}
+}
+
+// Div returns d ÷ other rounded to the given number of decimal places. It panics if other is zero,
+// like integer division does.
+func (d Decimal) Div(other Decimal, places int32, mode RoundingMode) Decimal {
+	if other.IsZero() {
+		panic("money: division by zero")
+	}
+	// d/other = (coef_d × 10^-scale_d) / (coef_o × 10^-scale_o); shift so the quotient has the wanted places
+	num := new(big.Int).Set(d.coefficient())
+	den := new(big.Int).Set(other.coefficient())
+	shift := places + other.scale - d.scale
+	if shift >= 0 {
+		num.Mul(num, pow10(shift))
+	} else {
+		den.Mul(den, pow10(-shift))
+	}
+	return Decimal{coef: roundQuo(num, den, mode), scale: places}
+}
+
+// Round returns d rounded to the given number of decimal places; fewer places are padded with zeros
+func (d Decimal) Round(places int32, mode RoundingMode) Decimal {
+	if places < 0 {
+		places = 0
+	}
+	if places >= d.scale {
+		return Decimal{coef: d.rescale(places), scale: places}
+	}
+	return Decimal{coef: roundQuo(d.coefficient(), pow10(d.scale-places), mode), scale: places}
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	return Decimal{coef: new(big.Int).Neg(d.coefficient()), scale: d.scale}
+}
+
+// Abs returns |d|
+func (d Decimal) Abs() Decimal {
+	return Decimal{coef: new(big.Int).Abs(d.coefficient()), scale: d.scale}
+}
+
+// Sign returns -1, 0 or 1
+func (d Decimal) Sign() int {
+	return d.coefficient().Sign()
+}
+
+// IsZero reports whether d is 0
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and other and returns -1, 0 or 1
+func (d Decimal) Cmp(other Decimal) int {
+	scale := max(d.scale, other.scale)
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// Equal reports whether d and other are the same number, regardless of scale
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// LessThan reports whether d < other
+func (d Decimal) LessThan(other Decimal) bool {
+	return d.Cmp(other) < 0
+}
+
+// GreaterThan reports whether d > other
+func (d Decimal) GreaterThan(other Decimal) bool {
+	return d.Cmp(other) > 0
+}
+
+// Float64 returns the nearest float; only for ratios shown to people, never for further money arithmetic
+func (d Decimal) Float64() float64 {
+	value, _ := strconv.ParseFloat(d.String(), 64)
+	return value
+}
+
+// String formats d in plain notation with all of its decimal places, e.g. "-12.50"
+func (d Decimal) String() string {
+	coef := d.coefficient()
+	digits := new(big.Int).Abs(coef).String()
+	if d.scale > 0 {
+		if pad := int(d.scale) + 1 - len(digits); pad > 0 {
+			digits = strings.Repeat("0", pad) + digits
+		}
+		digits = digits[:len(digits)-int(d.scale)] + "." + digits[len(digits)-int(d.scale):]
+	}
+	if coef.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// MarshalJSON writes d as a JSON number so API clients keep receiving numbers
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a numeric string; null leaves d unchanged
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	raw := string(bytes.Trim(data, `"`))
+	mantissa, exponent, found := strings.Cut(strings.ToLower(raw), "e")
+	parsed, err := Parse(mantissa)
+	if err != nil {
+		return err
+	}
+	if found {
+		// Exponent notation, e.g. 1e3: bounded so that a short body such as 1e10000000 cannot expand into
+		// millions of digits
+		exp, err := strconv.Atoi(strings.TrimPrefix(exponent, "+"))
+		if err != nil || exp > maxExponent || exp < -maxExponent || significantDigits(parsed) > maxSignificantDigits {
+			return fmt.Errorf("%w: %s", ErrInvalidDecimal, data)
+		}
+		parsed = parsed.shift(exp)
+	}
+	*d = parsed
+	return nil
+}
+
+// Limits on JSON numbers written with an exponent
+const (
+	maxExponent          = 30
+	maxSignificantDigits = 40
+)
+
+// significantDigits counts the digits of d without leading zeros
+func significantDigits(d Decimal) int {
+	if d.coef == nil || d.coef.Sign() == 0 {
+		return 0
+	}
+	return len(new(big.Int).Abs(d.coef).String())
+}
+
+// shift returns d × 10^exp
+func (d Decimal) shift(exp int) Decimal {
+	if d.coef == nil {
+		return Zero
+	}
+	scale := d.scale - int32(exp)
+	if scale < 0 {
+		return Decimal{coef: new(big.Int).Mul(d.coef, pow10(-scale))}
+	}
+	return Decimal{coef: d.coef, scale: scale}
+}
+
+// Scan reads a NUMERIC column
+func (d *Decimal) Scan(value interface{}) error {
+	var parsed Decimal
+	var err error
+	switch v := value.(type) {
+	case nil:
+		parsed = Zero
+	case []byte:
+		parsed, err = Parse(string(v))
+	case string:
+		parsed, err = Parse(v)
+	case int64:
+		parsed = NewFromInt(v)
+	case float64:
+		parsed = NewFromFloat(v)
+	default:
+		return fmt.Errorf("money: cannot scan %T into Decimal", value)
+	}
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value writes d as text so the database parses it exactly
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// pow10 returns 10^n
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(int64(n)), nil)
+}
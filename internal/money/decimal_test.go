@@ -0,0 +1,279 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"12.50", "12.50"},
+		{"-0.5", "-0.5"},
+		{"+3", "3"},
+		{".5", "0.5"},
+		{"5.", "5"},
+		{" 7.25 ", "7.25"},
+		{"0.00", "0.00"},
+		{"-0.00", "0.00"},
+		{"007.10", "7.10"},
+		{"123456789012345678901234567890.123", "123456789012345678901234567890.123"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{"", " ", "-", "+-1", "--1", ".", "1.2.3", "1e3", "abc", "1,50", "12 50"} {
+		if _, err := Parse(input); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalidDecimal", input, err)
+		}
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"New", New(1999, 2), "19.99"},
+		{"New negative scale", New(5, -2), "500"},
+		{"New negative", New(-5, 3), "-0.005"},
+		{"NewFromInt", NewFromInt(-42), "-42"},
+		{"NewFromFloat exact", NewFromFloat(0.1), "0.1"},
+		{"NewFromFloat integer", NewFromFloat(250), "250"},
+		{"Zero", Zero, "0"},
+	}
+	for _, tt := range tests {
+		if tt.got.String() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestArithmeticScale(t *testing.T) {
+	tests := []struct {
+		name string
+		got  Decimal
+		want string
+	}{
+		{"Add keeps the larger scale", MustParse("1.5").Add(MustParse("2.25")), "3.75"},
+		{"Add integer", MustParse("1.10").Add(NewFromInt(2)), "3.10"},
+		{"Add to zero value", Zero.Add(MustParse("0.01")), "0.01"},
+		{"Sub below zero", NewFromInt(1).Sub(MustParse("2.50")), "-1.50"},
+		{"Sub negative", MustParse("-1.25").Sub(MustParse("-0.25")), "-1.00"},
+		{"Mul adds scales", MustParse("1.5").Mul(MustParse("1.5")), "2.25"},
+		{"Mul integer", MustParse("0.10").Mul(NewFromInt(3)), "0.30"},
+		{"Mul negatives", MustParse("-0.2").Mul(MustParse("-0.3")), "0.06"},
+		{"Mul sign", MustParse("-2.5").Mul(NewFromInt(4)), "-10.0"},
+		{"Neg", MustParse("3.10").Neg(), "-3.10"},
+		{"Abs", MustParse("-3.10").Abs(), "3.10"},
+		{"Sums stay exact", MustParse("0.1").Add(MustParse("0.2")), "0.3"},
+	}
+	for _, tt := range tests {
+		if tt.got.String() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.10", "1.1", 0},
+		{"0", "0.00", 0},
+		{"-0.01", "0", -1},
+		{"2", "1.999", 1},
+		{"-2", "-1.999", -1},
+	}
+	for _, tt := range tests {
+		a, b := MustParse(tt.a), MustParse(tt.b)
+		if got := a.Cmp(b); got != tt.want {
+			t.Errorf("Cmp(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := a.Equal(b); got != (tt.want == 0) {
+			t.Errorf("Equal(%s, %s) = %v", tt.a, tt.b, got)
+		}
+		if got := a.LessThan(b); got != (tt.want < 0) {
+			t.Errorf("LessThan(%s, %s) = %v", tt.a, tt.b, got)
+		}
+		if got := a.GreaterThan(b); got != (tt.want > 0) {
+			t.Errorf("GreaterThan(%s, %s) = %v", tt.a, tt.b, got)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value  string
+		places int32
+		mode   RoundingMode
+		want   string
+	}{
+		// A tie on an even digit
+		{"2.345", 2, HalfEven, "2.34"},
+		{"2.345", 2, HalfUp, "2.35"},
+		{"2.345", 2, HalfDown, "2.34"},
+		{"2.345", 2, Down, "2.34"},
+		{"2.345", 2, Up, "2.35"},
+		{"2.345", 2, Floor, "2.34"},
+		{"2.345", 2, Ceiling, "2.35"},
+		// A tie on an odd digit
+		{"2.355", 2, HalfEven, "2.36"},
+		{"2.355", 2, HalfDown, "2.35"},
+		// Above and below one half
+		{"2.346", 2, HalfDown, "2.35"},
+		{"2.344", 2, HalfUp, "2.34"},
+		{"2.341", 2, Up, "2.35"},
+		{"2.349", 2, Down, "2.34"},
+		// Negative values round symmetrically, except Floor and Ceiling
+		{"-2.345", 2, HalfEven, "-2.34"},
+		{"-2.345", 2, HalfUp, "-2.35"},
+		{"-2.345", 2, HalfDown, "-2.34"},
+		{"-2.345", 2, Down, "-2.34"},
+		{"-2.345", 2, Up, "-2.35"},
+		{"-2.345", 2, Floor, "-2.35"},
+		{"-2.345", 2, Ceiling, "-2.34"},
+		{"-2.341", 2, Floor, "-2.35"},
+		{"-2.349", 2, Ceiling, "-2.34"},
+		// Whole units, e.g. JPY
+		{"0.5", 0, HalfEven, "0"},
+		{"1.5", 0, HalfEven, "2"},
+		{"-1.5", 0, HalfEven, "-2"},
+		{"1.5", 0, HalfUp, "2"},
+		{"2.5", -1, HalfEven, "2"}, // Negative places round to whole units
+		// More places pad with zeros
+		{"1.5", 2, HalfEven, "1.50"},
+		{"7", 3, Down, "7.000"},
+		{"1.25", 2, Up, "1.25"},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.value).Round(tt.places, tt.mode); got.String() != tt.want {
+			t.Errorf("Round(%s, %d, %d) = %s, want %s", tt.value, tt.places, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+	tests := []struct {
+		a, b   string
+		places int32
+		mode   RoundingMode
+		want   string
+	}{
+		{"10", "3", 2, HalfEven, "3.33"},
+		{"2", "3", 2, HalfEven, "0.67"},
+		{"-2", "3", 2, HalfEven, "-0.67"},
+		{"2", "-3", 2, HalfEven, "-0.67"},
+		{"-2", "-3", 2, HalfEven, "0.67"},
+		{"1", "8", 2, HalfEven, "0.12"},
+		{"1", "8", 2, HalfUp, "0.13"},
+		{"1", "-8", 2, HalfUp, "-0.13"},
+		{"1", "-8", 2, Floor, "-0.13"},
+		{"1", "-8", 2, Ceiling, "-0.12"},
+		{"100", "0.25", 2, HalfEven, "400.00"},
+		{"1.00", "3", 0, HalfEven, "0"},
+		{"0.10", "0.03", 4, HalfEven, "3.3333"},
+		{"123.456", "1", 1, HalfEven, "123.5"},
+		{"0", "7", 2, HalfEven, "0.00"},
+	}
+	for _, tt := range tests {
+		got := MustParse(tt.a).Div(MustParse(tt.b), tt.places, tt.mode)
+		if got.String() != tt.want {
+			t.Errorf("%s / %s (%d places, mode %d) = %s, want %s", tt.a, tt.b, tt.places, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDivByZero(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Div by zero did not panic")
+		}
+	}()
+	NewFromInt(1).Div(MustParse("0.00"), 2, HalfEven)
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`10.10`, "10.10"},
+		{`"10.10"`, "10.10"},
+		{`-3`, "-3"},
+		{`1e3`, "1000"},
+		{`1.5E-2`, "0.015"},
+		{`"2.5e+1"`, "25"},
+		{`1e30`, "1000000000000000000000000000000"},
+	}
+	for _, tt := range tests {
+		var d Decimal
+		if err := d.UnmarshalJSON([]byte(tt.input)); err != nil {
+			t.Errorf("UnmarshalJSON(%s) returned error: %v", tt.input, err)
+			continue
+		}
+		if d.String() != tt.want {
+			t.Errorf("UnmarshalJSON(%s) = %s, want %s", tt.input, d, tt.want)
+		}
+		encoded, _ := d.MarshalJSON()
+		if string(encoded) != tt.want {
+			t.Errorf("MarshalJSON(%s) = %s, want %s", d, encoded, tt.want)
+		}
+	}
+
+	d := MustParse("5.00")
+	if err := d.UnmarshalJSON([]byte("null")); err != nil || d.String() != "5.00" {
+		t.Errorf("UnmarshalJSON(null) changed the value to %s (error %v)", d, err)
+	}
+	if err := d.UnmarshalJSON([]byte(`"ten"`)); !errors.Is(err, ErrInvalidDecimal) {
+		t.Errorf("UnmarshalJSON(\"ten\") error = %v, want ErrInvalidDecimal", err)
+	}
+	// Exponents and digit counts are bounded before the number is expanded
+	for _, input := range []string{`1e10000000`, `1e31`, `1e-31`, `1.2345678901234567890123456789012345678901e2`, `1e`, `1e1.5`} {
+		if err := d.UnmarshalJSON([]byte(input)); !errors.Is(err, ErrInvalidDecimal) {
+			t.Errorf("UnmarshalJSON(%s) error = %v, want ErrInvalidDecimal", input, err)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{[]byte("12.30"), "12.30"},
+		{"-0.05", "-0.05"},
+		{int64(7), "7"},
+		{0.25, "0.25"},
+		{nil, "0"},
+	}
+	for _, tt := range tests {
+		var d Decimal
+		if err := d.Scan(tt.value); err != nil {
+			t.Errorf("Scan(%v) returned error: %v", tt.value, err)
+			continue
+		}
+		if d.String() != tt.want {
+			t.Errorf("Scan(%v) = %s, want %s", tt.value, d, tt.want)
+		}
+	}
+
+	var d Decimal
+	if err := d.Scan(true); err == nil {
+		t.Error("Scan(bool) did not return an error")
+	}
+}
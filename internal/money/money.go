@@ -0,0 +1,101 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCurrencyMismatch is returned when amounts in different currencies are combined
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// minorUnits lists the ISO-4217 currencies that do not use two decimal places
+var minorUnits = map[string]int32{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+}
+
+// MinorUnits returns the number of decimal places used by a currency, e.g. 2 for CAD and 0 for JPY
+func MinorUnits(currency string) int32 {
+	if places, ok := minorUnits[currency]; ok {
+		return places
+	}
+	return 2
+}
+
+// Money is an exact amount in a given ISO-4217 currency
+type Money struct {
+	Amount   Decimal `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// NewMoney returns the amount in the currency
+func NewMoney(amount Decimal, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// Add returns m + other; both must be in the same currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount.Add(other.Amount), Currency: m.Currency}, nil
+}
+
+// Sub returns m - other; both must be in the same currency
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s - %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount.Sub(other.Amount), Currency: m.Currency}, nil
+}
+
+// Round rounds the amount to the currency's minor units
+func (m Money) Round(mode RoundingMode) Money {
+	return Money{Amount: m.Amount.Round(MinorUnits(m.Currency), mode), Currency: m.Currency}
+}
+
+// Convert multiplies by the rate from m's currency to another one and rounds to the new currency's minor units
+func (m Money) Convert(rate Decimal, currency string, mode RoundingMode) Money {
+	return Money{Amount: m.Amount.Mul(rate), Currency: currency}.Round(mode)
+}
+
+// String formats the money as "12.50 CAD"
+func (m Money) String() string {
+	return m.Amount.String() + " " + m.Currency
+}
+
+// Allocate splits total in proportion to the weights, rounding each share to the given places.
+// The shares always add up to total exactly: the rounding difference goes to the largest weight.
+func Allocate(total Decimal, weights []Decimal, places int32, mode RoundingMode) []Decimal {
+	shares := make([]Decimal, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	sum := Zero
+	largest := 0
+	for i, weight := range weights {
+		sum = sum.Add(weight)
+		if weight.GreaterThan(weights[largest]) {
+			largest = i
+		}
+	}
+	if sum.IsZero() {
+		shares[largest] = total.Round(places, mode)
+		for i := range shares {
+			if i != largest {
+				shares[i] = Zero.Round(places, mode)
+			}
+		}
+		return shares
+	}
+
+	allocated := Zero
+	for i, weight := range weights {
+		shares[i] = total.Mul(weight).Div(sum, places, mode)
+		allocated = allocated.Add(shares[i])
+	}
+	shares[largest] = shares[largest].Add(total.Round(places, mode).Sub(allocated))
+	return shares
+}
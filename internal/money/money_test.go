@@ -0,0 +1,105 @@
+package money
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMinorUnits(t *testing.T) {
+	tests := map[string]int32{"CAD": 2, "USD": 2, "JPY": 0, "KRW": 0, "KWD": 3, "BHD": 3}
+	for currency, want := range tests {
+		if got := MinorUnits(currency); got != want {
+			t.Errorf("MinorUnits(%s) = %d, want %d", currency, got, want)
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount string
+		from   string
+		rate   string
+		to     string
+		mode   RoundingMode
+		want   string
+	}{
+		{"100.00", "USD", "1.3456", "CAD", HalfEven, "134.56"},
+		{"10.00", "USD", "149.555", "JPY", HalfEven, "1496"},
+		{"1000", "JPY", "0.0091", "CAD", HalfEven, "9.10"},
+		{"1.00", "CAD", "0.2255", "KWD", HalfEven, "0.226"},
+		{"0.05", "USD", "1.5", "CAD", HalfEven, "0.08"},
+		{"0.05", "USD", "1.5", "CAD", Down, "0.07"},
+		{"-20.00", "EUR", "1.4725", "CAD", HalfEven, "-29.45"},
+	}
+	for _, tt := range tests {
+		got := NewMoney(MustParse(tt.amount), tt.from).Convert(MustParse(tt.rate), tt.to, tt.mode)
+		if got.Currency != tt.to || got.Amount.String() != tt.want {
+			t.Errorf("%s %s at %s = %s, want %s %s", tt.amount, tt.from, tt.rate, got, tt.want, tt.to)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	sum, err := NewMoney(MustParse("1.10"), "CAD").Add(NewMoney(MustParse("2.05"), "CAD"))
+	if err != nil || sum.String() != "3.15 CAD" {
+		t.Errorf("Add = %s (error %v), want 3.15 CAD", sum, err)
+	}
+	difference, err := NewMoney(MustParse("1.10"), "CAD").Sub(NewMoney(MustParse("2.05"), "CAD"))
+	if err != nil || difference.String() != "-0.95 CAD" {
+		t.Errorf("Sub = %s (error %v), want -0.95 CAD", difference, err)
+	}
+	if _, err := NewMoney(NewFromInt(1), "CAD").Add(NewMoney(NewFromInt(1), "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Add across currencies error = %v, want ErrCurrencyMismatch", err)
+	}
+	if _, err := NewMoney(NewFromInt(1), "CAD").Sub(NewMoney(NewFromInt(1), "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Sub across currencies error = %v, want ErrCurrencyMismatch", err)
+	}
+	if got := NewMoney(MustParse("12.5"), "JPY").Round(HalfEven); got.String() != "12 JPY" {
+		t.Errorf("Round = %s, want 12 JPY", got)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   string
+		weights []string
+		places  int32
+		mode    RoundingMode
+		want    []string
+	}{
+		{"exact split", "10.00", []string{"1", "1"}, 2, HalfEven, []string{"5.00", "5.00"}},
+		{"remainder to the first largest weight", "100.00", []string{"1", "1", "1"}, 2, HalfEven, []string{"33.34", "33.33", "33.33"}},
+		{"remainder to a later largest weight", "0.10", []string{"1", "3", "3"}, 2, HalfEven, []string{"0.01", "0.05", "0.04"}},
+		{"rounding already adds up", "10", []string{"1", "2"}, 2, HalfEven, []string{"3.33", "6.67"}},
+		{"half-even ties", "0.05", []string{"1", "1"}, 2, HalfEven, []string{"0.03", "0.02"}},
+		{"half-up ties", "0.05", []string{"1", "1"}, 2, HalfUp, []string{"0.02", "0.03"}},
+		{"whole units", "1000", []string{"1", "1", "1"}, 0, HalfEven, []string{"334", "333", "333"}},
+		{"decimal weights", "12.00", []string{"2.50", "7.50"}, 2, HalfEven, []string{"3.00", "9.00"}},
+		{"negative total", "-100.00", []string{"1", "1", "1"}, 2, HalfEven, []string{"-33.34", "-33.33", "-33.33"}},
+		{"zero weights", "5", []string{"0", "0"}, 2, HalfEven, []string{"5.00", "0.00"}},
+		{"single weight", "7.777", []string{"3"}, 2, HalfEven, []string{"7.78"}},
+		{"no weights", "5", nil, 2, HalfEven, []string{}},
+	}
+	for _, tt := range tests {
+		weights := make([]Decimal, len(tt.weights))
+		for i, weight := range tt.weights {
+			weights[i] = MustParse(weight)
+		}
+		shares := Allocate(MustParse(tt.total), weights, tt.places, tt.mode)
+
+		got := make([]string, len(shares))
+		sum := Zero
+		for i, share := range shares {
+			got[i] = share.String()
+			sum = sum.Add(share)
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%s: Allocate = %v, want %v", tt.name, got, tt.want)
+		}
+		if len(shares) > 0 && !sum.Equal(MustParse(tt.total).Round(tt.places, tt.mode)) {
+			t.Errorf("%s: shares sum to %s, not the total %s", tt.name, sum, tt.total)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package money
+
+import "math/big"
+
+// RoundingMode decides what happens to the digits dropped by Round and Div
+type RoundingMode int
+
+const (
+	// HalfEven rounds to the nearest neighbour and ties to the even one (banker's rounding); it does not
+	// drift when many rounded values are summed, so it is used for totals and conversions
+	HalfEven RoundingMode = iota
+	// HalfUp rounds to the nearest neighbour and ties away from zero, as taught in school
+	HalfUp
+	// HalfDown rounds to the nearest neighbour and ties towards zero
+	HalfDown
+	// Down truncates towards zero
+	Down
+	// Up rounds away from zero
+	Up
+	// Floor rounds towards negative infinity
+	Floor
+	// Ceiling rounds towards positive infinity
+	Ceiling
+)
+
+// roundQuo returns num ÷ den rounded to an integer with the given mode
+func roundQuo(num, den *big.Int, mode RoundingMode) *big.Int {
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return quo
+	}
+
+	// Sign of the exact quotient; QuoRem truncates towards zero
+	sign := num.Sign() * den.Sign()
+	// Compare the dropped fraction with one half: 2|rem| against |den|
+	half := new(big.Int).Abs(rem)
+	half.Lsh(half, 1)
+	half.Sub(half, new(big.Int).Abs(den))
+	tie, aboveHalf := half.Sign() == 0, half.Sign() > 0
+
+	awayFromZero := false
+	switch mode {
+	case HalfEven:
+		awayFromZero = aboveHalf || (tie && quo.Bit(0) == 1)
+	case HalfUp:
+		awayFromZero = aboveHalf || tie
+	case HalfDown:
+		awayFromZero = aboveHalf
+	case Down:
+	case Up:
+		awayFromZero = true
+	case Floor:
+		awayFromZero = sign < 0
+	case Ceiling:
+		awayFromZero = sign > 0
+	}
+
+	if awayFromZero {
+		if sign < 0 {
+			quo.Sub(quo, bigOne)
+		} else {
+			quo.Add(quo, bigOne)
+		}
+	}
+	return quo
+}
@@ -0,0 +1,38 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"expense-mgmt/configs"
+	"fmt"
+)
+
+// ErrUnsupported is returned by an engine that cannot handle the given content; such receipts are not retried
+var ErrUnsupported = errors.New("content type not supported by OCR engine")
+
+// OCREngine extracts raw text from a receipt image
+type OCREngine interface {
+	// Name identifies the engine in logs
+	Name() string
+	// ExtractText returns the text found in the image
+	ExtractText(ctx context.Context, image []byte, contentType string) (string, error)
+}
+
+// NewEngine builds the OCR engine selected in the configuration; it returns nil when OCR is disabled
+func NewEngine(config *configs.Config) (OCREngine, error) {
+	switch config.OCR.Engine {
+	case "", "none":
+		return nil, nil
+	case "tesseract":
+		return &TesseractEngine{Binary: config.OCR.TesseractPath, Language: config.OCR.Language}, nil
+	case "http":
+		if config.OCR.Endpoint == "" {
+			return nil, fmt.Errorf("ocr.endpoint is required for the http engine")
+		}
+		return &HTTPEngine{Endpoint: config.OCR.Endpoint, APIKey: config.OCR.APIKey}, nil
+	case "fake":
+		return &FakeEngine{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported OCR engine %q", config.OCR.Engine)
+	}
+}
@@ -0,0 +1,37 @@
+package ocr
+
+import (
+	"context"
+)
+
+// FakeEngine is a deterministic engine for tests and local development
+type FakeEngine struct {
+	Text string // Text returned for every image; a sample receipt when empty
+	Err  error  // Error returned instead of text when set
+}
+
+// sampleReceiptText is returned by FakeEngine when no text is configured
+const sampleReceiptText = `FRESH MART
+123 Main St
+2024-11-20 14:32
+Milk 2L            4.99
+Bread              3.49
+SUBTOTAL           8.48
+TAX                0.44
+TOTAL              8.92`
+
+// Name identifies the engine in logs
+func (e *FakeEngine) Name() string {
+	return "fake"
+}
+
+// ExtractText returns the configured text or error
+func (e *FakeEngine) ExtractText(ctx context.Context, image []byte, contentType string) (string, error) {
+	if e.Err != nil {
+		return "", e.Err
+	}
+	if e.Text == "" {
+		return sampleReceiptText, nil
+	}
+	return e.Text, nil
+}
@@ -0,0 +1,64 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPEngine sends the image to a cloud OCR endpoint.
+// The endpoint receives the raw image as the request body and must answer with {"text": "..."}.
+type HTTPEngine struct {
+	Endpoint string
+	APIKey   string
+	Client   *http.Client
+}
+
+// Name identifies the engine in logs
+func (e *HTTPEngine) Name() string {
+	return "http"
+}
+
+// ExtractText posts the image to the endpoint and returns the text it extracted
+func (e *HTTPEngine) ExtractText(ctx context.Context, image []byte, contentType string) (string, error) {
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.Endpoint, bytes.NewReader(image))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("OCR request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnsupportedMediaType {
+		return "", ErrUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("OCR endpoint returned %d: %s", resp.StatusCode, body)
+	}
+
+	var result struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("invalid OCR response: %w", err)
+	}
+
+	return result.Text, nil
+}
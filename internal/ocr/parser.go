@@ -0,0 +1,336 @@
+package ocr
+
+import (
+	"expense-mgmt/internal/money"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParsedReceipt is the structured result of parsing raw receipt text. Every field carries a confidence
+// between 0 (not found) and 1 (certain).
+type ParsedReceipt struct {
+	Merchant           string
+	MerchantConfidence float64
+	Date               *time.Time
+	DateConfidence     float64
+	Subtotal           *money.Decimal
+	SubtotalConfidence float64
+	Tax                *money.Decimal
+	TaxConfidence      float64
+	Total              *money.Decimal
+	TotalConfidence    float64
+	Currency           string
+	CurrencyConfidence float64
+	LineItems          []ParsedLineItem
+}
+
+// ParsedLineItem is a single purchased item found on the receipt
+type ParsedLineItem struct {
+	Description string
+	Quantity    money.Decimal
+	UnitPrice   *money.Decimal
+	Amount      money.Decimal
+	Confidence  float64
+}
+
+var (
+	// trailingAmountPattern matches a price at the end of a line, e.g. "4.99", "$1,234.50", "12,99 EUR"
+	trailingAmountPattern = regexp.MustCompile(`(-?\d{1,3}(?:[,. ]\d{3})*[.,]\d{2}|-?\d+[.,]\d{2})\s*(?:[A-Z]{3}|[A-Z])?\s*$`)
+	subtotalPattern       = regexp.MustCompile(`(?i)\bsub\s*-?\s*total\b`)
+	totalPattern          = regexp.MustCompile(`(?i)\b(grand\s+total|total|amount\s+due|balance\s+due)\b`)
+	taxPattern            = regexp.MustCompile(`(?i)\b(tax|gst|hst|pst|qst|vat|tps|tvq)\b`)
+	taxTotalPattern       = regexp.MustCompile(`(?i)\b(total\s+tax|tax\s+total)\b`)
+	// nonItemPattern flags summary and payment lines that must not become line items
+	nonItemPattern     = regexp.MustCompile(`(?i)\b(sub\s*-?\s*total|total|tax|gst|hst|pst|qst|vat|change|cash|tender|visa|mastercard|amex|debit|credit|balance|amount\s+due|payment|rounding|discount\s+total|savings)\b`)
+	quantityPattern    = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*(?:x|@)\s*(\d+[.,]\d{2})\b`)
+	leadingQtyPattern  = regexp.MustCompile(`^(\d{1,3})\s+(\D.*)$`)
+	currencyCodeRegexp = regexp.MustCompile(`\b(CAD|USD|EUR|GBP|AUD|NZD|JPY|CHF|MXN|INR|CNY)\b`)
+	phonePattern       = regexp.MustCompile(`\(?\d{3}\)?[ .-]?\d{3}[ .-]?\d{4}`)
+	addressPattern     = regexp.MustCompile(`(?i)^\d+\s+\w+.*\b(st|street|ave|avenue|rd|road|blvd|dr|drive|way|lane|ln|hwy)\b`)
+
+	isoDatePattern     = regexp.MustCompile(`\b(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`)
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,2})[-/.](\d{1,2})[-/.](\d{2,4})\b`)
+	namedDatePattern   = regexp.MustCompile(`(?i)\b(?:(\d{1,2})\s+)?(jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.?\s+(?:(\d{1,2}),?\s+)?(\d{4})\b`)
+)
+
+var monthNames = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"may": time.May, "jun": time.June, "jul": time.July, "aug": time.August,
+	"sep": time.September, "sept": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// ParseReceipt extracts the merchant, date, amounts, currency and line items from raw OCR text
+func ParseReceipt(text string) ParsedReceipt {
+	var result ParsedReceipt
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return result
+	}
+
+	result.Merchant, result.MerchantConfidence = parseMerchant(lines)
+	result.Date, result.DateConfidence = parseDate(lines)
+	result.Currency, result.CurrencyConfidence = parseCurrency(text)
+
+	// Summary amounts; only the first total counts as payment sections often repeat it afterwards
+	firstSummary := len(lines)
+	taxTotal := money.Zero
+	taxLines := 0
+	for i, line := range lines {
+		amount, ok := trailingAmount(line)
+		label := strings.TrimSpace(trailingAmountPattern.ReplaceAllString(line, ""))
+
+		switch {
+		case subtotalPattern.MatchString(label):
+			if ok {
+				result.Subtotal, result.SubtotalConfidence = &amount, 0.9
+			}
+		case taxPattern.MatchString(label) && (!totalPattern.MatchString(label) || taxTotalPattern.MatchString(label)):
+			if ok {
+				taxTotal = taxTotal.Add(amount)
+				taxLines++
+			}
+		case totalPattern.MatchString(label):
+			if ok && result.Total == nil {
+				result.Total, result.TotalConfidence = &amount, 0.9
+				if strings.Contains(strings.ToLower(label), "grand") || strings.Contains(strings.ToLower(label), "due") {
+					result.TotalConfidence = 0.95
+				}
+			}
+		default:
+			continue
+		}
+		if i < firstSummary {
+			firstSummary = i
+		}
+	}
+	if taxLines > 0 {
+		tax := taxTotal
+		result.Tax, result.TaxConfidence = &tax, 0.85
+		if taxLines > 1 {
+			result.TaxConfidence = 0.75 // Several tax lines were summed
+		}
+	}
+
+	result.LineItems = parseLineItems(lines, firstSummary, result.Merchant)
+
+	// Fall back to the largest amount on the receipt when no total line was found
+	if result.Total == nil {
+		largest := money.Zero
+		for _, line := range lines {
+			if amount, ok := trailingAmount(line); ok && amount.GreaterThan(largest) {
+				largest = amount
+			}
+		}
+		if largest.Sign() > 0 {
+			result.Total, result.TotalConfidence = &largest, 0.4
+		}
+	}
+
+	// Cross-check the amounts against each other
+	if result.Subtotal != nil && result.Tax != nil && result.Total != nil &&
+		result.Subtotal.Add(*result.Tax).Equal(*result.Total) {
+		result.SubtotalConfidence, result.TaxConfidence, result.TotalConfidence = 0.99, 0.99, 0.99
+	}
+	if len(result.LineItems) > 0 {
+		itemsTotal := money.Zero
+		for _, item := range result.LineItems {
+			itemsTotal = itemsTotal.Add(item.Amount)
+		}
+		reference := result.Subtotal
+		if reference == nil && result.Tax == nil {
+			reference = result.Total
+		}
+		if reference != nil && itemsTotal.Equal(*reference) {
+			for i := range result.LineItems {
+				result.LineItems[i].Confidence = math.Max(result.LineItems[i].Confidence, 0.9)
+			}
+		}
+	}
+
+	return result
+}
+
+// parseMerchant takes the first line that looks like a name rather than an address, phone, date or price
+func parseMerchant(lines []string) (string, float64) {
+	for i, line := range lines {
+		if i >= 5 {
+			break
+		}
+		if _, ok := trailingAmount(line); ok {
+			continue
+		}
+		if phonePattern.MatchString(line) || addressPattern.MatchString(line) {
+			continue
+		}
+		if isoDatePattern.MatchString(line) || numericDatePattern.MatchString(line) {
+			continue
+		}
+		if !strings.ContainsFunc(line, func(r rune) bool { return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' }) {
+			continue
+		}
+		if i == 0 {
+			return line, 0.7
+		}
+		return line, 0.5
+	}
+	return "", 0
+}
+
+// parseDate returns the first plausible transaction date found on the receipt
+func parseDate(lines []string) (*time.Time, float64) {
+	for _, line := range lines {
+		if m := isoDatePattern.FindStringSubmatch(line); m != nil {
+			if date, ok := buildDate(atoi(m[1]), atoi(m[2]), atoi(m[3])); ok {
+				return &date, 0.95
+			}
+		}
+		if m := namedDatePattern.FindStringSubmatch(line); m != nil {
+			day := m[1]
+			if day == "" {
+				day = m[3]
+			}
+			month := monthNames[strings.ToLower(m[2])]
+			if month == 0 && len(m[2]) >= 3 {
+				month = monthNames[strings.ToLower(m[2][:3])]
+			}
+			if date, ok := buildDate(atoi(m[4]), int(month), atoi(day)); ok && day != "" {
+				return &date, 0.9
+			}
+		}
+		if m := numericDatePattern.FindStringSubmatch(line); m != nil {
+			first, second, year := atoi(m[1]), atoi(m[2]), atoi(m[3])
+			if year < 100 {
+				year += 2000
+			}
+			// North American receipts default to MM/DD; a first part above 12 can only be a day
+			if first > 12 {
+				if date, ok := buildDate(year, second, first); ok {
+					return &date, 0.8
+				}
+				continue
+			}
+			if date, ok := buildDate(year, first, second); ok {
+				if second > 12 {
+					return &date, 0.8
+				}
+				return &date, 0.6 // Day and month are ambiguous
+			}
+		}
+	}
+	return nil, 0
+}
+
+// parseCurrency looks for an explicit ISO code first and falls back to unambiguous symbols
+func parseCurrency(text string) (string, float64) {
+	if m := currencyCodeRegexp.FindStringSubmatch(text); m != nil {
+		return m[1], 0.9
+	}
+	switch {
+	case strings.Contains(text, "€"):
+		return "EUR", 0.8
+	case strings.Contains(text, "£"):
+		return "GBP", 0.8
+	case strings.Contains(text, "¥"):
+		return "JPY", 0.6
+	}
+	// "$" alone does not tell CAD from USD
+	return "", 0
+}
+
+// parseLineItems reads the priced lines above the first summary line
+func parseLineItems(lines []string, end int, merchant string) []ParsedLineItem {
+	var items []ParsedLineItem
+	for i := 0; i < end && i < len(lines); i++ {
+		line := lines[i]
+		if line == merchant || nonItemPattern.MatchString(line) {
+			continue
+		}
+		amount, ok := trailingAmount(line)
+		if !ok {
+			continue
+		}
+		description := strings.TrimSpace(trailingAmountPattern.ReplaceAllString(line, ""))
+		description = strings.TrimRight(description, " $€£:")
+		if description == "" || isoDatePattern.MatchString(description) || numericDatePattern.MatchString(description) {
+			continue
+		}
+
+		item := ParsedLineItem{Description: description, Quantity: money.NewFromInt(1), Amount: amount, Confidence: 0.7}
+
+		// "2 x 3.49 Bread" or "2 @ 3.49" style quantities
+		if m := quantityPattern.FindStringSubmatch(description); m != nil {
+			quantity, _ := money.Parse(m[1])
+			unitPrice, _ := parseAmount(m[2])
+			item.Quantity = quantity
+			item.UnitPrice = &unitPrice
+			item.Description = strings.TrimSpace(description[len(m[0]):])
+			if quantity.Mul(unitPrice).Round(2, money.HalfUp).Equal(amount) {
+				item.Confidence = 0.85
+			}
+		} else if m := leadingQtyPattern.FindStringSubmatch(description); m != nil {
+			quantity, _ := money.Parse(m[1])
+			if quantity.Sign() > 0 {
+				unitPrice := amount.Div(quantity, 2, money.HalfUp)
+				item.Quantity = quantity
+				item.UnitPrice = &unitPrice
+				item.Description = m[2]
+			}
+		}
+		if item.Description == "" {
+			item.Description = description
+		}
+
+		items = append(items, item)
+	}
+	return items
+}
+
+// trailingAmount returns the price at the end of a line, if any
+func trailingAmount(line string) (money.Decimal, bool) {
+	m := trailingAmountPattern.FindStringSubmatch(line)
+	if m == nil {
+		return money.Zero, false
+	}
+	return parseAmount(m[1])
+}
+
+// parseAmount handles both "1,234.56" and "1.234,56" notations
+func parseAmount(raw string) (money.Decimal, bool) {
+	raw = strings.ReplaceAll(raw, " ", "")
+	if len(raw) >= 3 && raw[len(raw)-3] == ',' {
+		raw = strings.ReplaceAll(raw[:len(raw)-3], ".", "") + "." + raw[len(raw)-2:]
+	} else {
+		raw = strings.ReplaceAll(raw, ",", "")
+	}
+	value, err := money.Parse(raw)
+	if err != nil {
+		return money.Zero, false
+	}
+	return value, true
+}
+
+// buildDate validates the parts and rejects dates in the future
+func buildDate(year, month, day int) (time.Time, bool) {
+	if year < 2000 || month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Day() != day || date.After(time.Now().AddDate(0, 0, 1)) {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func atoi(s string) int {
+	value, _ := strconv.Atoi(s)
+	return value
+}
@@ -0,0 +1,179 @@
+package ocr
+
+import (
+	"expense-mgmt/internal/money"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// field formats a parsed value with its confidence, or "none"
+func field(value string, confidence float64) string {
+	if value == "" {
+		return "none"
+	}
+	return fmt.Sprintf("%s (%.2f)", value, confidence)
+}
+
+func amount(value *money.Decimal) string {
+	if value == nil {
+		return ""
+	}
+	return value.String()
+}
+
+func day(value *time.Time) string {
+	if value == nil {
+		return ""
+	}
+	return value.Format("2006-01-02")
+}
+
+// summary formats the merchant, date, amounts and currency of a parsed receipt
+func summary(receipt ParsedReceipt) string {
+	return strings.Join([]string{
+		field(receipt.Merchant, receipt.MerchantConfidence),
+		field(day(receipt.Date), receipt.DateConfidence),
+		"subtotal " + field(amount(receipt.Subtotal), receipt.SubtotalConfidence),
+		"tax " + field(amount(receipt.Tax), receipt.TaxConfidence),
+		"total " + field(amount(receipt.Total), receipt.TotalConfidence),
+		"currency " + field(receipt.Currency, receipt.CurrencyConfidence),
+	}, "; ")
+}
+
+// items formats line items as "description quantity x unit price = amount (confidence)"
+func items(receipt ParsedReceipt) []string {
+	var lines []string
+	for _, item := range receipt.LineItems {
+		unitPrice := "?"
+		if item.UnitPrice != nil {
+			unitPrice = item.UnitPrice.String()
+		}
+		lines = append(lines, fmt.Sprintf("%s %s x %s = %s (%.2f)", item.Description, item.Quantity, unitPrice, item.Amount, item.Confidence))
+	}
+	return lines
+}
+
+func TestParseReceipt(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		summary string
+		items   []string
+	}{
+		{
+			name: "grocery receipt with every field",
+			text: `FRESH MART
+				123 Main St
+				(555) 123-4567
+				2024-03-15 14:32
+				Milk 2L 4.99
+				2 x 3.49 Bread 6.98
+				Apples 5.25
+				SUBTOTAL 17.22
+				GST 0.86
+				TOTAL 18.08 CAD
+				VISA 18.08`,
+			summary: "FRESH MART (0.70); 2024-03-15 (0.95); subtotal 17.22 (0.99); tax 0.86 (0.99); total 18.08 (0.99); currency CAD (0.90)",
+			items:   []string{"Milk 2L 1 x ? = 4.99 (0.90)", "Bread 2 x 3.49 = 6.98 (0.90)", "Apples 1 x ? = 5.25 (0.90)"},
+		},
+		{
+			name: "no total line falls back to the largest amount",
+			text: `Corner Cafe
+				Jan 5, 2024
+				Latte 4.50
+				Muffin 3.25
+				Thank you!`,
+			summary: "Corner Cafe (0.70); 2024-01-05 (0.90); subtotal none; tax none; total 4.50 (0.40); currency none",
+			items:   []string{"Latte 1 x ? = 4.50 (0.70)", "Muffin 1 x ? = 3.25 (0.70)"},
+		},
+		{
+			name: "the first plausible date wins",
+			text: `BOOK NOOK
+				Offer valid to 2099-06-30
+				Sale 45/13/2024
+				Date: 31/01/2024
+				Returns by 2024-02-29
+				Paperback 24.99
+				Total $24.99`,
+			summary: "BOOK NOOK (0.70); 2024-01-31 (0.80); subtotal none; tax none; total 24.99 (0.90); currency none",
+			items:   []string{"Paperback 1 x ? = 24.99 (0.90)"},
+		},
+		{
+			name: "thousands separators",
+			text: `ELECTRO WORLD
+				Laptop 1,299.99
+				Warranty 150.00
+				Subtotal 1,449.99
+				HST 188.50
+				Grand Total 1,638.49`,
+			summary: "ELECTRO WORLD (0.70); none; subtotal 1449.99 (0.99); tax 188.50 (0.99); total 1638.49 (0.99); currency none",
+			items:   []string{"Laptop 1 x ? = 1299.99 (0.90)", "Warranty 1 x ? = 150.00 (0.90)"},
+		},
+		{
+			name: "decimal commas and an ambiguous numeric date",
+			text: `Café Müller
+				12.03.2024
+				2 Croissant 5,00
+				Espressomaschine 1.234,56
+				TOTAL 1.239,56 EUR`,
+			summary: "Café Müller (0.70); 2024-12-03 (0.60); subtotal none; tax none; total 1239.56 (0.90); currency EUR (0.90)",
+			items:   []string{"Croissant 2 x 2.50 = 5.00 (0.90)", "Espressomaschine 1 x ? = 1234.56 (0.90)"},
+		},
+		{
+			name: "several tax lines are summed",
+			text: `HARDWARE HUT
+				Hammer 20.00
+				GST 1.00
+				PST 1.40
+				Total 22.40
+				Change 0.00`,
+			summary: "HARDWARE HUT (0.70); none; subtotal none; tax 2.40 (0.75); total 22.40 (0.90); currency none",
+			items:   []string{"Hammer 1 x ? = 20.00 (0.70)"},
+		},
+		{
+			name: "only the first total counts",
+			text: `Gas & Go
+				Unleaded 45.00
+				TOTAL 45.00
+				Debit TOTAL 50.00
+				Amount due 45.00`,
+			summary: "Gas & Go (0.70); none; subtotal none; tax none; total 45.00 (0.90); currency none",
+			items:   []string{"Unleaded 1 x ? = 45.00 (0.90)"},
+		},
+		{
+			name:    "empty text",
+			text:    " \n \n",
+			summary: "none; none; subtotal none; tax none; total none; currency none",
+		},
+	}
+	for _, tt := range tests {
+		receipt := ParseReceipt(tt.text)
+		if got := summary(receipt); got != tt.summary {
+			t.Errorf("%s: parsed\n  %s\nwant\n  %s", tt.name, got, tt.summary)
+		}
+		if got := items(receipt); fmt.Sprint(got) != fmt.Sprint(tt.items) {
+			t.Errorf("%s: line items = %q, want %q", tt.name, got, tt.items)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"4.99", "4.99"},
+		{"1,234.50", "1234.50"},
+		{"1.234,50", "1234.50"},
+		{"12,99", "12.99"},
+		{"1 234,50", "1234.50"},
+		{"-3.00", "-3.00"},
+	}
+	for _, tt := range tests {
+		if got, ok := parseAmount(tt.raw); !ok || got.String() != tt.want {
+			t.Errorf("parseAmount(%q) = %s, %v, want %s", tt.raw, got, ok, tt.want)
+		}
+	}
+}
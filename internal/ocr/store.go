@@ -0,0 +1,70 @@
+package ocr
+
+import (
+	"errors"
+	"expense-mgmt/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// SaveParse replaces the parsed fields and line items stored for a receipt
+func SaveParse(tx *gorm.DB, receiptID string, parsed ParsedReceipt) error {
+	if err := tx.Where("receipt_id = ?", receiptID).Delete(&models.ReceiptLineItem{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("receipt_id = ?", receiptID).Delete(&models.ReceiptParse{}).Error; err != nil {
+		return err
+	}
+
+	parse := models.ReceiptParse{
+		ReceiptID:          receiptID,
+		Merchant:           parsed.Merchant,
+		MerchantConfidence: parsed.MerchantConfidence,
+		TransactionDate:    parsed.Date,
+		DateConfidence:     parsed.DateConfidence,
+		Subtotal:           parsed.Subtotal,
+		SubtotalConfidence: parsed.SubtotalConfidence,
+		Tax:                parsed.Tax,
+		TaxConfidence:      parsed.TaxConfidence,
+		Total:              parsed.Total,
+		TotalConfidence:    parsed.TotalConfidence,
+		Currency:           parsed.Currency,
+		CurrencyConfidence: parsed.CurrencyConfidence,
+	}
+	if err := tx.Create(&parse).Error; err != nil {
+		return err
+	}
+
+	if len(parsed.LineItems) == 0 {
+		return nil
+	}
+	items := make([]models.ReceiptLineItem, 0, len(parsed.LineItems))
+	for i, item := range parsed.LineItems {
+		items = append(items, models.ReceiptLineItem{
+			ReceiptID:   receiptID,
+			Position:    i + 1,
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Amount:      item.Amount,
+			Confidence:  item.Confidence,
+		})
+	}
+	return tx.Create(&items).Error
+}
+
+// LoadParse fetches the parsed fields of a receipt together with its line items; it returns nil when nothing was parsed
+func LoadParse(db *gorm.DB, receiptID string) (*models.ReceiptParse, error) {
+	var parse models.ReceiptParse
+	if err := db.Where("receipt_id = ?", receiptID).First(&parse).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := db.Where("receipt_id = ?", receiptID).Order("position ASC").Find(&parse.LineItems).Error; err != nil {
+		return nil, err
+	}
+	return &parse, nil
+}
@@ -0,0 +1,49 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// TesseractEngine runs the local tesseract binary on the image
+type TesseractEngine struct {
+	Binary   string // Path to the tesseract binary (default: tesseract)
+	Language string // Language pack passed with -l (default: eng)
+}
+
+// Name identifies the engine in logs
+func (e *TesseractEngine) Name() string {
+	return "tesseract"
+}
+
+// ExtractText pipes the image through tesseract and returns its stdout
+func (e *TesseractEngine) ExtractText(ctx context.Context, image []byte, contentType string) (string, error) {
+	// Tesseract reads raster images only
+	if !strings.HasPrefix(contentType, "image/") {
+		return "", ErrUnsupported
+	}
+
+	binary := e.Binary
+	if binary == "" {
+		binary = "tesseract"
+	}
+	language := e.Language
+	if language == "" {
+		language = "eng"
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, "stdin", "stdout", "-l", language)
+	cmd.Stdin = bytes.NewReader(image)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,259 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"expense-mgmt/configs"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/storage"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultWorkers      = 1
+	defaultMaxAttempts  = 3
+	defaultPollInterval = 10 * time.Second
+	retryBaseDelay      = 30 * time.Second // Delay before the first retry, doubled on each further attempt
+	staleAfter          = 10 * time.Minute // Receipts stuck in processing this long are picked up again
+	extractTimeout      = 2 * time.Minute
+)
+
+// Worker processes pending receipts in the background and stores their OCR text
+type Worker struct {
+	DB           *gorm.DB
+	Store        storage.BlobStore
+	Engine       OCREngine
+	Workers      int
+	MaxAttempts  int
+	PollInterval time.Duration
+
+	wake chan struct{}
+}
+
+var defaultWorker *Worker
+
+// StartWorker builds the worker from the configuration and starts it; OCR stays disabled when no engine is configured
+func StartWorker(database *gorm.DB, store storage.BlobStore) (*Worker, error) {
+	config := configs.LoadConfig() // Load the configuration
+
+	engine, err := NewEngine(config)
+	if err != nil {
+		return nil, err
+	}
+	if engine == nil {
+		log.Println("OCR engine not configured, receipts will stay pending")
+		return nil, nil
+	}
+
+	worker := &Worker{
+		DB:           database,
+		Store:        store,
+		Engine:       engine,
+		Workers:      config.OCR.Workers,
+		MaxAttempts:  config.OCR.MaxAttempts,
+		PollInterval: time.Duration(config.OCR.PollIntervalSeconds) * time.Second,
+	}
+	worker.Start(context.Background())
+	defaultWorker = worker
+
+	log.Printf("OCR worker started (engine: %s, workers: %d)", engine.Name(), worker.Workers)
+	return worker, nil
+}
+
+// Notify wakes the running worker so a freshly queued receipt is picked up without waiting for the next poll
+func Notify() {
+	if defaultWorker != nil {
+		defaultWorker.Notify()
+	}
+}
+
+// Start launches the worker goroutines; they stop when ctx is cancelled
+func (w *Worker) Start(ctx context.Context) {
+	if w.Workers <= 0 {
+		w.Workers = defaultWorkers
+	}
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = defaultMaxAttempts
+	}
+	if w.PollInterval <= 0 {
+		w.PollInterval = defaultPollInterval
+	}
+	w.wake = make(chan struct{}, 1)
+
+	for i := 0; i < w.Workers; i++ {
+		go w.run(ctx)
+	}
+}
+
+// Notify wakes one idle worker goroutine
+func (w *Worker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+}
+
+func (w *Worker) run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain every receipt that is ready before going back to sleep
+		for ctx.Err() == nil {
+			processed, err := w.ProcessNext(ctx)
+			if err != nil {
+				log.Printf("OCR worker error: %v", err)
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-w.wake:
+		}
+	}
+}
+
+// ProcessNext claims one receipt that is due and runs OCR on it. It reports whether a receipt was processed.
+func (w *Worker) ProcessNext(ctx context.Context) (bool, error) {
+	receipt, err := w.claim()
+	if err != nil || receipt == nil {
+		return false, err
+	}
+
+	text, err := w.extract(ctx, receipt)
+	if err != nil {
+		return true, w.fail(receipt, err)
+	}
+	return true, w.complete(receipt, text)
+}
+
+// claim locks the oldest due receipt and marks it as processing so other workers and pods skip it. Receipts left
+// processing by a crash or a hang are picked up again once stale, unless they already used all their attempts: those
+// are marked as failed instead, so a receipt that kills the worker is not retried forever.
+func (w *Worker) claim() (*models.Receipt, error) {
+	var receipt models.Receipt
+	found := false
+	now := time.Now()
+
+	err := w.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Receipt{}).
+			Where("status = ? AND updated_at < ? AND attempts >= ?", models.ReceiptStatusProcessing, now.Add(-staleAfter), w.MaxAttempts).
+			Updates(map[string]interface{}{
+				"status":          models.ReceiptStatusFailed,
+				"last_error":      "processing did not finish",
+				"next_attempt_at": nil,
+				"updated_at":      now,
+			}).Error; err != nil {
+			return err
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR (status = ? AND updated_at < ? AND attempts < ?)",
+				models.ReceiptStatusPending, now, models.ReceiptStatusProcessing, now.Add(-staleAfter), w.MaxAttempts).
+			Order("scanned_date ASC").
+			First(&receipt).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		found = true
+		receipt.Attempts++
+		return tx.Model(&receipt).Updates(map[string]interface{}{
+			"status":     models.ReceiptStatusProcessing,
+			"attempts":   receipt.Attempts,
+			"updated_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim receipt: %w", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return &receipt, nil
+}
+
+// extract loads the stored image and hands it to the engine
+func (w *Worker) extract(ctx context.Context, receipt *models.Receipt) (string, error) {
+	if receipt.StorageKey == "" {
+		return "", fmt.Errorf("%w: receipt has no stored image", ErrUnsupported)
+	}
+
+	reader, err := w.Store.Get(ctx, receipt.StorageKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read receipt image: %w", err)
+	}
+	defer reader.Close()
+
+	image, err := io.ReadAll(io.LimitReader(reader, storage.MaxUploadBytes()+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read receipt image: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+	return w.Engine.ExtractText(ctx, image, receipt.ContentType)
+}
+
+// complete stores the extracted text with its parsed fields and marks the receipt as done
+func (w *Worker) complete(receipt *models.Receipt, text string) error {
+	parsed := ParseReceipt(text)
+	now := time.Now()
+
+	return w.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Receipt{}).
+			Where("id = ? AND status = ?", receipt.ID, models.ReceiptStatusProcessing).
+			Updates(map[string]interface{}{
+				"ocr_data":        text,
+				"status":          models.ReceiptStatusDone,
+				"last_error":      "",
+				"next_attempt_at": nil,
+				"processed_at":    now,
+				"updated_at":      now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil // Deleted or requeued while we were working on it
+		}
+		return SaveParse(tx, receipt.ID, parsed)
+	})
+}
+
+// fail schedules a retry with exponential backoff, or marks the receipt as failed once attempts are exhausted
+func (w *Worker) fail(receipt *models.Receipt, cause error) error {
+	log.Printf("OCR failed for receipt %s (attempt %d): %v", receipt.ID, receipt.Attempts, cause)
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_error": cause.Error(),
+		"updated_at": now,
+	}
+	if errors.Is(cause, ErrUnsupported) || receipt.Attempts >= w.MaxAttempts {
+		updates["status"] = models.ReceiptStatusFailed
+		updates["next_attempt_at"] = nil
+	} else {
+		updates["status"] = models.ReceiptStatusPending
+		updates["next_attempt_at"] = now.Add(retryBaseDelay << (receipt.Attempts - 1))
+	}
+
+	return w.DB.Model(&models.Receipt{}).
+		Where("id = ? AND status = ?", receipt.ID, models.ReceiptStatusProcessing).
+		Updates(updates).Error
+}
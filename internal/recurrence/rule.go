@@ -0,0 +1,298 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the base unit of a recurrence rule
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxIterations bounds rule expansion so a malformed rule cannot loop forever
+const maxIterations = 100000
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Rule is a subset of the iCalendar RRULE: FREQ, INTERVAL, BYMONTHDAY, BYDAY, UNTIL and COUNT.
+// Occurrences are anchored on the start date passed to the expansion methods.
+type Rule struct {
+	Frequency  Frequency
+	Interval   int            // Every Interval periods (default 1)
+	ByMonthDay int            // Monthly only: day of month, -1 for the last day; defaults to the start day
+	ByWeekday  []time.Weekday // Weekly only: days of the week; defaults to the start weekday
+	Until      *time.Time     // Last date an occurrence may fall on (inclusive)
+	Count      int            // Total number of occurrences counted from the start date (0 = unlimited)
+}
+
+// Parse reads a rule such as "FREQ=MONTHLY;BYMONTHDAY=15;COUNT=12"; an optional "RRULE:" prefix is ignored
+func Parse(value string) (Rule, error) {
+	var rule Rule
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return rule, errors.New("rule is empty")
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, found := strings.Cut(part, "=")
+		if !found {
+			return rule, fmt.Errorf("invalid rule part %q", part)
+		}
+		key, val = strings.ToUpper(strings.TrimSpace(key)), strings.ToUpper(strings.TrimSpace(val))
+
+		switch key {
+		case "FREQ":
+			rule.Frequency = Frequency(val)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil {
+				return rule, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = interval
+		case "BYMONTHDAY":
+			day, err := strconv.Atoi(val)
+			if err != nil {
+				return rule, fmt.Errorf("invalid BYMONTHDAY %q", val)
+			}
+			rule.ByMonthDay = day
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				weekday, ok := weekdayCodes[code]
+				if !ok {
+					return rule, fmt.Errorf("invalid BYDAY %q", code)
+				}
+				rule.ByWeekday = append(rule.ByWeekday, weekday)
+			}
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return rule, fmt.Errorf("invalid UNTIL %q", val)
+			}
+			rule.Until = &until
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil {
+				return rule, fmt.Errorf("invalid COUNT %q", val)
+			}
+			rule.Count = count
+		default:
+			return rule, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	return rule, rule.Validate()
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "2006-01-02"} {
+		if until, err := time.Parse(layout, value); err == nil {
+			return time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, errors.New("unrecognized date")
+}
+
+// Validate checks that the rule can be expanded
+func (r Rule) Validate() error {
+	switch r.Frequency {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return errors.New("FREQ is required")
+	default:
+		return fmt.Errorf("unsupported FREQ %q", r.Frequency)
+	}
+	if r.Interval < 0 {
+		return errors.New("INTERVAL must be positive")
+	}
+	if r.Count < 0 {
+		return errors.New("COUNT must be positive")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return errors.New("COUNT and UNTIL cannot be combined")
+	}
+	if r.ByMonthDay != 0 {
+		if r.Frequency != Monthly {
+			return errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+		}
+		if r.ByMonthDay < -1 || r.ByMonthDay > 31 {
+			return errors.New("BYMONTHDAY must be between 1 and 31, or -1 for the last day")
+		}
+	}
+	if len(r.ByWeekday) > 0 && r.Frequency != Weekly {
+		return errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	return nil
+}
+
+// String renders the rule back to RRULE syntax
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	if len(r.ByWeekday) > 0 {
+		codes := make([]string, 0, len(r.ByWeekday))
+		for _, weekday := range r.ByWeekday {
+			for code, day := range weekdayCodes {
+				if day == weekday {
+					codes = append(codes, code)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Each calls fn with every occurrence in order, starting at start, until fn returns false or the rule ends
+func (r Rule) Each(start time.Time, fn func(index int, occurrence time.Time) bool) {
+	start = truncateDay(start)
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 1
+	}
+
+	index := 0
+	emit := func(occurrence time.Time) bool {
+		if occurrence.Before(start) {
+			return true // Days of the first period that fall before the start date
+		}
+		if r.Until != nil && occurrence.After(truncateDay(*r.Until)) {
+			return false
+		}
+		if r.Count > 0 && index >= r.Count {
+			return false
+		}
+		keepGoing := fn(index, occurrence)
+		index++
+		return keepGoing
+	}
+
+	for period := 0; period < maxIterations; period++ {
+		switch r.Frequency {
+		case Daily:
+			if !emit(start.AddDate(0, 0, period*interval)) {
+				return
+			}
+		case Weekly:
+			weekdays := r.ByWeekday
+			if len(weekdays) == 0 {
+				weekdays = []time.Weekday{start.Weekday()}
+			}
+			sorted := append([]time.Weekday(nil), weekdays...)
+			sort.Slice(sorted, func(i, j int) bool { return weekdayOffset(sorted[i]) < weekdayOffset(sorted[j]) })
+			// Weeks start on Monday
+			weekStart := start.AddDate(0, 0, -weekdayOffset(start.Weekday())+period*interval*7)
+			for _, weekday := range sorted {
+				if !emit(weekStart.AddDate(0, 0, weekdayOffset(weekday))) {
+					return
+				}
+			}
+		case Monthly:
+			day := r.ByMonthDay
+			if day == 0 {
+				day = start.Day()
+			}
+			if !emit(monthDay(start.Year(), start.Month()+time.Month(period*interval), day)) {
+				return
+			}
+		case Yearly:
+			if !emit(monthDay(start.Year()+period*interval, start.Month(), start.Day())) {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Between returns the occurrences falling within [from, to]
+func (r Rule) Between(start, from, to time.Time) []time.Time {
+	from, to = truncateDay(from), truncateDay(to)
+	var occurrences []time.Time
+	r.Each(start, func(_ int, occurrence time.Time) bool {
+		if occurrence.After(to) {
+			return false
+		}
+		if !occurrence.Before(from) {
+			occurrences = append(occurrences, occurrence)
+		}
+		return true
+	})
+	return occurrences
+}
+
+// Next returns the first occurrence strictly after the given date
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	after = truncateDay(after)
+	var next time.Time
+	found := false
+	r.Each(start, func(_ int, occurrence time.Time) bool {
+		if occurrence.After(after) {
+			next, found = occurrence, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// First returns the first occurrence on or after the start date
+func (r Rule) First(start time.Time) (time.Time, bool) {
+	return r.Next(start, truncateDay(start).AddDate(0, 0, -1))
+}
+
+// CountBefore returns how many occurrences fall strictly before the given date
+func (r Rule) CountBefore(start, before time.Time) int {
+	before = truncateDay(before)
+	count := 0
+	r.Each(start, func(_ int, occurrence time.Time) bool {
+		if !occurrence.Before(before) {
+			return false
+		}
+		count++
+		return true
+	})
+	return count
+}
+
+// monthDay builds a date on the given day of month, clamped to the month's last day (-1 means the last day)
+func monthDay(year int, month time.Month, day int) time.Time {
+	firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	if day == -1 || day > lastDay {
+		day = lastDay
+	}
+	return firstOfMonth.AddDate(0, 0, day-1)
+}
+
+// weekdayOffset returns the position of the weekday in a Monday-first week
+func weekdayOffset(weekday time.Weekday) int {
+	return (int(weekday) + 6) % 7
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,190 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// occurrences lists the first n occurrences of the rule from start as YYYY-MM-DD
+func occurrences(rule Rule, start time.Time, n int) string {
+	var dates []string
+	rule.Each(start, func(index int, occurrence time.Time) bool {
+		dates = append(dates, occurrence.Format("2006-01-02"))
+		return index+1 < n
+	})
+	return strings.Join(dates, " ")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // Rule rendered back by String
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=monthly;bymonthday=15;count=12", "FREQ=MONTHLY;BYMONTHDAY=15;COUNT=12"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR"},
+		{"FREQ=YEARLY;INTERVAL=1", "FREQ=YEARLY"},
+		{"FREQ=MONTHLY;UNTIL=20240315", "FREQ=MONTHLY;UNTIL=20240315"},
+		{"FREQ=MONTHLY;UNTIL=20240315T120000Z", "FREQ=MONTHLY;UNTIL=20240315"},
+		{"FREQ=MONTHLY;UNTIL=2024-03-15", "FREQ=MONTHLY;UNTIL=20240315"},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.input, err)
+			continue
+		}
+		if got := rule.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"FREQ",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;FOO=1",
+		"FREQ=DAILY;INTERVAL=x",
+		"FREQ=DAILY;INTERVAL=-1",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;BYMONTHDAY=3",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=MONTHLY;BYMONTHDAY=-2",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;UNTIL=15/03/2024",
+		"FREQ=MONTHLY;COUNT=2;UNTIL=20240101",
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) did not return an error", input)
+		}
+	}
+}
+
+func TestEach(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		n     int
+		want  string
+	}{
+		{"daily", "FREQ=DAILY", "2024-02-27", 4, "2024-02-27 2024-02-28 2024-02-29 2024-03-01"},
+		{"daily interval", "FREQ=DAILY;INTERVAL=2", "2024-01-30", 3, "2024-01-30 2024-02-01 2024-02-03"},
+		{"weekly on the start weekday", "FREQ=WEEKLY", "2024-01-03", 3, "2024-01-03 2024-01-10 2024-01-17"},
+		{"weekly BYDAY skips days before the start", "FREQ=WEEKLY;BYDAY=MO,WE,FR", "2024-01-03", 5,
+			"2024-01-03 2024-01-05 2024-01-08 2024-01-10 2024-01-12"},
+		{"weekly BYDAY in any order", "FREQ=WEEKLY;BYDAY=FR,MO", "2024-01-01", 3, "2024-01-01 2024-01-05 2024-01-08"},
+		{"biweekly", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", "2024-01-01", 3, "2024-01-07 2024-01-21 2024-02-04"},
+		{"monthly on the start day", "FREQ=MONTHLY", "2024-01-15", 3, "2024-01-15 2024-02-15 2024-03-15"},
+		{"monthly from the 31st clamps and recovers", "FREQ=MONTHLY", "2023-01-31", 4, "2023-01-31 2023-02-28 2023-03-31 2023-04-30"},
+		{"BYMONTHDAY=31 clamps to short months", "FREQ=MONTHLY;BYMONTHDAY=31", "2023-01-05", 4, "2023-01-31 2023-02-28 2023-03-31 2023-04-30"},
+		{"BYMONTHDAY=30 in a leap February", "FREQ=MONTHLY;BYMONTHDAY=30", "2024-01-30", 3, "2024-01-30 2024-02-29 2024-03-30"},
+		{"BYMONTHDAY=-1 is the last day", "FREQ=MONTHLY;BYMONTHDAY=-1", "2024-01-10", 4, "2024-01-31 2024-02-29 2024-03-31 2024-04-30"},
+		{"BYMONTHDAY before the start day begins next month", "FREQ=MONTHLY;BYMONTHDAY=10", "2024-01-15", 2, "2024-02-10 2024-03-10"},
+		{"quarterly across a year end", "FREQ=MONTHLY;INTERVAL=3", "2024-11-30", 3, "2024-11-30 2025-02-28 2025-05-30"},
+		{"yearly", "FREQ=YEARLY", "2023-06-01", 3, "2023-06-01 2024-06-01 2025-06-01"},
+		{"yearly from February 29", "FREQ=YEARLY", "2024-02-29", 5, "2024-02-29 2025-02-28 2026-02-28 2027-02-28 2028-02-29"},
+		{"COUNT stops the series", "FREQ=MONTHLY;COUNT=3", "2024-01-15", 10, "2024-01-15 2024-02-15 2024-03-15"},
+		{"COUNT only counts days from the start", "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3", "2024-01-03", 10, "2024-01-05 2024-01-08 2024-01-12"},
+		{"UNTIL is inclusive", "FREQ=MONTHLY;UNTIL=20240315", "2024-01-15", 10, "2024-01-15 2024-02-15 2024-03-15"},
+		{"UNTIL before an occurrence", "FREQ=MONTHLY;UNTIL=20240314", "2024-01-15", 10, "2024-01-15 2024-02-15"},
+		{"UNTIL before the start", "FREQ=DAILY;UNTIL=20231231", "2024-01-01", 10, ""},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q) returned error: %v", tt.name, tt.rule, err)
+		}
+		if got := occurrences(rule, date(tt.start), tt.n); got != tt.want {
+			t.Errorf("%s: occurrences = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEachIgnoresTimeOfDay(t *testing.T) {
+	rule, _ := Parse("FREQ=DAILY;COUNT=2")
+	start := time.Date(2024, 3, 10, 18, 30, 0, 0, time.FixedZone("EST", -5*3600))
+	if got := occurrences(rule, start, 5); got != "2024-03-10 2024-03-11" {
+		t.Errorf("occurrences = %q, want 2024-03-10 2024-03-11", got)
+	}
+}
+
+func TestBetween(t *testing.T) {
+	rule, _ := Parse("FREQ=MONTHLY;BYMONTHDAY=31")
+	var got []string
+	for _, occurrence := range rule.Between(date("2024-01-01"), date("2024-02-01"), date("2024-04-30")) {
+		got = append(got, occurrence.Format("2006-01-02"))
+	}
+	if want := "2024-02-29 2024-03-31 2024-04-30"; strings.Join(got, " ") != want {
+		t.Errorf("Between = %v, want %s", got, want)
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		after string
+		want  string // Empty when the series has ended
+	}{
+		{"next month", "FREQ=MONTHLY", "2024-02-15", "2024-03-15"},
+		{"strictly after", "FREQ=MONTHLY", "2024-02-14", "2024-02-15"},
+		{"before the start", "FREQ=MONTHLY", "2023-06-01", "2024-01-15"},
+		{"clamped month end", "FREQ=MONTHLY;BYMONTHDAY=31", "2024-01-31", "2024-02-29"},
+		{"after the last counted occurrence", "FREQ=MONTHLY;COUNT=2", "2024-02-15", ""},
+		{"after UNTIL", "FREQ=MONTHLY;UNTIL=20240320", "2024-03-15", ""},
+	}
+	for _, tt := range tests {
+		rule, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("%s: Parse(%q) returned error: %v", tt.name, tt.rule, err)
+		}
+		next, found := rule.Next(date("2024-01-15"), date(tt.after))
+		got := ""
+		if found {
+			got = next.Format("2006-01-02")
+		}
+		if got != tt.want {
+			t.Errorf("%s: Next = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFirstAndCountBefore(t *testing.T) {
+	rule, _ := Parse("FREQ=MONTHLY;BYMONTHDAY=10")
+	if first, ok := rule.First(date("2024-01-15")); !ok || !first.Equal(date("2024-02-10")) {
+		t.Errorf("First = %v, %v, want 2024-02-10", first, ok)
+	}
+	if first, ok := rule.First(date("2024-01-10")); !ok || !first.Equal(date("2024-01-10")) {
+		t.Errorf("First on an occurrence = %v, %v, want 2024-01-10", first, ok)
+	}
+
+	tests := []struct {
+		before string
+		want   int
+	}{
+		{"2024-01-10", 0},
+		{"2024-01-11", 1},
+		{"2024-04-10", 3},
+		{"2024-04-11", 4},
+	}
+	for _, tt := range tests {
+		if got := rule.CountBefore(date("2024-01-10"), date(tt.before)); got != tt.want {
+			t.Errorf("CountBefore(%s) = %d, want %d", tt.before, got, tt.want)
+		}
+	}
+}
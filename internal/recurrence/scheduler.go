@@ -0,0 +1,159 @@
+package recurrence
+
+import (
+	"errors"
+	"expense-mgmt/internal/models"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultSchedulerInterval is how often the scheduler looks for due recurring expenses
+const DefaultSchedulerInterval = time.Hour
+
+// OnMaterialized, when set, is called after expenses were created for a user, e.g. to check their budget alerts
+var OnMaterialized func(userID uuid.UUID, dates ...time.Time)
+
+// StartScheduler materializes due recurring expenses now and then on every interval
+func StartScheduler(database *gorm.DB, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+
+	go func() {
+		for {
+			created, err := MaterializeDue(database, time.Now())
+			if err != nil {
+				log.Printf("Recurring expense scheduler error: %v", err)
+			} else if created > 0 {
+				log.Printf("Materialized %d recurring expenses", created)
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// MaterializeDue creates the expenses of every active template whose next occurrence is due.
+// Templates are locked one at a time with SKIP LOCKED so several instances can run side by side.
+func MaterializeDue(database *gorm.DB, now time.Time) (int, error) {
+	total := 0
+	for {
+		found := false
+		var userID uuid.UUID
+		var created []time.Time
+		err := database.Transaction(func(tx *gorm.DB) error {
+			var template models.RecurringExpense
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("active = ? AND next_occurrence IS NOT NULL AND next_occurrence <= ?", true, truncateDay(now)).
+				Order("next_occurrence ASC").
+				First(&template).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			found = true
+			userID = template.UserID
+			created, err = Materialize(tx, &template, now)
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+		total += len(created)
+		if len(created) > 0 {
+			// Catch-up occurrences may fall in earlier budget periods than today's
+			if OnMaterialized != nil {
+				OnMaterialized(userID, created...)
+			}
+		}
+		if !found {
+			return total, nil
+		}
+	}
+}
+
+// Materialize creates the template's expenses that are due up to now and advances its next occurrence, returning the
+// dates of the expenses created. It must run inside a transaction holding a lock on the template.
+func Materialize(tx *gorm.DB, template *models.RecurringExpense, now time.Time) ([]time.Time, error) {
+	if !template.Active || template.NextOccurrence == nil {
+		return nil, nil
+	}
+
+	rule, err := Parse(template.Rule)
+	if err != nil {
+		// A broken rule would be retried forever; stop the template instead
+		log.Printf("Deactivating recurring expense %s with invalid rule %q: %v", template.ID, template.Rule, err)
+		template.Active = false
+		return nil, tx.Model(template).Update("active", false).Error
+	}
+
+	today := truncateDay(now)
+	if template.NextOccurrence.After(today) {
+		return nil, nil
+	}
+
+	var created []time.Time
+	for _, occurrence := range rule.Between(template.StartDate, *template.NextOccurrence, today) {
+		templateID := template.ID
+		expense := models.Expense{
+			UserID:             template.UserID,
+			CategoryID:         template.CategoryID,
+			Amount:             template.Amount,
+			Date:               occurrence,
+			Description:        template.Description,
+			RecurringExpenseID: &templateID,
+		}
+		// The unique (recurring_expense_id, date) index makes re-runs harmless
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&expense)
+		if result.Error != nil {
+			return created, result.Error
+		}
+		if result.RowsAffected > 0 {
+			created = append(created, occurrence)
+		}
+	}
+
+	if err := Refresh(tx, template, rule); err != nil {
+		return created, err
+	}
+	return created, nil
+}
+
+// Refresh recomputes the template's occurrence count and next occurrence from the expenses linked to it
+func Refresh(tx *gorm.DB, template *models.RecurringExpense, rule Rule) error {
+	var progress struct {
+		Count    int64
+		LastDate *time.Time
+	}
+	if err := tx.Model(&models.Expense{}).
+		Select("COUNT(*) AS count, MAX(date) AS last_date").
+		Where("recurring_expense_id = ?", template.ID).
+		Scan(&progress).Error; err != nil {
+		return err
+	}
+
+	var next time.Time
+	var ok bool
+	if progress.LastDate != nil {
+		next, ok = rule.Next(template.StartDate, *progress.LastDate)
+	} else {
+		next, ok = rule.First(template.StartDate)
+	}
+
+	template.OccurrenceCount = int(progress.Count)
+	template.NextOccurrence = nil
+	if ok {
+		template.NextOccurrence = &next
+	}
+
+	return tx.Model(template).Updates(map[string]interface{}{
+		"occurrence_count": template.OccurrenceCount,
+		"next_occurrence":  template.NextOccurrence,
+	}).Error
+}
@@ -23,6 +23,11 @@ func CategoryRoutes(router *gin.Engine) {
 		categoryGroup.GET("/:categoryId", controller.GetCategoryDetails)
 		categoryGroup.PUT("/:categoryId", controller.UpdateCategory)
 		categoryGroup.DELETE("/:categoryId", controller.DeleteCategory)
+		categoryGroup.POST("/:categoryId/merge", controller.MergeCategory) // Move everything into another category and archive this one
+		categoryGroup.POST("/:categoryId/archive", controller.ArchiveCategory)     // Hide a custom category from the list, keeping its history
+		categoryGroup.POST("/:categoryId/unarchive", controller.UnarchiveCategory) // Bring an archived category back
+		categoryGroup.PUT("/:categoryId/preference", controller.SetCategoryPreference)      // Hide or rename a default category for the user
+		categoryGroup.DELETE("/:categoryId/preference", controller.ResetCategoryPreference) // Show a default category as seeded again
 		// categoryGroup.GET("/:categoryId/summary", controller.GetCategorySummary)
 		// categoryGroup.GET("/:categoryId/budget", controller.GetCategoryBudgetStatus)
 	}
@@ -38,6 +43,7 @@ func ExpenseRoutes(router *gin.Engine) {
 		expenseGroup.DELETE("/:expenseId", controller.DeleteExpense)
 		expenseGroup.PUT("/:expenseId", controller.UpdateExpense)
 		expenseGroup.GET("/analysis", controller.ExpenseAnalysis)
+		expenseGroup.POST("/category-suggestions", controller.SuggestCategories) // Likely categories for a draft expense
 	}
 }
 
@@ -50,6 +56,102 @@ func BudgetRoutes(router *gin.Engine) {
 		budgetGroup.GET("/:budgetId", controller.GetSingleBudget)  // Get a single budget
 		budgetGroup.PUT("/:budgetId", controller.UpdateBudget)     // Update a budget
 		budgetGroup.DELETE("/:budgetId", controller.DeleteBudget)  // Delete a budget
+		budgetGroup.GET("/:budgetId/periods", controller.ListBudgetPeriods) // Periods of a recurring budget with their spending
+		budgetGroup.GET("/:budgetId/alerts", controller.ListBudgetAlerts)   // Alert thresholds reached in each period
+		budgetGroup.GET("/:budgetId/versions", controller.ListBudgetVersions) // Every change to the budget, with who made it
 		budgetGroup.GET("/analysis", controller.BudgetAnalysis)
 	}
 }
+
+func ReceiptRoutes(router *gin.Engine) {
+	receiptGroup := router.Group("/api/v1/receipts")
+	receiptGroup.Use(middleware.AuthMiddleware())
+	{
+		receiptGroup.POST("/", controller.UploadReceipt)                   // Upload a receipt image
+		receiptGroup.GET("/", controller.ListReceipts)                     // List all receipts
+		receiptGroup.GET("/:receiptId", controller.GetReceipt)             // Get receipt metadata
+		receiptGroup.GET("/:receiptId/image", controller.DownloadReceipt)  // Download the receipt image
+		receiptGroup.GET("/:receiptId/draft", controller.GetReceiptDraft)  // Parsed fields and expense draft
+		receiptGroup.POST("/:receiptId/expense", controller.CreateExpenseFromReceipt) // Create an expense from the receipt
+		receiptGroup.POST("/:receiptId/reprocess", controller.ReprocessReceipt) // Queue the receipt for OCR again
+		receiptGroup.DELETE("/:receiptId", controller.DeleteReceipt)       // Delete a receipt
+	}
+}
+
+func RecurringExpenseRoutes(router *gin.Engine) {
+	recurringGroup := router.Group("/api/v1/recurring-expenses")
+	recurringGroup.Use(middleware.AuthMiddleware())
+	{
+		recurringGroup.POST("/", controller.CreateRecurringExpense)                        // Create a recurring expense
+		recurringGroup.GET("/", controller.ListRecurringExpenses)                          // List recurring expenses
+		recurringGroup.GET("/:recurringExpenseId", controller.GetRecurringExpense)         // Get a recurring expense and its next dates
+		recurringGroup.PUT("/:recurringExpenseId", controller.UpdateRecurringExpense)      // Update the whole series or this and future
+		recurringGroup.DELETE("/:recurringExpenseId", controller.DeleteRecurringExpense)   // Stop a recurring expense
+	}
+}
+
+func TagRoutes(router *gin.Engine) {
+	tagGroup := router.Group("/api/v1/tags")
+	tagGroup.Use(middleware.AuthMiddleware())
+	{
+		tagGroup.POST("/", controller.CreateTag)            // Create a tag
+		tagGroup.GET("/", controller.ListTags)              // List tags with their usage
+		tagGroup.GET("/:tagId", controller.GetTag)          // Get a single tag
+		tagGroup.PUT("/:tagId", controller.UpdateTag)       // Rename or recolor a tag
+		tagGroup.DELETE("/:tagId", controller.DeleteTag)    // Delete a tag and untag its expenses
+	}
+}
+
+func RuleRoutes(router *gin.Engine) {
+	ruleGroup := router.Group("/api/v1/rules")
+	ruleGroup.Use(middleware.AuthMiddleware())
+	{
+		ruleGroup.POST("/", controller.CreateRule)            // Create a category rule
+		ruleGroup.GET("/", controller.ListRules)              // List rules in priority order
+		ruleGroup.GET("/dry-run", controller.DryRunRules)     // Show which existing expenses the rules would recategorize
+		ruleGroup.POST("/apply", controller.ApplyRules)       // Recategorize existing expenses retroactively
+		ruleGroup.GET("/:ruleId", controller.GetRule)         // Get a single rule
+		ruleGroup.PUT("/:ruleId", controller.UpdateRule)      // Update a rule
+		ruleGroup.DELETE("/:ruleId", controller.DeleteRule)   // Delete a rule
+	}
+}
+
+func NotificationRoutes(router *gin.Engine) {
+	notificationGroup := router.Group("/api/v1/notifications")
+	notificationGroup.Use(middleware.AuthMiddleware())
+	{
+		notificationGroup.GET("/", controller.ListNotifications)                          // In-app inbox, newest first
+		notificationGroup.POST("/read", controller.MarkAllNotificationsRead)               // Mark every notification as read
+		notificationGroup.POST("/:notificationId/read", controller.MarkNotificationRead)   // Mark one notification as read
+	}
+}
+
+func EnvelopeRoutes(router *gin.Engine) {
+	envelopeGroup := router.Group("/api/v1/envelopes")
+	envelopeGroup.Use(middleware.AuthMiddleware())
+	{
+		envelopeGroup.POST("/", controller.CreateEnvelopePlan)                            // Declare the income of a period
+		envelopeGroup.GET("/", controller.ListEnvelopePlans)                              // List envelope plans
+		envelopeGroup.GET("/:planId", controller.GetEnvelopePlan)                         // Envelopes with their allocation, spending and balance
+		envelopeGroup.DELETE("/:planId", controller.DeleteEnvelopePlan)                   // Delete a plan and its envelopes
+		envelopeGroup.PUT("/:planId/income", controller.UpdateEnvelopeIncome)             // Change the income of the period
+		envelopeGroup.GET("/:planId/unassigned", controller.GetEnvelopeUnassigned)        // Income not allocated to an envelope yet
+		envelopeGroup.PUT("/:planId/allocations", controller.SetEnvelopeAllocation)       // Set the amount of a category's envelope
+		envelopeGroup.POST("/:planId/transfers", controller.CreateEnvelopeTransfer)       // Move money between envelopes
+		envelopeGroup.GET("/:planId/transfers", controller.ListEnvelopeTransfers)         // Transfer history of the plan
+	}
+}
+
+func BudgetTemplateRoutes(router *gin.Engine) {
+	templateGroup := router.Group("/api/v1/budget-templates")
+	templateGroup.Use(middleware.AuthMiddleware())
+	{
+		templateGroup.POST("/", controller.CreateBudgetTemplate)                    // Create a template of category amounts
+		templateGroup.POST("/from-month", controller.CreateBudgetTemplateFromMonth) // Build a template from a past month's spending
+		templateGroup.GET("/", controller.ListBudgetTemplates)                      // List templates with their lines
+		templateGroup.GET("/:templateId", controller.GetBudgetTemplate)             // Get a single template
+		templateGroup.PUT("/:templateId", controller.UpdateBudgetTemplate)          // Rename a template or replace its lines
+		templateGroup.DELETE("/:templateId", controller.DeleteBudgetTemplate)       // Delete a template
+		templateGroup.POST("/:templateId/apply", controller.ApplyBudgetTemplate)    // Create a budget per line over a date range
+	}
+}
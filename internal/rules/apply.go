@@ -0,0 +1,108 @@
+package rules
+
+import (
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// batchSize is how many expenses are matched per query when rules run over existing expenses
+const batchSize = 500
+
+// Change is an existing expense that the rules would move to another category
+type Change struct {
+	ExpenseID      uuid.UUID     `json:"expense_id"`
+	Date           time.Time     `json:"date"`
+	Description    string        `json:"description"`
+	Merchant       string        `json:"merchant,omitempty"`
+	Amount         money.Decimal `json:"amount"`
+	FromCategoryID uuid.UUID     `json:"from_category_id"`
+	ToCategoryID   uuid.UUID     `json:"to_category_id"`
+	RuleID         uuid.UUID     `json:"rule_id"`
+	RuleName       string        `json:"rule_name"`
+}
+
+// Scope selects the existing expenses rules run over; nil dates are open ends
+type Scope struct {
+	UserID       uuid.UUID
+	HomeCurrency string // Currency of expenses recorded before currencies were tracked
+	StartDate    *time.Time
+	EndDate      *time.Time
+}
+
+// Preview lists the expenses in scope whose category the engine would change. Expenses no rule matches keep
+// their category, and split expenses are skipped since each allocation has its own category.
+func Preview(database *gorm.DB, engine *Engine, scope Scope) ([]Change, error) {
+	query := database.Model(&models.Expense{}).
+		Where("user_id = ?", scope.UserID).
+		Where("expense_id NOT IN (?)", database.Model(&models.ExpenseAllocation{}).Select("expense_id"))
+	if scope.StartDate != nil {
+		query = query.Where("date >= ?", *scope.StartDate)
+	}
+	if scope.EndDate != nil {
+		query = query.Where("date <= ?", *scope.EndDate)
+	}
+
+	changes := []Change{}
+	var batch []models.Expense
+	result := query.FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		for i := range batch {
+			expense := &batch[i]
+			if expense.Currency == "" {
+				expense.Currency = scope.HomeCurrency
+			}
+			rule := engine.Match(expense)
+			if rule == nil || rule.CategoryID == expense.CategoryID {
+				continue
+			}
+			changes = append(changes, Change{
+				ExpenseID:      expense.ExpenseID,
+				Date:           expense.Date,
+				Description:    expense.Description,
+				Merchant:       expense.Merchant,
+				Amount:         expense.Amount,
+				FromCategoryID: expense.CategoryID,
+				ToCategoryID:   rule.CategoryID,
+				RuleID:         rule.ID,
+				RuleName:       rule.Name,
+			})
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	// Batches are read in primary key order
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Date.Before(changes[j].Date) })
+	return changes, nil
+}
+
+// Apply recategorizes the expenses Preview reports, in one transaction, and returns the changes made
+func Apply(database *gorm.DB, engine *Engine, scope Scope) ([]Change, error) {
+	var changes []Change
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if changes, err = Preview(tx, engine, scope); err != nil {
+			return err
+		}
+
+		byCategory := map[uuid.UUID][]uuid.UUID{}
+		for _, change := range changes {
+			byCategory[change.ToCategoryID] = append(byCategory[change.ToCategoryID], change.ExpenseID)
+		}
+		for categoryID, expenseIDs := range byCategory {
+			if err := tx.Model(&models.Expense{}).
+				Where("user_id = ? AND expense_id IN ?", scope.UserID, expenseIDs).
+				Update("category_id", categoryID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
@@ -0,0 +1,77 @@
+package rules
+
+import (
+	"errors"
+	"expense-mgmt/internal/models"
+	"log"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FallbackCategoryKey is the seed key of the default category given to expenses no rule matches
+const FallbackCategoryKey = "others"
+
+// ErrNoFallbackCategory is returned when the fallback default category has not been seeded
+var ErrNoFallbackCategory = errors.New("fallback category not found")
+
+// Engine holds a user's active rules in priority order
+type Engine struct {
+	rules []*Rule
+}
+
+// Load compiles the user's active rules. Rules that no longer compile are skipped and logged.
+func Load(database *gorm.DB, userID interface{}) (*Engine, error) {
+	var stored []models.CategoryRule
+	if err := database.Where("user_id = ? AND active = ?", userID, true).
+		Order("priority ASC, created_at ASC").
+		Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	engine := &Engine{}
+	for _, rule := range stored {
+		compiled, err := Compile(rule)
+		if err != nil {
+			log.Printf("Skipping category rule %s: %v", rule.ID, err)
+			continue
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	return engine, nil
+}
+
+// NewEngine returns an engine over already compiled rules, tried in the given order
+func NewEngine(rules ...*Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Match returns the first rule matching the expense, or nil
+func (e *Engine) Match(expense *models.Expense) *Rule {
+	for _, rule := range e.rules {
+		if rule.Matches(expense) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Categorize returns the category of the first matching rule, or the fallback category when none matches
+func (e *Engine) Categorize(database *gorm.DB, expense *models.Expense) (uuid.UUID, *Rule, error) {
+	if rule := e.Match(expense); rule != nil {
+		return rule.CategoryID, rule, nil
+	}
+	categoryID, err := FallbackCategoryID(database)
+	return categoryID, nil, err
+}
+
+// FallbackCategoryID returns the ID of the default "Others" category
+func FallbackCategoryID(database *gorm.DB) (uuid.UUID, error) {
+	var category models.Category
+	err := database.Where("is_default = ? AND seed_key = ?", true, FallbackCategoryKey).
+		First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, ErrNoFallbackCategory
+	}
+	return category.ID, err
+}
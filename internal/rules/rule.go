@@ -0,0 +1,143 @@
+package rules
+
+import (
+	"errors"
+	"expense-mgmt/internal/models"
+	"expense-mgmt/internal/money"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Condition fields and operators
+const (
+	FieldDescription = "description"
+	FieldMerchant    = "merchant"
+	FieldAmount      = "amount"
+	FieldCurrency    = "currency"
+
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+// textOperators apply to description, merchant and currency; comparisons ignore case except for regex
+var textOperators = map[string]func(value, pattern string) bool{
+	"contains":    strings.Contains,
+	"equals":      func(value, pattern string) bool { return value == pattern },
+	"starts_with": strings.HasPrefix,
+	"ends_with":   strings.HasSuffix,
+}
+
+// amountOperators apply to the amount, compared with Decimal.Cmp
+var amountOperators = map[string]func(cmp int) bool{
+	"eq":  func(cmp int) bool { return cmp == 0 },
+	"lt":  func(cmp int) bool { return cmp < 0 },
+	"lte": func(cmp int) bool { return cmp <= 0 },
+	"gt":  func(cmp int) bool { return cmp > 0 },
+	"gte": func(cmp int) bool { return cmp >= 0 },
+}
+
+// Rule is a category rule with its conditions parsed, ready to be matched against expenses
+type Rule struct {
+	models.CategoryRule
+	conditions []condition
+}
+
+// condition is a parsed models.RuleCondition
+type condition struct {
+	field   string
+	text    func(value string) bool
+	amount  func(amount money.Decimal) bool
+	pattern string
+}
+
+// Compile validates a rule and parses its conditions
+func Compile(rule models.CategoryRule) (*Rule, error) {
+	if rule.Match != MatchAll && rule.Match != MatchAny {
+		return nil, fmt.Errorf("match must be '%s' or '%s'", MatchAll, MatchAny)
+	}
+	if len(rule.Conditions) == 0 {
+		return nil, errors.New("a rule needs at least one condition")
+	}
+
+	compiled := &Rule{CategoryRule: rule}
+	for i, raw := range rule.Conditions {
+		parsed, err := compileCondition(raw)
+		if err != nil {
+			return nil, fmt.Errorf("condition %d: %w", i+1, err)
+		}
+		compiled.conditions = append(compiled.conditions, parsed)
+	}
+	return compiled, nil
+}
+
+// compileCondition checks that the operator suits the field and parses the value
+func compileCondition(raw models.RuleCondition) (condition, error) {
+	parsed := condition{field: raw.Field, pattern: raw.Value}
+	switch raw.Field {
+	case FieldDescription, FieldMerchant, FieldCurrency:
+		if raw.Value == "" {
+			return parsed, errors.New("value is required")
+		}
+		if raw.Operator == "regex" {
+			expression, err := regexp.Compile(raw.Value)
+			if err != nil {
+				return parsed, fmt.Errorf("invalid regex: %w", err)
+			}
+			parsed.text = expression.MatchString
+			return parsed, nil
+		}
+		test, ok := textOperators[raw.Operator]
+		if !ok {
+			return parsed, fmt.Errorf("operator %q does not apply to %s", raw.Operator, raw.Field)
+		}
+		pattern := strings.ToLower(raw.Value)
+		parsed.text = func(value string) bool { return test(strings.ToLower(value), pattern) }
+	case FieldAmount:
+		test, ok := amountOperators[raw.Operator]
+		if !ok {
+			return parsed, fmt.Errorf("operator %q does not apply to amount", raw.Operator)
+		}
+		threshold, err := money.Parse(raw.Value)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid amount %q", raw.Value)
+		}
+		parsed.amount = func(amount money.Decimal) bool { return test(amount.Cmp(threshold)) }
+	default:
+		return parsed, fmt.Errorf("unknown field %q", raw.Field)
+	}
+	return parsed, nil
+}
+
+// Matches reports whether the expense satisfies all (or any, per Match) of the rule's conditions
+func (r *Rule) Matches(expense *models.Expense) bool {
+	for _, c := range r.conditions {
+		matched := c.matches(expense)
+		if r.Match == MatchAny && matched {
+			return true
+		}
+		if r.Match == MatchAll && !matched {
+			return false
+		}
+	}
+	return r.Match == MatchAll
+}
+
+// matches tests the condition against one expense. Amounts are compared in the currency the expense was paid in.
+func (c condition) matches(expense *models.Expense) bool {
+	switch c.field {
+	case FieldDescription:
+		return c.text(expense.Description)
+	case FieldMerchant:
+		return c.text(expense.Merchant)
+	case FieldCurrency:
+		return c.text(expense.Currency)
+	case FieldAmount:
+		amount := expense.Amount
+		if expense.OriginalAmount != nil {
+			amount = *expense.OriginalAmount
+		}
+		return c.amount(amount)
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"expense-mgmt/configs"
+	"fmt"
+	"io"
+	"log"
+)
+
+// ErrNotFound is returned when a blob does not exist in the store
+var ErrNotFound = errors.New("blob not found")
+
+// DefaultMaxUploadBytes is used when no upload limit is configured (10 MB)
+const DefaultMaxUploadBytes int64 = 10 << 20
+
+// BlobStore abstracts where receipt images are kept so the backend can be swapped
+// (local disk, object storage, ...) without touching the controllers
+type BlobStore interface {
+	// Put stores the content read from r under key and returns the number of bytes written
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Get opens the blob stored under key; callers must close the returned reader
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key; deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Store - Global blob store used by the receipt handlers
+var Store BlobStore
+
+var maxUploadBytes = DefaultMaxUploadBytes
+
+// InitStore builds the blob store selected in the configuration
+func InitStore() (BlobStore, error) {
+	config := configs.LoadConfig() // Load the configuration
+
+	if config.Storage.MaxUploadBytes > 0 {
+		maxUploadBytes = config.Storage.MaxUploadBytes
+	}
+
+	switch config.Storage.Driver {
+	case "", "local":
+		path := config.Storage.LocalPath
+		if path == "" {
+			path = "./data/receipts"
+		}
+		store, err := NewLocalStore(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize local storage: %w", err)
+		}
+		Store = store
+	default:
+		return nil, fmt.Errorf("unsupported storage driver %q", config.Storage.Driver)
+	}
+
+	log.Printf("Blob storage initialized (driver: %s)", config.Storage.Driver)
+	return Store, nil
+}
+
+// GetStore returns the blob store instance
+func GetStore() BlobStore {
+	return Store
+}
+
+// MaxUploadBytes returns the maximum accepted upload size in bytes
+func MaxUploadBytes() int64 {
+	return maxUploadBytes
+}
@@ -0,0 +1,155 @@
+package suggest
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// smoothing is the Laplace smoothing added to every token count, so unseen tokens do not zero out a category
+const smoothing = 1.0
+
+// Suggestion is a likely category for an expense with its estimated probability
+type Suggestion struct {
+	CategoryID  uuid.UUID `json:"category_id"`
+	Probability float64   `json:"probability"`
+}
+
+// Classifier is a multinomial naive Bayes model of which category a user files an expense text under.
+// It is trained one example at a time, so it can follow expenses as they are created, recategorized or
+// deleted. It is not safe for concurrent use.
+type Classifier struct {
+	examples   int                          // Examples learned in total
+	documents  map[uuid.UUID]int            // Examples per category
+	tokens     map[uuid.UUID]map[string]int // Token counts per category
+	totals     map[uuid.UUID]int            // Token count per category
+	vocabulary map[string]int               // Token counts over all categories
+}
+
+// NewClassifier returns an untrained classifier
+func NewClassifier() *Classifier {
+	return &Classifier{
+		documents:  map[uuid.UUID]int{},
+		tokens:     map[uuid.UUID]map[string]int{},
+		totals:     map[uuid.UUID]int{},
+		vocabulary: map[string]int{},
+	}
+}
+
+// Learn adds an example of text filed under the category
+func (m *Classifier) Learn(text string, categoryID uuid.UUID) {
+	m.examples++
+	m.documents[categoryID]++
+	counts := m.tokens[categoryID]
+	if counts == nil {
+		counts = map[string]int{}
+		m.tokens[categoryID] = counts
+	}
+	for _, token := range Tokenize(text) {
+		counts[token]++
+		m.totals[categoryID]++
+		m.vocabulary[token]++
+	}
+}
+
+// Unlearn removes an example added by Learn; examples that were never learned are ignored
+func (m *Classifier) Unlearn(text string, categoryID uuid.UUID) {
+	if m.documents[categoryID] == 0 {
+		return
+	}
+	m.examples--
+	m.documents[categoryID]--
+	counts := m.tokens[categoryID]
+	for _, token := range Tokenize(text) {
+		if counts[token] == 0 {
+			continue
+		}
+		decrement(counts, token)
+		decrement(m.vocabulary, token)
+		m.totals[categoryID]--
+	}
+	if m.documents[categoryID] == 0 {
+		delete(m.documents, categoryID)
+		delete(m.tokens, categoryID)
+		delete(m.totals, categoryID)
+	}
+}
+
+// Examples returns how many examples the classifier holds
+func (m *Classifier) Examples() int {
+	return m.examples
+}
+
+// Predict returns every known category ordered from the most to the least likely for the text. Tokens the
+// classifier has never seen are ignored; when none is known the categories are ranked by how often they are used.
+func (m *Classifier) Predict(text string) []Suggestion {
+	if m.examples == 0 {
+		return []Suggestion{}
+	}
+
+	var known []string
+	for _, token := range Tokenize(text) {
+		if m.vocabulary[token] > 0 {
+			known = append(known, token)
+		}
+	}
+
+	vocabularySize := float64(len(m.vocabulary))
+	scores := make([]Suggestion, 0, len(m.documents))
+	best := math.Inf(-1)
+	for categoryID, documents := range m.documents {
+		score := math.Log(float64(documents) / float64(m.examples))
+		denominator := float64(m.totals[categoryID]) + smoothing*vocabularySize
+		for _, token := range known {
+			score += math.Log((float64(m.tokens[categoryID][token]) + smoothing) / denominator)
+		}
+		scores = append(scores, Suggestion{CategoryID: categoryID, Probability: score})
+		best = math.Max(best, score)
+	}
+
+	// Normalize the log scores into probabilities, shifted by the best score to avoid underflow
+	var sum float64
+	for i := range scores {
+		scores[i].Probability = math.Exp(scores[i].Probability - best)
+		sum += scores[i].Probability
+	}
+	for i := range scores {
+		scores[i].Probability /= sum
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Probability != scores[j].Probability {
+			return scores[i].Probability > scores[j].Probability
+		}
+		return scores[i].CategoryID.String() < scores[j].CategoryID.String()
+	})
+	return scores
+}
+
+// Tokenize lowercases the text and splits it into words. Single characters and plain numbers, such as
+// amounts, dates and card digits, say little about the category and are dropped.
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := words[:0]
+	for _, word := range words {
+		if len([]rune(word)) < 2 || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// decrement lowers a count, removing the key when it reaches zero
+func decrement(counts map[string]int, key string) {
+	if counts[key] <= 1 {
+		delete(counts, key)
+		return
+	}
+	counts[key]--
+}
@@ -0,0 +1,220 @@
+package suggest
+
+import (
+	"container/list"
+	"expense-mgmt/internal/models"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// DefaultCapacity is how many users' classifiers the default store keeps in memory
+const DefaultCapacity = 1000
+
+// Store caches a classifier per user in memory, evicting the least recently used one beyond its capacity. Learn,
+// Relearn and Unlearn keep a classifier current as this instance changes single expenses. Expenses may also change
+// in bulk or through another instance of the service, so a cached classifier is only used while the user's
+// expenses still have the count and latest update it last accounted for; otherwise it is retrained.
+type Store struct {
+	mu       sync.Mutex
+	capacity int
+	users    map[uuid.UUID]*list.Element // Elements hold a *userClassifier, most recently used first
+	recent   *list.List
+}
+
+// userClassifier guards one user's classifier together with the state of the expenses it was trained on
+type userClassifier struct {
+	mu          sync.Mutex
+	userID      uuid.UUID
+	trained     bool
+	fingerprint expenseFingerprint
+	classifier  *Classifier
+}
+
+// expenseFingerprint changes whenever one of the user's expenses is created, updated or deleted
+type expenseFingerprint struct {
+	Count       int64
+	LastUpdated *time.Time
+}
+
+var defaultStore = NewStore(DefaultCapacity)
+
+// NewStore returns an empty store keeping at most capacity classifiers
+func NewStore(capacity int) *Store {
+	if capacity <= 0 {
+		capacity = DefaultCapacity
+	}
+	return &Store{capacity: capacity, users: map[uuid.UUID]*list.Element{}, recent: list.New()}
+}
+
+// Text is what the classifier learns from an expense: its description and merchant
+func Text(expense *models.Expense) string {
+	return expense.Description + " " + expense.Merchant
+}
+
+// Suggest ranks the categories the user has filed expenses under for the text, training the user's classifier
+// first if it is missing or out of date. It also returns how many expenses the classifier has learned from.
+func (s *Store) Suggest(database *gorm.DB, userID uuid.UUID, text string) ([]Suggestion, int, error) {
+	fingerprint, err := readFingerprint(database, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	user := s.get(userID)
+	user.mu.Lock()
+	defer user.mu.Unlock()
+	if !user.trained || !user.fingerprint.equal(fingerprint) {
+		classifier, err := Train(database, userID)
+		if err != nil {
+			return nil, 0, err
+		}
+		user.classifier = classifier
+		user.fingerprint = fingerprint
+		user.trained = true
+	}
+
+	return user.classifier.Predict(text), user.classifier.Examples(), nil
+}
+
+// Learn adds an expense to the user's classifier once it has been created
+func (s *Store) Learn(database *gorm.DB, userID uuid.UUID, expense *models.Expense) {
+	s.update(database, userID, 1, &expense.UpdatedAt, func(classifier *Classifier) {
+		classifier.Learn(Text(expense), expense.CategoryID)
+	})
+}
+
+// Relearn replaces an expense's previous state with its saved one, e.g. after it was recategorized
+func (s *Store) Relearn(database *gorm.DB, userID uuid.UUID, previous, expense *models.Expense) {
+	s.update(database, userID, 0, &expense.UpdatedAt, func(classifier *Classifier) {
+		classifier.Unlearn(Text(previous), previous.CategoryID)
+		classifier.Learn(Text(expense), expense.CategoryID)
+	})
+}
+
+// Unlearn removes an expense from the user's classifier once it has been deleted
+func (s *Store) Unlearn(database *gorm.DB, userID uuid.UUID, expense *models.Expense) {
+	s.update(database, userID, -1, nil, func(classifier *Classifier) {
+		classifier.Unlearn(Text(expense), expense.CategoryID)
+	})
+}
+
+// update applies a change this instance made to one expense. Classifiers not trained yet are left alone, since
+// training reads the expense from the database. The change is only applied if the user's expenses now differ from
+// what the classifier accounted for by just that expense; otherwise they also changed elsewhere and the classifier
+// is retrained on the next request.
+func (s *Store) update(database *gorm.DB, userID uuid.UUID, delta int64, updated *time.Time, change func(*Classifier)) {
+	s.mu.Lock()
+	element, ok := s.users[userID]
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	user := element.Value.(*userClassifier)
+
+	user.mu.Lock()
+	defer user.mu.Unlock()
+	if !user.trained {
+		return
+	}
+	fingerprint, err := readFingerprint(database, userID)
+	if err != nil || !fingerprint.follows(user.fingerprint, delta, updated) {
+		user.trained = false
+		return
+	}
+	change(user.classifier)
+	user.fingerprint = fingerprint
+}
+
+// get returns the user's cache entry, creating it if needed, and marks it as the most recently used
+func (s *Store) get(userID uuid.UUID) *userClassifier {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.users[userID]; ok {
+		s.recent.MoveToFront(element)
+		return element.Value.(*userClassifier)
+	}
+	user := &userClassifier{userID: userID}
+	s.users[userID] = s.recent.PushFront(user)
+	for s.recent.Len() > s.capacity {
+		oldest := s.recent.Back()
+		s.recent.Remove(oldest)
+		delete(s.users, oldest.Value.(*userClassifier).userID)
+	}
+	return user
+}
+
+// readFingerprint reads the current fingerprint of the user's expenses
+func readFingerprint(database *gorm.DB, userID uuid.UUID) (expenseFingerprint, error) {
+	var fingerprint expenseFingerprint
+	err := database.Model(&models.Expense{}).
+		Select("COUNT(*) AS count, MAX(updated_at) AS last_updated").
+		Where("user_id = ?", userID).
+		Scan(&fingerprint).Error
+	return fingerprint, err
+}
+
+// follows reports whether f is what previous becomes after delta expenses were created or deleted and, unless
+// updated is nil, one was saved at updated. The database keeps timestamps to the microsecond.
+func (f expenseFingerprint) follows(previous expenseFingerprint, delta int64, updated *time.Time) bool {
+	if f.Count != previous.Count+delta {
+		return false
+	}
+	if updated != nil {
+		return f.LastUpdated != nil && f.LastUpdated.Sub(*updated).Abs() < time.Microsecond
+	}
+	// A deletion leaves the latest update as it was, or earlier
+	return f.LastUpdated == nil || previous.LastUpdated != nil && !f.LastUpdated.After(*previous.LastUpdated)
+}
+
+// equal reports whether two fingerprints describe the same expenses
+func (f expenseFingerprint) equal(other expenseFingerprint) bool {
+	if f.Count != other.Count || (f.LastUpdated == nil) != (other.LastUpdated == nil) {
+		return false
+	}
+	return f.LastUpdated == nil || f.LastUpdated.Equal(*other.LastUpdated)
+}
+
+// Train builds a classifier from all of the user's expenses
+func Train(database *gorm.DB, userID uuid.UUID) (*Classifier, error) {
+	rows, err := database.Model(&models.Expense{}).
+		Select("COALESCE(description, ''), COALESCE(merchant, ''), category_id").
+		Where("user_id = ?", userID).
+		Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	classifier := NewClassifier()
+	for rows.Next() {
+		var expense models.Expense
+		if err := rows.Scan(&expense.Description, &expense.Merchant, &expense.CategoryID); err != nil {
+			return nil, err
+		}
+		classifier.Learn(Text(&expense), expense.CategoryID)
+	}
+	return classifier, rows.Err()
+}
+
+// Suggest asks the default store for category suggestions
+func Suggest(database *gorm.DB, userID uuid.UUID, text string) ([]Suggestion, int, error) {
+	return defaultStore.Suggest(database, userID, text)
+}
+
+// Learn adds a created expense to the default store
+func Learn(database *gorm.DB, userID uuid.UUID, expense *models.Expense) {
+	defaultStore.Learn(database, userID, expense)
+}
+
+// Relearn updates a changed expense in the default store
+func Relearn(database *gorm.DB, userID uuid.UUID, previous, expense *models.Expense) {
+	defaultStore.Relearn(database, userID, previous, expense)
+}
+
+// Unlearn removes a deleted expense from the default store
+func Unlearn(database *gorm.DB, userID uuid.UUID, expense *models.Expense) {
+	defaultStore.Unlearn(database, userID, expense)
+}